@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/bootstrap"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/database"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/router"
@@ -18,8 +21,14 @@ func main() {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
-	// Set JWT secret
+	// Set JWT secret, signing keys and access token lifetime
 	auth.SetJWTSecret(cfg.JWT.Secret)
+	auth.SetJWTExpiry(time.Duration(cfg.JWT.ExpiryHour) * time.Hour)
+	keySet, err := auth.LoadKeySet(cfg.JWT.SigningKeys, cfg.JWT.Secret, cfg.JWT.AcceptHS256)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	auth.SetKeySet(keySet)
 
 	// Connect to database
 	db, err := database.Connect(cfg.Database)
@@ -40,6 +49,9 @@ func main() {
 	// Setup router
 	r := router.SetupRouter(db, cfg)
 
+	// Run subscription and invoice schedulers
+	bootstrap.StartBackgroundJobs(context.Background(), db, cfg)
+
 	// Start server
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
 	log.Printf("Starting AE SaaS Basic server on %s", addr)
@@ -0,0 +1,98 @@
+// Package bootstrap builds the application services from the configuration
+// and runs the background jobs, for both the router and the server mains.
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/vat"
+	mailer "github.com/ae-saas-basic/ae-saas-basic/services"
+	"gorm.io/gorm"
+)
+
+// StartBackgroundJobs runs the scheduled jobs until ctx is done: subscriptions
+// move between states as trials, periods and grace periods end, and customers
+// are invoiced for every billing period of their plan. Call it after
+// router.SetupRouter, which registers the tenancy callbacks the jobs rely on.
+func StartBackgroundJobs(ctx context.Context, db *gorm.DB, cfg config.Config) {
+	interval := time.Duration(cfg.Billing.SchedulerIntervalMinute) * time.Minute
+
+	subscriptionService := services.NewSubscriptionService(db, SubscriptionConfig(cfg.Billing))
+	go subscriptionService.Run(ctx, interval)
+
+	numberingService := services.NewNumberingService(db, NumberingConfig(cfg.Billing))
+	invoiceService := services.NewInvoiceService(db, numberingService, NewPDFService(cfg.PDF), mailer.NewEmailService(), InvoiceConfig(cfg))
+	go invoiceService.Run(ctx, interval)
+}
+
+// NewPDFService creates the PDF service from the PDF configuration
+func NewPDFService(cfg config.PDFConfig) *services.PDFService {
+	pdfServiceConfig := &services.PDFConfig{
+		PageSize:     cfg.PageSize,
+		Orientation:  cfg.Orientation,
+		MarginTop:    cfg.MarginTop,
+		MarginRight:  cfg.MarginRight,
+		MarginBottom: cfg.MarginBottom,
+		MarginLeft:   cfg.MarginLeft,
+		Quality:      cfg.Quality,
+		EnableJS:     cfg.EnableJS,
+		LoadTimeout:  cfg.LoadTimeout,
+		Headers:      make(map[string]string),
+	}
+	return services.NewPDFService(cfg.TemplateDir, cfg.OutputDir, pdfServiceConfig)
+}
+
+// SubscriptionConfig converts the billing configuration for the subscription service
+func SubscriptionConfig(cfg config.BillingConfig) services.SubscriptionConfig {
+	return services.SubscriptionConfig{
+		TrialPeriod: time.Duration(cfg.TrialDays) * 24 * time.Hour,
+		GracePeriod: time.Duration(cfg.PastDueGraceDays) * 24 * time.Hour,
+	}
+}
+
+// NumberingConfig converts the billing configuration for the numbering service
+func NumberingConfig(cfg config.BillingConfig) services.NumberingConfig {
+	return services.NumberingConfig{
+		Invoice: models.NumberingScheme{
+			Prefix:    cfg.InvoiceNumberPrefix,
+			YearReset: cfg.NumberYearReset,
+			Padding:   cfg.NumberPadding,
+		},
+		CreditNote: models.NumberingScheme{
+			Prefix:    cfg.CreditNoteNumberPrefix,
+			YearReset: cfg.NumberYearReset,
+			Padding:   cfg.NumberPadding,
+		},
+	}
+}
+
+// InvoiceConfig converts the billing and company configuration for the invoice service
+func InvoiceConfig(cfg config.Config) services.InvoiceConfig {
+	return services.InvoiceConfig{
+		VAT: vat.Engine{
+			Country:  cfg.Billing.VATCountry,
+			Rates:    cfg.Billing.VATRates,
+			OSS:      cfg.Billing.VATOSS,
+			FlatRate: cfg.Billing.TaxRate,
+		},
+		PaymentTerm:  time.Duration(cfg.Billing.PaymentTermDays) * 24 * time.Hour,
+		BillingStart: cfg.Billing.InvoiceBillingStart,
+		Locale:       cfg.Billing.InvoiceLocale,
+		Company: models.CompanyInfo{
+			Name:    cfg.Company.Name,
+			Address: cfg.Company.Address,
+			City:    cfg.Company.City,
+			State:   cfg.Company.State,
+			ZipCode: cfg.Company.Zip,
+			Country: cfg.Company.Country,
+			Phone:   cfg.Company.Phone,
+			Email:   cfg.Company.Email,
+			Website: cfg.Company.Website,
+			TaxID:   cfg.Company.TaxID,
+		},
+	}
+}
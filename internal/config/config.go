@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ae-saas-basic/ae-saas-basic/internal/database"
 )
@@ -12,6 +14,10 @@ type Config struct {
 	Server   ServerConfig
 	Database database.Config
 	JWT      JWTConfig
+	Auth     AuthConfig
+	Tenant   TenantConfig
+	Billing  BillingConfig
+	Company  CompanyConfig
 	Email    EmailConfig
 	PDF      PDFConfig
 }
@@ -25,8 +31,67 @@ type ServerConfig struct {
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret     string
-	ExpiryHour int
+	Secret            string
+	ExpiryHour        int    // access token lifetime
+	RefreshExpiryHour int    // refresh token lifetime
+	SigningKeys       string // kid=path[@activeFrom] entries for RS256/EdDSA keys; empty uses HS256
+	AcceptHS256       bool   // keep verifying HS256 tokens when signing keys are configured
+}
+
+// AuthConfig holds account security configuration
+type AuthConfig struct {
+	FrontendURL                 string // base URL used for links in account emails
+	PasswordResetExpiryMinute   int
+	RequireEmailVerification    bool // reject unverified users on protected routes
+	EmailVerificationExpiryHour int
+	VerificationResendSecond    int    // minimum delay between verification emails
+	TOTPIssuer                  string // issuer shown in authenticator apps
+	MFAChallengeExpiryMinute    int
+	LoginFreeAttempts           int // failed logins per account before backoff starts
+	LoginBackoffSecond          int // first backoff delay, doubled on every further failure
+	LoginMaxAttempts            int // failed logins that lock an account
+	LoginIPMaxAttempts          int // failed logins that block a client IP
+	LoginLockoutMinute          int
+	OpenRegistration            bool // allow self-registration into existing tenants
+	TenantSignup                bool // allow self-service signup of new tenants
+	InvitationExpiryHour        int
+}
+
+// TenantConfig holds tenant resolution configuration
+type TenantConfig struct {
+	BaseDomain string // tenants are served under <slug>.<base domain>; empty disables subdomains
+}
+
+// BillingConfig holds subscription and invoicing configuration
+type BillingConfig struct {
+	TrialDays               int                // trial length of new subscriptions; 0 starts them active
+	PastDueGraceDays        int                // days a past due subscription is kept before it expires
+	SchedulerIntervalMinute int                // how often due subscriptions and invoices are processed
+	TaxRate                 float64            // tax rate of invoices in percent when VATCountry isn't set
+	PaymentTermDays         int                // days between issuing an invoice and its due date
+	InvoiceBillingStart     time.Time          // date invoicing starts from for customers without invoices; zero starts with the current period
+	InvoiceLocale           string             // locale amounts on invoices and their emails are formatted in, e.g. de-DE
+	InvoiceNumberPrefix     string             // default prefix of invoice numbers
+	CreditNoteNumberPrefix  string             // default prefix of credit note numbers
+	NumberPadding           int                // default number of digits of the sequence number
+	NumberYearReset         bool               // by default restart numbering every year
+	VATCountry              string             // seller's country picking invoice VAT by buyer country; empty charges TaxRate
+	VATRates                map[string]float64 // VAT rates in percent overriding the built-in EU rates, by country
+	VATOSS                  bool               // charge EU consumers abroad their country's rate (One-Stop-Shop)
+}
+
+// CompanyConfig holds the company shown as issuer on invoices
+type CompanyConfig struct {
+	Name    string
+	Address string
+	City    string
+	State   string
+	Zip     string
+	Country string
+	Phone   string
+	Email   string
+	Website string
+	TaxID   string
 }
 
 // EmailConfig holds email configuration
@@ -64,16 +129,68 @@ func Load() Config {
 			Mode: getEnv("GIN_MODE", "debug"),
 		},
 		Database: database.Config{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "password"),
-			DBName:   getEnv("DB_NAME", "ae_saas_basic"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:             getEnv("DB_HOST", "localhost"),
+			Port:             getEnv("DB_PORT", "5432"),
+			User:             getEnv("DB_USER", "postgres"),
+			Password:         getEnv("DB_PASSWORD", "password"),
+			DBName:           getEnv("DB_NAME", "ae_saas_basic"),
+			SSLMode:          getEnv("DB_SSLMODE", "disable"),
+			RowLevelSecurity: getEnvAsBool("DB_ROW_LEVEL_SECURITY", false),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-			ExpiryHour: getEnvAsInt("JWT_EXPIRY_HOUR", 24),
+			Secret:            getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
+			ExpiryHour:        getEnvAsInt("JWT_EXPIRY_HOUR", 1),
+			RefreshExpiryHour: getEnvAsInt("JWT_REFRESH_EXPIRY_HOUR", 720), // 30 days
+			SigningKeys:       getEnv("JWT_SIGNING_KEYS", ""),
+			AcceptHS256:       getEnvAsBool("JWT_ACCEPT_HS256", true),
+		},
+		Auth: AuthConfig{
+			FrontendURL:                 getEnv("FRONTEND_URL", "http://localhost:3000"),
+			PasswordResetExpiryMinute:   getEnvAsInt("PASSWORD_RESET_EXPIRY_MINUTE", 60),
+			RequireEmailVerification:    getEnvAsBool("FEATURE_EMAIL_VERIFICATION", false),
+			EmailVerificationExpiryHour: getEnvAsInt("EMAIL_VERIFICATION_EXPIRY_HOUR", 48),
+			VerificationResendSecond:    getEnvAsInt("EMAIL_VERIFICATION_RESEND_SECOND", 60),
+			TOTPIssuer:                  getEnv("TOTP_ISSUER", "AE SaaS Basic"),
+			MFAChallengeExpiryMinute:    getEnvAsInt("MFA_CHALLENGE_EXPIRY_MINUTE", 5),
+			LoginFreeAttempts:           getEnvAsInt("LOGIN_FREE_ATTEMPTS", 3),
+			LoginBackoffSecond:          getEnvAsInt("LOGIN_BACKOFF_SECOND", 1),
+			LoginMaxAttempts:            getEnvAsInt("LOGIN_MAX_ATTEMPTS", 10),
+			LoginIPMaxAttempts:          getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 100),
+			LoginLockoutMinute:          getEnvAsInt("LOGIN_LOCKOUT_MINUTE", 15),
+			OpenRegistration:            getEnvAsBool("FEATURE_USER_REGISTRATION", true),
+			TenantSignup:                getEnvAsBool("FEATURE_TENANT_SIGNUP", true),
+			InvitationExpiryHour:        getEnvAsInt("INVITATION_EXPIRY_HOUR", 168),
+		},
+		Tenant: TenantConfig{
+			BaseDomain: getEnv("TENANT_BASE_DOMAIN", ""),
+		},
+		Billing: BillingConfig{
+			TrialDays:               getEnvAsInt("SUBSCRIPTION_TRIAL_DAYS", 0),
+			PastDueGraceDays:        getEnvAsInt("SUBSCRIPTION_GRACE_DAYS", 7),
+			SchedulerIntervalMinute: getEnvAsInt("SUBSCRIPTION_SCHEDULER_INTERVAL_MINUTE", 60),
+			TaxRate:                 getEnvAsFloat64("INVOICE_TAX_RATE", 0),
+			PaymentTermDays:         getEnvAsInt("INVOICE_PAYMENT_TERM_DAYS", 14),
+			InvoiceBillingStart:     getEnvAsDate("INVOICE_BILLING_START"),
+			InvoiceLocale:           getEnv("INVOICE_LOCALE", "en"),
+			InvoiceNumberPrefix:     getEnv("INVOICE_NUMBER_PREFIX", "INV-"),
+			CreditNoteNumberPrefix:  getEnv("CREDIT_NOTE_NUMBER_PREFIX", "CN-"),
+			NumberPadding:           getEnvAsInt("INVOICE_NUMBER_PADDING", 6),
+			NumberYearReset:         getEnvAsBool("INVOICE_NUMBER_YEAR_RESET", false),
+			VATCountry:              getEnv("VAT_COUNTRY", ""),
+			VATRates:                getEnvAsFloat64Map("VAT_RATES"),
+			VATOSS:                  getEnvAsBool("VAT_OSS", true),
+		},
+		Company: CompanyConfig{
+			Name:    getEnv("COMPANY_NAME", "AE SaaS Basic"),
+			Address: getEnv("COMPANY_ADDRESS", ""),
+			City:    getEnv("COMPANY_CITY", ""),
+			State:   getEnv("COMPANY_STATE", ""),
+			Zip:     getEnv("COMPANY_ZIP", ""),
+			Country: getEnv("COMPANY_COUNTRY", ""),
+			Phone:   getEnv("COMPANY_PHONE", ""),
+			Email:   getEnv("COMPANY_EMAIL", ""),
+			Website: getEnv("COMPANY_WEBSITE", ""),
+			TaxID:   getEnv("COMPANY_TAX_ID", ""),
 		},
 		Email: EmailConfig{
 			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
@@ -143,3 +260,29 @@ func getEnvAsFloat64(key string, defaultVal float64) float64 {
 	}
 	return defaultVal
 }
+
+// getEnvAsDate gets environment variable as a YYYY-MM-DD date in UTC, the
+// zero time if it is unset or malformed
+func getEnvAsDate(key string) time.Time {
+	value, err := time.Parse("2006-01-02", getEnv(key, ""))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
+
+// getEnvAsFloat64Map gets environment variable as comma separated KEY=value
+// pairs of float64 values, skipping malformed pairs
+func getEnvAsFloat64Map(key string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		name, valueStr, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		if value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64); err == nil {
+			values[strings.ToUpper(strings.TrimSpace(name))] = value
+		}
+	}
+	return values
+}
@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -22,6 +27,9 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// RowLevelSecurity enforces tenant isolation with PostgreSQL row-level
+	// security policies in addition to the query callbacks
+	RowLevelSecurity bool
 }
 
 // SeedData represents the structure of seed data from JSON
@@ -39,16 +47,16 @@ type SeedTenant struct {
 
 // SeedPlan represents plan seed data
 type SeedPlan struct {
-	Name          string                 `json:"name"`
-	Slug          string                 `json:"slug"`
-	Description   string                 `json:"description"`
-	Price         float64                `json:"price"`
-	Currency      string                 `json:"currency"`
-	InvoicePeriod string                 `json:"invoice_period"`
-	MaxUsers      int                    `json:"max_users"`
-	MaxClients    int                    `json:"max_clients"`
-	Features      map[string]interface{} `json:"features"`
-	Active        bool                   `json:"active"`
+	Name          string              `json:"name"`
+	Slug          string              `json:"slug"`
+	Description   string              `json:"description"`
+	Price         float64             `json:"price"`
+	Currency      string              `json:"currency"`
+	InvoicePeriod string              `json:"invoice_period"`
+	MaxUsers      int                 `json:"max_users"`
+	MaxClients    int                 `json:"max_clients"`
+	Features      models.PlanFeatures `json:"features"`
+	Active        bool                `json:"active"`
 }
 
 // SeedUser represents user seed data
@@ -129,6 +137,16 @@ func Connect(config Config) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 
+	// Restrict tenant-scoped models to the tenant of each request
+	if err := tenancy.Register(db); err != nil {
+		return nil, fmt.Errorf("failed to register tenancy callbacks: %w", err)
+	}
+	if config.RowLevelSecurity {
+		if err := tenancy.EnableRowLevelSecurity(db); err != nil {
+			return nil, fmt.Errorf("failed to enable row-level security: %w", err)
+		}
+	}
+
 	return db, nil
 }
 
@@ -143,38 +161,52 @@ func ConnectWithAutoCreate(config Config) (*gorm.DB, error) {
 	return Connect(config)
 }
 
+// coreTables lists the tables of the original schema. A database that contains
+// only some of them is considered inconsistent and is recreated from scratch.
+var coreTables = []string{"tenants", "users", "plans", "customers", "contacts", "newsletters", "emails", "token_blacklist"}
+
 // Migrate runs database migrations with table existence checking
 func Migrate(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
-	// Check if we need to run migrations at all
+	// Check if the existing schema is consistent
 	log.Println("Checking migration status...")
 
-	var tableCount int64
-	err := db.Raw("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = CURRENT_SCHEMA() AND table_name IN ('tenants', 'users', 'plans', 'customers', 'contacts', 'newsletters', 'emails', 'token_blacklist')").Scan(&tableCount).Error
-	if err != nil {
-		return fmt.Errorf("failed to check existing tables: %w", err)
-	}
-
-	if tableCount == 8 {
-		log.Println("All tables already exist, skipping migrations")
-		return nil
+	tableCount := 0
+	for _, table := range coreTables {
+		if db.Migrator().HasTable(table) {
+			tableCount++
+		}
 	}
 
-	log.Printf("Found %d existing tables, running fresh migrations...", tableCount)
+	if tableCount > 0 && tableCount < len(coreTables) {
+		log.Printf("Found %d of %d core tables, running fresh migrations...", tableCount, len(coreTables))
 
-	// Drop all tables to avoid conflicts and recreate them
-	log.Println("Dropping existing tables to avoid conflicts...")
-	dropTables := []string{"emails", "contacts", "newsletters", "customers", "users", "plans", "tenants", "token_blacklist"}
-	for _, table := range dropTables {
-		err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table)).Error
-		if err != nil {
-			log.Printf("Warning: Failed to drop table %s: %v", table, err)
+		// Drop all tables to avoid conflicts and recreate them
+		log.Println("Dropping existing tables to avoid conflicts...")
+		dropTables := []string{"emails", "contacts", "newsletters", "customers", "users", "plans", "tenants", "token_blacklist", "refresh_tokens", "password_reset_tokens", "recovery_codes", "api_keys", "sessions", "login_throttles", "invitations", "user_settings", "tenant_domains", "customer_contacts", "subscriptions", "invoices", "invoice_line_items", "numbering_schemes", "number_sequences", "credit_notes", "credit_note_line_items"}
+		for _, table := range dropTables {
+			err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table)).Error
+			if err != nil {
+				log.Printf("Warning: Failed to drop table %s: %v", table, err)
+			}
 		}
+	} else if tableCount == len(coreTables) {
+		log.Println("Core tables already exist, migrating new tables and columns")
 	}
 
-	// Now run clean migrations
-	log.Println("Running clean migrations...")
+	// Users created before email verification existed are treated as verified
+	backfillEmailVerified := db.Migrator().HasTable(&models.User{}) && !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	// Tenants put on a plan before subscriptions existed get one
+	backfillSubscriptions := db.Migrator().HasTable(&models.Tenant{}) && !db.Migrator().HasTable(&models.Subscription{})
+
+	// Amounts stored as floats before money was kept in minor units are
+	// converted once the new columns exist
+	legacyMoney := legacyMoneyTables(db)
+
+	// AutoMigrate only adds missing tables, columns and indexes
+	log.Println("Running migrations...")
 	models := []interface{}{
 		&models.Tenant{},
 		&models.Plan{},
@@ -182,12 +214,32 @@ func Migrate(db *gorm.DB) error {
 		&models.Email{},
 		&models.Contact{},
 		&models.User{},
+		&models.UserSettings{},
 		&models.Customer{},
 		&models.TokenBlacklist{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.LoginThrottle{},
+		&models.Invitation{},
+		&models.TenantDomain{},
+		&models.CustomerContact{},
+		&models.Subscription{},
+		&models.Invoice{},
+		&models.InvoiceLineItem{},
+		&models.NumberingScheme{},
+		&models.NumberSequence{},
+		&models.CreditNote{},
+		&models.CreditNoteLineItem{},
 	}
 
 	for i, model := range models {
 		log.Printf("Migrating model %d: %T", i+1, model)
+		if err := addTenantColumn(db, model); err != nil {
+			return err
+		}
 		err := db.AutoMigrate(model)
 		if err != nil {
 			log.Printf("ERROR: Migration failed for model %T: %v", model, err)
@@ -196,10 +248,163 @@ func Migrate(db *gorm.DB) error {
 		log.Printf("Successfully migrated model %T", model)
 	}
 
+	// Install or remove the row-level security policies of tenant-scoped tables
+	if err := tenancy.MigratePolicies(db, models...); err != nil {
+		return err
+	}
+
+	if backfillEmailVerified {
+		log.Println("Marking existing users as email verified...")
+		if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			return fmt.Errorf("failed to backfill email verification: %w", err)
+		}
+	}
+
+	if backfillSubscriptions {
+		// Their first period ends right away, so the scheduler renews it
+		// with the plan's invoice period
+		log.Println("Subscribing existing tenants to their plan...")
+		now := time.Now()
+		if err := db.Exec(`INSERT INTO subscriptions (created_at, updated_at, tenant_id, plan_id, status, started_at, current_period_start, current_period_end, cancel_at_period_end)
+			SELECT ?, ?, id, plan_id, 'active', created_at, ?, ?, ? FROM tenants WHERE plan_id IS NOT NULL AND deleted_at IS NULL`,
+			now, now, now, now, false).Error; err != nil {
+			return fmt.Errorf("failed to backfill subscriptions: %w", err)
+		}
+	}
+
+	if len(legacyMoney) > 0 {
+		log.Println("Converting amounts to minor units...")
+		if err := convertLegacyMoney(db, legacyMoney); err != nil {
+			return err
+		}
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
 
+// legacyMoneyTable describes a table whose amounts were stored as float
+// columns in major units, next to a currency column of the row or its parent
+type legacyMoneyTable struct {
+	table    string
+	columns  []string // float columns, each replaced by <column>_amount and <column>_currency
+	currency string   // SQL expression of the row's currency
+	parent   string   // table holding the currency column, if not the table itself
+}
+
+// legacyMoneyColumns lists the tables that had float amounts. Line items come
+// before their parents, whose currency column they read.
+var legacyMoneyColumns = []legacyMoneyTable{
+	{table: "plans", columns: []string{"price"}, currency: "currency"},
+	{table: "invoice_line_items", columns: []string{"unit_price", "total"}, parent: "invoices",
+		currency: "(SELECT invoices.currency FROM invoices WHERE invoices.id = invoice_line_items.invoice_id)"},
+	{table: "invoices", columns: []string{"subtotal", "tax_amount", "total"}, currency: "currency"},
+	{table: "credit_note_line_items", columns: []string{"unit_price", "total"}, parent: "credit_notes",
+		currency: "(SELECT credit_notes.currency FROM credit_notes WHERE credit_notes.id = credit_note_line_items.credit_note_id)"},
+	{table: "credit_notes", columns: []string{"subtotal", "tax_amount", "total"}, currency: "currency"},
+}
+
+// legacyMoneyTables returns the tables that still have float amounts
+func legacyMoneyTables(db *gorm.DB) []legacyMoneyTable {
+	var tables []legacyMoneyTable
+	for _, table := range legacyMoneyColumns {
+		if db.Migrator().HasTable(table.table) && db.Migrator().HasColumn(table.table, table.columns[0]) {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// convertLegacyMoney fills the amount and currency columns of tables with
+// float amounts, rounding to the minor unit of each currency, then drops the
+// float and currency columns
+func convertLegacyMoney(db *gorm.DB, tables []legacyMoneyTable) error {
+	for _, table := range tables {
+		source := table.table
+		if table.parent != "" {
+			source = table.parent
+		}
+		var currencies []string
+		if err := db.Table(source).Distinct("currency").Pluck("currency", &currencies).Error; err != nil {
+			return fmt.Errorf("failed to read currencies of %s: %w", source, err)
+		}
+
+		for _, code := range currencies {
+			currency := money.Currency(strings.ToUpper(code))
+			if currency == "" {
+				currency = money.DefaultCurrency
+			}
+			factor := math.Pow10(currency.Digits())
+			for _, column := range table.columns {
+				query := fmt.Sprintf("UPDATE %s SET %s_amount = ROUND(%s * ?), %s_currency = ? WHERE %s = ?",
+					table.table, column, column, column, table.currency)
+				if err := db.Exec(query, factor, currency, code).Error; err != nil {
+					return fmt.Errorf("failed to convert %s.%s: %w", table.table, column, err)
+				}
+			}
+		}
+	}
+
+	for _, table := range tables {
+		columns := table.columns
+		if table.parent == "" {
+			columns = append(columns, "currency")
+		}
+		for _, column := range columns {
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table.table, column)).Error; err != nil {
+				return fmt.Errorf("failed to drop %s.%s: %w", table.table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addTenantColumn prepares existing tables of models that became tenant-owned.
+// It adds a nullable tenant_id column and assigns all rows to the default
+// tenant, so AutoMigrate can then make the column NOT NULL.
+func addTenantColumn(db *gorm.DB, model interface{}) error {
+	if _, ok := model.(models.TenantScoped); !ok {
+		return nil
+	}
+	if !db.Migrator().HasTable(model) || db.Migrator().HasColumn(model, "TenantID") {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return fmt.Errorf("failed to parse model %T: %w", model, err)
+	}
+
+	tenantID, err := defaultTenantID(db)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Assigning existing %s to tenant %d...", stmt.Schema.Table, tenantID)
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN tenant_id bigint", stmt.Schema.Table)).Error; err != nil {
+		return fmt.Errorf("failed to add tenant_id to %s: %w", stmt.Schema.Table, err)
+	}
+	if err := db.Exec(fmt.Sprintf("UPDATE %s SET tenant_id = ?", stmt.Schema.Table), tenantID).Error; err != nil {
+		return fmt.Errorf("failed to assign %s to tenant %d: %w", stmt.Schema.Table, tenantID, err)
+	}
+	return nil
+}
+
+// defaultTenantID returns the oldest tenant, which takes over data created
+// before it was tenant-owned. A "Default" tenant is created if there is none.
+func defaultTenantID(db *gorm.DB) (uint, error) {
+	var tenant models.Tenant
+	err := db.Order("id ASC").First(&tenant).Error
+	if err == gorm.ErrRecordNotFound {
+		tenant = models.Tenant{Name: "Default", Slug: "default"}
+		err = db.Create(&tenant).Error
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find default tenant: %w", err)
+	}
+	return tenant.ID, nil
+}
+
 // loadSeedData loads seed data from JSON file
 func loadSeedData() (*SeedData, error) {
 	// Get the current working directory
@@ -207,7 +412,7 @@ func loadSeedData() (*SeedData, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current directory: %w", err)
 	}
-	
+
 	// Look for seed-data.json in current directory or parent directories
 	seedDataPath := filepath.Join(pwd, "seed-data.json")
 	if _, err := os.Stat(seedDataPath); os.IsNotExist(err) {
@@ -264,22 +469,24 @@ func Seed(db *gorm.DB) error {
 	db.Model(&models.Plan{}).Count(&planCount)
 	if planCount == 0 {
 		for _, planData := range seedData.Plans {
-			// Convert features map to JSON string
-			featuresJSON, err := json.Marshal(planData.Features)
-			if err != nil {
-				return fmt.Errorf("failed to marshal features for plan %s: %w", planData.Name, err)
+			if err := planData.Features.Validate(); err != nil {
+				return fmt.Errorf("invalid features for plan %s: %w", planData.Name, err)
+			}
+
+			currency := money.Currency(strings.ToUpper(planData.Currency))
+			if currency == "" {
+				currency = money.DefaultCurrency
 			}
 
 			plan := models.Plan{
 				Name:          planData.Name,
 				Slug:          planData.Slug,
 				Description:   planData.Description,
-				Price:         planData.Price,
-				Currency:      planData.Currency,
+				Price:         money.FromFloat(planData.Price, currency),
 				InvoicePeriod: planData.InvoicePeriod,
 				MaxUsers:      planData.MaxUsers,
 				MaxClients:    planData.MaxClients,
-				Features:      string(featuresJSON),
+				Features:      planData.Features,
 				Active:        planData.Active,
 			}
 			if err := db.Create(&plan).Error; err != nil {
@@ -306,15 +513,18 @@ func Seed(db *gorm.DB) error {
 				return fmt.Errorf("failed to hash password for user %s: %w", userData.Username, err)
 			}
 
+			// Seeded accounts are trusted and don't need to verify their email
+			verifiedAt := time.Now()
 			user := models.User{
-				Username:     userData.Username,
-				Email:        userData.Email,
-				PasswordHash: string(hashedPassword),
-				FirstName:    userData.FirstName,
-				LastName:     userData.LastName,
-				TenantID:     tenant.ID,
-				Role:         userData.Role,
-				Active:       userData.Active,
+				Username:        userData.Username,
+				Email:           userData.Email,
+				PasswordHash:    string(hashedPassword),
+				FirstName:       userData.FirstName,
+				LastName:        userData.LastName,
+				TenantID:        tenant.ID,
+				Role:            userData.Role,
+				Active:          userData.Active,
+				EmailVerifiedAt: &verifiedAt,
 			}
 
 			if err := db.Create(&user).Error; err != nil {
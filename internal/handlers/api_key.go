@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are recognisable in logs and secret scanners
+	apiKeyPrefix = "ak_"
+	// apiKeyDisplayLength is the number of leading characters stored to identify a key
+	apiKeyDisplayLength = 11
+)
+
+type APIKeyHandler struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
+	return &APIKeyHandler{db: db}
+}
+
+// GetAPIKeys retrieves the API keys of the admin's tenant
+// @Summary Get all API keys
+// @Description Get a paginated list of API keys for the authenticated tenant. Keys themselves are never returned
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var apiKeys []models.APIKey
+	var total int64
+
+	query := h.db.Model(&models.APIKey{}).Where("tenant_id = ?", user.TenantID)
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count API keys", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve API keys", err.Error()))
+		return
+	}
+
+	// Convert to response format
+	var responses []models.APIKeyResponse
+	for _, apiKey := range apiKeys {
+		responses = append(responses, apiKey.ToResponse())
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("API keys retrieved successfully", response))
+}
+
+// CreateAPIKey creates a new API key for the admin's tenant
+// @Summary Create a new API key
+// @Description Create a named, scoped API key. The key is returned only in this response; only its hash is stored
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.APIKeyCreateRequest true "API key creation data"
+// @Success 201 {object} models.APIResponse{data=models.APIKeyCreateResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	var req models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid scope", "Unknown scope "+scope))
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid expiry", "expires_at must be in the future"))
+		return
+	}
+
+	secret, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate API key", err.Error()))
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		TenantID:    user.TenantID,
+		CreatedByID: user.ID,
+		Name:        req.Name,
+		Prefix:      key[:apiKeyDisplayLength],
+		KeyHash:     auth.HashToken(key),
+		Scopes:      strings.Join(req.Scopes, ","),
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create API key", err.Error()))
+		return
+	}
+
+	response := models.APIKeyCreateResponse{
+		APIKeyResponse: apiKey.ToResponse(),
+		Key:            key,
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("API key created successfully", response))
+}
+
+// RevokeAPIKey revokes an API key of the admin's tenant
+// @Summary Revoke an API key
+// @Description Revoke an API key by ID within the authenticated tenant. Revoked keys are rejected immediately
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIResponse{data=models.APIKeyResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid API key ID", err.Error()))
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := h.db.Where("id = ? AND tenant_id = ?", id, user.TenantID).First(&apiKey).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("API key not found", "API key with specified ID does not exist"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve API key", err.Error()))
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		if err := h.db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke API key", err.Error()))
+			return
+		}
+		apiKey.RevokedAt = &now
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("API key revoked successfully", apiKey.ToResponse()))
+}
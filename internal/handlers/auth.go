@@ -1,34 +1,62 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
 	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	mailer "github.com/ae-saas-basic/ae-saas-basic/services"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// dummyPasswordHash is compared against when a login names an unknown user,
+// so the response time doesn't reveal whether the account exists
+const dummyPasswordHash = "$2a$10$8sSB6W5HK9FHg96r9uY0CuCFypHWykP3R93zJR7Ip8n7aHwS3UIFq"
+
 type AuthHandler struct {
-	db *gorm.DB
+	db              *gorm.DB
+	cfg             config.AuthConfig
+	tokenService    *services.TokenService
+	throttleService *services.LoginThrottleService
+	entitlements    *services.EntitlementService
+	subscriptions   *services.SubscriptionService
+	emailService    *mailer.EmailService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, cfg config.AuthConfig, tokenService *services.TokenService, throttleService *services.LoginThrottleService, entitlements *services.EntitlementService, subscriptions *services.SubscriptionService) *AuthHandler {
+	return &AuthHandler{
+		db:              db,
+		cfg:             cfg,
+		tokenService:    tokenService,
+		throttleService: throttleService,
+		entitlements:    entitlements,
+		subscriptions:   subscriptions,
+		emailService:    mailer.NewEmailService(),
+	}
 }
 
-// Login authenticates a user and returns a JWT token
+// Login authenticates a user and returns an access and refresh token
 // @Summary Login user
-// @Description Authenticate user with username/email and password
+// @Description Authenticate user with username/email and password. Returns a short-lived access token and a refresh token for the device, or an MFA challenge for users with two-factor authentication
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body models.LoginRequest true "Login credentials"
 // @Success 200 {object} models.APIResponse{data=models.LoginResponse}
+// @Success 200 {object} models.APIResponse{data=models.MFAChallengeResponse}
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -37,43 +65,104 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
+	// Reject clients that failed too often before touching the account
+	if h.rejectThrottled(c, models.ThrottleScopeIP, c.ClientIP()) {
+		return
+	}
+
 	// Find user by username or email
-	if err := h.db.Where("username = ? OR email = ?", req.Username, req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid credentials", "User not found"))
+	var found *models.User
+	var user models.User
+	if err := h.db.Where("username = ? OR email = ?", req.Username, req.Username).First(&user).Error; err == nil {
+		found = &user
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve user", err.Error()))
 		return
 	}
 
-	// Check if user is active
-	if !user.Active {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Account disabled", "User account is not active"))
+	accountKey := services.AccountKey(found, req.Username)
+	if h.rejectThrottled(c, models.ThrottleScopeAccount, accountKey) {
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid credentials", "Password mismatch"))
+	// Verify password; unknown users are checked against a dummy hash so both
+	// cases take the same time and get the same response
+	passwordHash := dummyPasswordHash
+	if found != nil {
+		passwordHash = found.PasswordHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil || found == nil {
+		if err := h.throttleService.RecordFailure(accountKey, c.ClientIP()); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid credentials", "Invalid username or password"))
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateJWT(user.ID, user.TenantID, user.Role)
+	if h.rejectDisabledAccount(c, &user) {
+		return
+	}
+
+	// Users with two-factor authentication get a challenge instead of tokens;
+	// their failed attempts are only forgotten once the second factor succeeds
+	if user.IsTwoFactorEnabled() {
+		challenge, err := h.mfaChallenge(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create MFA challenge", err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, models.SuccessResponse("Two-factor authentication required", challenge))
+		return
+	}
+
+	if err := h.throttleService.Reset(accountKey); err != nil {
+		log.Printf("Failed to reset login throttle for user %d: %v", user.ID, err)
+	}
+
+	// Generate access and refresh tokens
+	pair, err := h.tokenService.IssueTokenPair(&user, clientInfo(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate token", err.Error()))
 		return
 	}
 
-	response := models.LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+	c.JSON(http.StatusOK, models.SuccessResponse("Login successful", loginResponse(pair, &user)))
+}
+
+// Refresh exchanges a refresh token for a new token pair
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token. The presented refresh token is rotated; replaying it revokes all tokens of that login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.APIResponse{data=models.LoginResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse("Login successful", response))
+	pair, user, err := h.tokenService.Rotate(req.RefreshToken, clientInfo(c, ""))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid refresh token", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to refresh token", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Token refreshed successfully", loginResponse(pair, user)))
 }
 
 // Logout blacklists the current JWT token
 // @Summary Logout user
-// @Description Blacklist the current JWT token
+// @Description Blacklist the current JWT token and revoke its refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -119,27 +208,46 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
+	// Revoke the refresh tokens issued together with this access token
+	if err := h.tokenService.RevokeByAccessTokenID(tokenID, "User logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to logout", err.Error()))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse("Logout successful", nil))
 }
 
 // Register creates a new user account
 // @Summary Register new user
-// @Description Create a new user account
+// @Description Create a new user account in an existing tenant and send an email verification link. Self-registered users always get the user role; admins join through invitations
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body models.UserCreateRequest true "User registration data"
 // @Success 201 {object} models.APIResponse{data=models.UserResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 409 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
+	if !h.cfg.OpenRegistration {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Registration disabled", "Ask a tenant admin for an invitation"))
+		return
+	}
+
 	var req models.UserCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
 		return
 	}
 
+	// Elevated roles are only granted by admins
+	if req.Role != "" && req.Role != models.RoleUser {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Invalid role", "Role "+req.Role+" can't be self-assigned"))
+		return
+	}
+
 	// Check if username or email already exists
 	var existingUser models.User
 	if err := h.db.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
@@ -154,6 +262,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Respect the user limit of the tenant's plan
+	if rejectLimit(c, h.entitlements, tenant.ID, services.LimitUsers) {
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -161,11 +274,6 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Set default role if not provided
-	if req.Role == "" {
-		req.Role = "user"
-	}
-
 	// Create user
 	user := models.User{
 		Username:     req.Username,
@@ -173,7 +281,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		PasswordHash: string(hashedPassword),
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
-		Role:         req.Role,
+		Role:         models.RoleUser,
 		TenantID:     req.TenantID,
 		Active:       true,
 	}
@@ -183,6 +291,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Send the email verification link
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	// Note: Tenant relation temporarily disabled due to GORM relation issues
 	// h.db.Preload("Tenant").First(&user, user.ID)
 
@@ -268,3 +381,104 @@ func (h *AuthHandler) Me(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.SuccessResponse("User retrieved successfully", user.ToResponse()))
 }
+
+// UnlockUser clears the failed login attempts of a user in the admin's tenant
+// @Summary Unlock user
+// @Description Lift the login lockout of a user within the authenticated admin's tenant
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return
+	}
+
+	if err := h.throttleService.Reset(services.UserAccountKey(target.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to unlock user", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User unlocked successfully", nil))
+}
+
+// rejectThrottled answers with 429 if login attempts for the scope and identifier
+// are currently blocked. It returns true when the request was rejected.
+func (h *AuthHandler) rejectThrottled(c *gin.Context, scope, identifier string) bool {
+	retryAfter, err := h.throttleService.RetryAfter(scope, identifier)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check login attempts", err.Error()))
+		return true
+	}
+	if retryAfter <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponseFunc("Too many login attempts", fmt.Sprintf("Try again in %d seconds", seconds)))
+	return true
+}
+
+// currentPrincipal returns the authenticated user or API key set by the auth middleware
+func currentPrincipal(c *gin.Context) (*models.Principal, bool) {
+	principalInterface, exists := c.Get("principal")
+	if !exists {
+		return nil, false
+	}
+	principal, ok := principalInterface.(*models.Principal)
+	return principal, ok
+}
+
+// rejectDisabledAccount responds and returns true when the user is inactive
+// or belongs to a deleted or suspended tenant, none of whom can log in
+func (h *AuthHandler) rejectDisabledAccount(c *gin.Context, user *models.User) bool {
+	if !user.Active {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Account disabled", "User account is not active"))
+		return true
+	}
+
+	var tenant models.Tenant
+	if err := h.db.Select("id", "status").First(&tenant, user.TenantID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Account disabled", "Tenant associated with user not found"))
+		return true
+	}
+	if tenant.IsSuspended() {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Tenant suspended", "The organisation's account is suspended"))
+		return true
+	}
+	return false
+}
+
+// tenantDB returns a session restricted to the tenant of the authenticated
+// principal, as carried by the request context. The tenancy callbacks limit
+// its queries, updates and deletes of tenant-scoped models to that tenant and
+// set it on creates, so handlers don't filter by tenant themselves.
+func tenantDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
+
+// clientInfo collects the device information stored with issued refresh tokens
+func clientInfo(c *gin.Context, deviceName string) services.ClientInfo {
+	return services.ClientInfo{
+		DeviceName: deviceName,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+}
+
+// loginResponse builds the login response from an issued token pair
+func loginResponse(pair *services.TokenPair, user *models.User) models.LoginResponse {
+	return models.LoginResponse{
+		Token:                 pair.AccessToken,
+		ExpiresAt:             pair.AccessTokenExpiresAt,
+		RefreshToken:          pair.RefreshToken,
+		RefreshTokenExpiresAt: pair.RefreshTokenExpiresAt,
+		User:                  user.ToResponse(),
+	}
+}
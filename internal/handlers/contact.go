@@ -9,27 +9,31 @@ import (
 	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ContactHandler struct {
 	db           *gorm.DB
 	emailService *services.EmailService
+	entitlements *services.EntitlementService
 }
 
 // NewContactHandler creates a new contact handler
-func NewContactHandler(db *gorm.DB) *ContactHandler {
+func NewContactHandler(db *gorm.DB, entitlements *services.EntitlementService) *ContactHandler {
 	return &ContactHandler{
 		db:           db,
 		emailService: services.NewEmailService(),
+		entitlements: entitlements,
 	}
 }
 
-// GetContacts retrieves all contacts with pagination
+// GetContacts retrieves all contacts with pagination and tenant isolation
 // @Summary Get all contacts
-// @Description Get a paginated list of all contacts
+// @Description Get a paginated list of contacts for the authenticated tenant with their customer links
 // @Tags contacts
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param active query bool false "Filter by active status"
@@ -44,7 +48,7 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	var contacts []models.Contact
 	var total int64
 
-	query := h.db.Model(&models.Contact{})
+	query := tenantDB(c, h.db).Model(&models.Contact{})
 
 	// Filter by active status if provided
 	if activeStr := c.Query("active"); activeStr != "" {
@@ -67,7 +71,7 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	}
 
 	// Get paginated results
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&contacts).Error; err != nil {
+	if err := query.Preload("Customers").Offset(offset).Limit(limit).Order("created_at DESC").Find(&contacts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contacts", err.Error()))
 		return
 	}
@@ -91,31 +95,21 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse("Contacts retrieved successfully", response))
 }
 
-// GetContact retrieves a specific contact by ID
+// GetContact retrieves a specific contact by ID with tenant isolation
 // @Summary Get contact by ID
-// @Description Get a specific contact by its ID
+// @Description Get a specific contact by its ID within the authenticated tenant, including its customer links
 // @Tags contacts
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Contact ID"
 // @Success 200 {object} models.APIResponse{data=models.ContactResponse}
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /contacts/{id} [get]
 func (h *ContactHandler) GetContact(c *gin.Context) {
-	id, err := utils.ValidateID(c, "id")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid contact ID", err.Error()))
-		return
-	}
-
-	var contact models.Contact
-	if err := h.db.First(&contact, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Contact not found", "Contact with specified ID does not exist"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contact", err.Error()))
+	contact, ok := h.findContact(c)
+	if !ok {
 		return
 	}
 
@@ -124,22 +118,35 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 
 // CreateContact creates a new contact
 // @Summary Create a new contact
-// @Description Create a new contact
+// @Description Create a new contact within the authenticated tenant
 // @Tags contacts
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param request body models.ContactCreateRequest true "Contact creation data"
 // @Success 201 {object} models.APIResponse{data=models.ContactResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
 // @Router /contacts [post]
 func (h *ContactHandler) CreateContact(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
 	var req models.ContactCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
 		return
 	}
 
+	// Respect the contact quota of the tenant's plan
+	if rejectLimit(c, h.entitlements, principal.TenantID, services.LimitContacts) {
+		return
+	}
+
 	// Set default type if not provided
 	contactType := req.Type
 	if contactType == "" {
@@ -161,7 +168,7 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		Active:    true,
 	}
 
-	if err := h.db.Create(&contact).Error; err != nil {
+	if err := tenantDB(c, h.db).Create(&contact).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create contact", err.Error()))
 		return
 	}
@@ -171,11 +178,12 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 
 // UpdateContact updates an existing contact
 // @Summary Update a contact
-// @Description Update an existing contact by ID
+// @Description Update an existing contact by ID within the authenticated tenant
 // @Tags contacts
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Contact ID"
 // @Param request body models.ContactUpdateRequest true "Contact update data"
 // @Success 200 {object} models.APIResponse{data=models.ContactResponse}
@@ -183,9 +191,8 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 // @Failure 404 {object} models.ErrorResponse
 // @Router /contacts/{id} [put]
 func (h *ContactHandler) UpdateContact(c *gin.Context) {
-	id, err := utils.ValidateID(c, "id")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid contact ID", err.Error()))
+	contact, ok := h.findContact(c)
+	if !ok {
 		return
 	}
 
@@ -195,16 +202,6 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		return
 	}
 
-	var contact models.Contact
-	if err := h.db.First(&contact, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Contact not found", "Contact with specified ID does not exist"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contact", err.Error()))
-		return
-	}
-
 	// Update fields if provided
 	if req.FirstName != "" {
 		contact.FirstName = req.FirstName
@@ -243,7 +240,7 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		contact.Active = *req.Active
 	}
 
-	if err := h.db.Save(&contact).Error; err != nil {
+	if err := tenantDB(c, h.db).Omit(clause.Associations).Save(contact).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update contact", err.Error()))
 		return
 	}
@@ -253,33 +250,29 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 
 // DeleteContact deletes a contact (soft delete)
 // @Summary Delete a contact
-// @Description Soft delete a contact by ID
+// @Description Soft delete a contact by ID within the authenticated tenant and remove its customer links
 // @Tags contacts
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Contact ID"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /contacts/{id} [delete]
 func (h *ContactHandler) DeleteContact(c *gin.Context) {
-	id, err := utils.ValidateID(c, "id")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid contact ID", err.Error()))
+	contact, ok := h.findContact(c)
+	if !ok {
 		return
 	}
 
-	var contact models.Contact
-	if err := h.db.First(&contact, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Contact not found", "Contact with specified ID does not exist"))
-			return
+	err := tenantDB(c, h.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&models.CustomerContact{}).Error; err != nil {
+			return err
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contact", err.Error()))
-		return
-	}
-
-	if err := h.db.Delete(&contact).Error; err != nil {
+		return tx.Delete(contact).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to delete contact", err.Error()))
 		return
 	}
@@ -289,10 +282,11 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 
 // SubmitContactForm handles contact form submissions
 // @Summary Submit contact form
-// @Description Submit a contact form and optionally subscribe to newsletter
+// @Description Submit a contact form to the tenant resolved for the request and optionally subscribe to its newsletter
 // @Tags contact
 // @Accept json
 // @Produce json
+// @Param X-Tenant header string false "Tenant slug, unless the host selects the tenant"
 // @Param contactForm body models.ContactFormRequest true "Contact form data"
 // @Success 200 {object} models.ContactFormResponse
 // @Failure 400 {object} models.ErrorResponse
@@ -306,6 +300,13 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 		return
 	}
 
+	// Submissions belong to the tenant the form is served for
+	if _, exists := c.Get("tenant"); !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tenant required: address the form to a tenant's domain or set the X-Tenant header"})
+		return
+	}
+	db := tenantDB(c, h.db)
+
 	// Set timestamp if not provided
 	if req.Timestamp == "" {
 		req.Timestamp = time.Now().Format(time.RFC3339)
@@ -342,11 +343,11 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 		// Check if email already exists in newsletter
 		var existingNewsletter models.Newsletter
 		var count int64
-		h.db.Model(&models.Newsletter{}).Where("email = ?", req.Email).Count(&count)
+		db.Model(&models.Newsletter{}).Where("email = ?", req.Email).Count(&count)
 
 		if count == 0 {
 			// Create new newsletter subscription
-			if err := h.db.Create(&newsletter).Error; err != nil {
+			if err := db.Create(&newsletter).Error; err != nil {
 				// Don't fail the whole request if newsletter signup fails
 				response.NewsletterAdded = false
 				response.NewsletterMessage = "Contact form sent, but newsletter subscription failed"
@@ -356,14 +357,14 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 			}
 		} else {
 			// Update existing subscription
-			result := h.db.Where("email = ?", req.Email).First(&existingNewsletter)
+			result := db.Where("email = ?", req.Email).First(&existingNewsletter)
 			if result.Error == nil {
 				existingNewsletter.Name = req.Name
 				existingNewsletter.Interest = req.Subject
 				existingNewsletter.Source = req.Source
 				existingNewsletter.LastContact = time.Now()
 
-				if err := h.db.Save(&existingNewsletter).Error; err != nil {
+				if err := db.Save(&existingNewsletter).Error; err != nil {
 					response.NewsletterAdded = false
 					response.NewsletterMessage = "Contact form sent, but newsletter update failed"
 				} else {
@@ -381,20 +382,21 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GetNewsletterSubscriptions gets all newsletter subscriptions (admin only)
+// GetNewsletterSubscriptions gets the newsletter subscriptions of the tenant (admin only)
 // @Summary Get newsletter subscriptions
-// @Description Get all newsletter subscriptions for admin users
+// @Description Get all newsletter subscriptions of the authenticated tenant
 // @Tags contact
 // @Accept json
 // @Produce json
 // @Success 200 {array} models.Newsletter
 // @Failure 500 {object} models.ErrorResponse
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /contact/newsletter [get]
 func (h *ContactHandler) GetNewsletterSubscriptions(c *gin.Context) {
 	var newsletters []models.Newsletter
 
-	if err := h.db.Find(&newsletters).Error; err != nil {
+	if err := tenantDB(c, h.db).Find(&newsletters).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch newsletter subscriptions"})
 		return
 	}
@@ -404,10 +406,12 @@ func (h *ContactHandler) GetNewsletterSubscriptions(c *gin.Context) {
 
 // UnsubscribeFromNewsletter handles newsletter unsubscription
 // @Summary Unsubscribe from newsletter
-// @Description Unsubscribe an email from the newsletter
+// @Description Unsubscribe an email from the authenticated tenant's newsletter
 // @Tags contact
 // @Accept json
 // @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param email query string true "Email to unsubscribe"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} models.ErrorResponse
@@ -422,7 +426,7 @@ func (h *ContactHandler) UnsubscribeFromNewsletter(c *gin.Context) {
 	}
 
 	// Soft delete the newsletter subscription
-	result := h.db.Where("email = ?", email).Delete(&models.Newsletter{})
+	result := tenantDB(c, h.db).Where("email = ?", email).Delete(&models.Newsletter{})
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe from newsletter"})
 		return
@@ -435,3 +439,25 @@ func (h *ContactHandler) UnsubscribeFromNewsletter(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully unsubscribed from newsletter"})
 }
+
+// findContact loads the contact of the principal's tenant referenced by the
+// path together with its customer links
+func (h *ContactHandler) findContact(c *gin.Context) (*models.Contact, bool) {
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid contact ID", err.Error()))
+		return nil, false
+	}
+
+	var contact models.Contact
+	if err := tenantDB(c, h.db).Preload("Customers").First(&contact, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Contact not found", "Contact with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contact", err.Error()))
+		return nil, false
+	}
+
+	return &contact, true
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CancelInvoice cancels an invoice with a credit note
+// @Summary Cancel invoice
+// @Description Cancel an invoice of the authenticated tenant by issuing a credit note for everything not credited yet. Unpaid invoices become void
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Invoice ID"
+// @Param request body models.InvoiceCancelRequest false "Reason"
+// @Success 201 {object} models.APIResponse{data=models.CreditNoteResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/invoices/{id}/cancel [post]
+func (h *InvoiceHandler) CancelInvoice(c *gin.Context) {
+	invoice, ok := h.findInvoice(c)
+	if !ok {
+		return
+	}
+
+	var req models.InvoiceCancelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+			return
+		}
+	}
+
+	note, err := h.invoices.Cancel(invoice, req.Reason)
+	h.respondWithCreditNote(c, "Invoice cancelled successfully", note, err)
+}
+
+// CreateCreditNote credits lines of an invoice
+// @Summary Create credit note
+// @Description Issue a credit note for quantities of lines of an invoice of the authenticated tenant. Unpaid invoices credited in full become void
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Invoice ID"
+// @Param request body models.CreditNoteCreateRequest true "Credited lines"
+// @Success 201 {object} models.APIResponse{data=models.CreditNoteResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/invoices/{id}/credit-notes [post]
+func (h *InvoiceHandler) CreateCreditNote(c *gin.Context) {
+	invoice, ok := h.findInvoice(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreditNoteCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	note, err := h.invoices.Credit(invoice, req.Reason, req.Items)
+	h.respondWithCreditNote(c, "Credit note created successfully", note, err)
+}
+
+// GetCreditNotes retrieves the credit notes of the tenant with pagination
+// @Summary Get all credit notes
+// @Description Get a paginated list of credit notes of the authenticated tenant, newest first
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param invoice_id query int false "Filter by invoice"
+// @Param customer_id query int false "Filter by customer"
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /credit-notes [get]
+func (h *InvoiceHandler) GetCreditNotes(c *gin.Context) {
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var notes []models.CreditNote
+	var total int64
+
+	query := tenantDB(c, h.db).Model(&models.CreditNote{})
+	if invoiceID := c.Query("invoice_id"); invoiceID != "" {
+		query = query.Where("invoice_id = ?", invoiceID)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count credit notes", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Offset(offset).Limit(limit).Order("issued_at DESC, id DESC").Find(&notes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve credit notes", err.Error()))
+		return
+	}
+
+	responses := make([]models.CreditNoteResponse, 0, len(notes))
+	for _, note := range notes {
+		responses = append(responses, note.ToResponse())
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Credit notes retrieved successfully", response))
+}
+
+// GetCreditNote retrieves a specific credit note by ID
+// @Summary Get credit note by ID
+// @Description Get a credit note of the authenticated tenant with its lines
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Credit note ID"
+// @Success 200 {object} models.APIResponse{data=models.CreditNoteResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /credit-notes/{id} [get]
+func (h *InvoiceHandler) GetCreditNote(c *gin.Context) {
+	note, ok := h.findCreditNote(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Credit note retrieved successfully", note.ToResponse()))
+}
+
+// DownloadCreditNote returns the PDF of a credit note
+// @Summary Download credit note
+// @Description Download the PDF of a credit note of the authenticated tenant, rendering it if it hasn't been rendered yet
+// @Tags invoices
+// @Produce application/pdf
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Credit note ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /credit-notes/{id}/download [get]
+func (h *InvoiceHandler) DownloadCreditNote(c *gin.Context) {
+	note, ok := h.findCreditNote(c)
+	if !ok {
+		return
+	}
+
+	pdf, err := h.invoices.CreditNotePDF(note)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to render credit note", err.Error()))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", note.Number+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetCustomerBalance retrieves what a customer owes
+// @Summary Get customer balance
+// @Description Get the invoiced, credited, paid and outstanding amounts of a customer of the authenticated tenant per currency. Credit notes reduce the outstanding amount; a negative one is owed to the customer
+// @Tags customers
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Customer ID"
+// @Success 200 {object} models.APIResponse{data=[]models.CustomerBalance}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /customers/{id}/balance [get]
+func (h *InvoiceHandler) GetCustomerBalance(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid customer ID", err.Error()))
+		return
+	}
+
+	var customer models.Customer
+	if err := tenantDB(c, h.db).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Customer not found", "Customer with specified ID does not exist"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve customer", err.Error()))
+		return
+	}
+
+	balances, err := h.invoices.Balances(principal.TenantID, customer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to calculate balance", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Customer balance retrieved successfully", balances))
+}
+
+// respondWithCreditNote writes the newly issued credit note, or the error
+// response matching a service error
+func (h *InvoiceHandler) respondWithCreditNote(c *gin.Context, message string, note *models.CreditNote, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, models.SuccessResponse(message, note.ToResponse()))
+	case errors.Is(err, services.ErrNothingToCredit):
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Invoice already credited", err.Error()))
+	case errors.Is(err, services.ErrUnknownInvoiceLine), errors.Is(err, services.ErrCreditExceedsInvoice):
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid credit note", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to issue credit note", err.Error()))
+	}
+}
+
+// findCreditNote loads the credit note named by the id path parameter within
+// the tenant, answering with an error response if that fails
+func (h *InvoiceHandler) findCreditNote(c *gin.Context) (*models.CreditNote, bool) {
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid credit note ID", err.Error()))
+		return nil, false
+	}
+
+	var note models.CreditNote
+	if err := tenantDB(c, h.db).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&note, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Credit note not found", "Credit note with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve credit note", err.Error()))
+		return nil, false
+	}
+	return &note, true
+}
@@ -1,29 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
 	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/vat"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CustomerHandler struct {
-	db *gorm.DB
+	db           *gorm.DB
+	entitlements *services.EntitlementService
 }
 
 // NewCustomerHandler creates a new customer handler
-func NewCustomerHandler(db *gorm.DB) *CustomerHandler {
-	return &CustomerHandler{db: db}
+func NewCustomerHandler(db *gorm.DB, entitlements *services.EntitlementService) *CustomerHandler {
+	return &CustomerHandler{
+		db:           db,
+		entitlements: entitlements,
+	}
 }
 
 // GetCustomers retrieves all customers with pagination and tenant isolation
 // @Summary Get all customers
-// @Description Get a paginated list of customers for the authenticated tenant
+// @Description Get a paginated list of customers for the authenticated tenant with their linked contacts
 // @Tags customers
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param active query bool false "Filter by active status"
@@ -31,13 +41,10 @@ func NewCustomerHandler(db *gorm.DB) *CustomerHandler {
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customers [get]
 func (h *CustomerHandler) GetCustomers(c *gin.Context) {
-	// Get user from context for tenant isolation
-	userInterface, exists := c.Get("user")
-	if !exists {
+	if _, exists := currentPrincipal(c); !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 		return
 	}
-	user := userInterface.(*models.User)
 
 	page, limit := utils.GetPaginationParams(c)
 	offset := utils.GetOffset(page, limit)
@@ -45,7 +52,7 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 	var customers []models.Customer
 	var total int64
 
-	query := h.db.Model(&models.Customer{}).Where("tenant_id = ?", user.TenantID)
+	query := tenantDB(c, h.db).Model(&models.Customer{})
 
 	// Filter by active status if provided
 	if activeStr := c.Query("active"); activeStr != "" {
@@ -64,7 +71,7 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 
 	// Get paginated results with preloaded relationships
 	// Note: Tenant and Plan relations temporarily disabled due to GORM relation issues
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&customers).Error; err != nil {
+	if err := query.Preload("Contacts").Offset(offset).Limit(limit).Order("created_at DESC").Find(&customers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve customers", err.Error()))
 		return
 	}
@@ -90,23 +97,21 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 
 // GetCustomer retrieves a specific customer by ID with tenant isolation
 // @Summary Get customer by ID
-// @Description Get a specific customer by its ID within the authenticated tenant
+// @Description Get a specific customer by its ID within the authenticated tenant, including its linked contacts
 // @Tags customers
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Customer ID"
 // @Success 200 {object} models.APIResponse{data=models.CustomerResponse}
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /customers/{id} [get]
 func (h *CustomerHandler) GetCustomer(c *gin.Context) {
-	// Get user from context for tenant isolation
-	userInterface, exists := c.Get("user")
-	if !exists {
+	if _, exists := currentPrincipal(c); !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 		return
 	}
-	user := userInterface.(*models.User)
 
 	id, err := utils.ValidateID(c, "id")
 	if err != nil {
@@ -116,7 +121,7 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 
 	var customer models.Customer
 	// Note: Plan and Tenant relations temporarily disabled due to GORM relation issues
-	if err := h.db.Where("id = ? AND tenant_id = ?", id, user.TenantID).First(&customer).Error; err != nil {
+	if err := tenantDB(c, h.db).Preload("Contacts").First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Customer not found", "Customer with specified ID does not exist"))
 			return
@@ -135,27 +140,33 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param request body models.CustomerCreateRequest true "Customer creation data"
 // @Success 201 {object} models.APIResponse{data=models.CustomerResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
 // @Router /customers [post]
 func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
-	// Get user from context for tenant isolation
-	userInterface, exists := c.Get("user")
+	principal, exists := currentPrincipal(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 		return
 	}
-	user := userInterface.(*models.User)
 
 	var req models.CustomerCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
 		return
 	}
+	vatID, ok := validVATID(c, req.VAT)
+	if !ok {
+		return
+	}
 
-	// Ensure customer is created within user's organization
-	req.TenantID = user.TenantID
+	// Respect the customer limit of the tenant's plan
+	if rejectLimit(c, h.entitlements, principal.TenantID, services.LimitCustomers) {
+		return
+	}
 
 	// Verify the plan exists
 	var plan models.Plan
@@ -173,15 +184,15 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		City:          req.City,
 		Country:       req.Country,
 		TaxID:         req.TaxID,
-		VAT:           req.VAT,
+		VAT:           vatID,
 		PlanID:        req.PlanID,
-		TenantID:      req.TenantID,
 		Status:        "active",
 		PaymentMethod: req.PaymentMethod,
 		Active:        true,
 	}
 
-	if err := h.db.Create(&customer).Error; err != nil {
+	// The customer is created within the principal's tenant
+	if err := tenantDB(c, h.db).Create(&customer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create customer", err.Error()))
 		return
 	}
@@ -199,6 +210,7 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Customer ID"
 // @Param request body models.CustomerUpdateRequest true "Customer update data"
 // @Success 200 {object} models.APIResponse{data=models.CustomerResponse}
@@ -206,13 +218,10 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 // @Failure 404 {object} models.ErrorResponse
 // @Router /customers/{id} [put]
 func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
-	// Get user from context for tenant isolation
-	userInterface, exists := c.Get("user")
-	if !exists {
+	if _, exists := currentPrincipal(c); !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 		return
 	}
-	user := userInterface.(*models.User)
 
 	id, err := utils.ValidateID(c, "id")
 	if err != nil {
@@ -227,7 +236,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	}
 
 	var customer models.Customer
-	if err := h.db.Where("id = ? AND tenant_id = ?", id, user.TenantID).First(&customer).Error; err != nil {
+	if err := tenantDB(c, h.db).Preload("Contacts").First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Customer not found", "Customer with specified ID does not exist"))
 			return
@@ -262,7 +271,11 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		customer.TaxID = req.TaxID
 	}
 	if req.VAT != "" {
-		customer.VAT = req.VAT
+		vatID, ok := validVATID(c, req.VAT)
+		if !ok {
+			return
+		}
+		customer.VAT = vatID
 	}
 	if req.PlanID != nil {
 		// Verify the plan exists
@@ -283,7 +296,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		customer.Active = *req.Active
 	}
 
-	if err := h.db.Save(&customer).Error; err != nil {
+	if err := tenantDB(c, h.db).Omit(clause.Associations).Save(&customer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update customer", err.Error()))
 		return
 	}
@@ -296,23 +309,21 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 
 // DeleteCustomer deletes a customer (soft delete)
 // @Summary Delete a customer
-// @Description Soft delete a customer by ID within the authenticated tenant
+// @Description Soft delete a customer by ID within the authenticated tenant and remove its contact links
 // @Tags customers
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Customer ID"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /customers/{id} [delete]
 func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
-	// Get user from context for tenant isolation
-	userInterface, exists := c.Get("user")
-	if !exists {
+	if _, exists := currentPrincipal(c); !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 		return
 	}
-	user := userInterface.(*models.User)
 
 	id, err := utils.ValidateID(c, "id")
 	if err != nil {
@@ -321,7 +332,7 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 	}
 
 	var customer models.Customer
-	if err := h.db.Where("id = ? AND tenant_id = ?", id, user.TenantID).First(&customer).Error; err != nil {
+	if err := tenantDB(c, h.db).First(&customer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Customer not found", "Customer with specified ID does not exist"))
 			return
@@ -330,10 +341,214 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&customer).Error; err != nil {
+	err = tenantDB(c, h.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id = ?", customer.ID).Delete(&models.CustomerContact{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&customer).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to delete customer", err.Error()))
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse("Customer deleted successfully", nil))
 }
+
+// GetCustomerContacts lists the contacts linked to a customer
+// @Summary Get customer contacts
+// @Description Get the contacts linked to a customer with their roles
+// @Tags customers
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Customer ID"
+// @Success 200 {object} models.APIResponse{data=[]models.ContactResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /customers/{id}/contacts [get]
+func (h *CustomerHandler) GetCustomerContacts(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+
+	var contacts []models.Contact
+	if err := tenantDB(c, h.db).
+		Where("id IN (?)", tenantDB(c, h.db).Model(&models.CustomerContact{}).Select("contact_id").Where("customer_id = ?", customer.ID)).
+		Preload("Customers").
+		Order("last_name, first_name").
+		Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contacts", err.Error()))
+		return
+	}
+
+	responses := make([]models.ContactResponse, 0, len(contacts))
+	for _, contact := range contacts {
+		responses = append(responses, contact.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Customer contacts retrieved successfully", responses))
+}
+
+// LinkContact links a contact to a customer in a role
+// @Summary Link a contact to a customer
+// @Description Link a contact of the authenticated tenant to a customer as primary, billing or technical contact
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Customer ID"
+// @Param request body models.CustomerContactRequest true "Contact and role"
+// @Success 201 {object} models.APIResponse{data=models.CustomerContactResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /customers/{id}/contacts [post]
+func (h *CustomerHandler) LinkContact(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+
+	var req models.CustomerContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	if !models.IsValidContactRole(req.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "Role must be one of: primary, billing, technical"))
+		return
+	}
+
+	// The contact must belong to the same tenant as the customer
+	var contact models.Contact
+	if err := tenantDB(c, h.db).First(&contact, req.ContactID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Contact not found", "Contact with specified ID does not exist"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve contact", err.Error()))
+		return
+	}
+
+	var count int64
+	if err := tenantDB(c, h.db).Model(&models.CustomerContact{}).
+		Where("customer_id = ? AND contact_id = ? AND role = ?", customer.ID, contact.ID, req.Role).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to link contact", err.Error()))
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Contact already linked", "Contact is already linked to the customer with this role"))
+		return
+	}
+
+	link := models.CustomerContact{
+		CustomerID: customer.ID,
+		ContactID:  contact.ID,
+		Role:       req.Role,
+	}
+	if err := tenantDB(c, h.db).Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to link contact", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("Contact linked successfully", link.ToResponse()))
+}
+
+// UnlinkContact removes a contact from a customer
+// @Summary Unlink a contact from a customer
+// @Description Remove the links between a customer and a contact, optionally only the link with the given role
+// @Tags customers
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Customer ID"
+// @Param contact_id path int true "Contact ID"
+// @Param role query string false "Only remove the link with this role"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /customers/{id}/contacts/{contact_id} [delete]
+func (h *CustomerHandler) UnlinkContact(c *gin.Context) {
+	customer, ok := h.findCustomer(c)
+	if !ok {
+		return
+	}
+
+	contactID, err := utils.ValidateID(c, "contact_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid contact ID", err.Error()))
+		return
+	}
+
+	query := tenantDB(c, h.db).Where("customer_id = ? AND contact_id = ?", customer.ID, contactID)
+	if role := c.Query("role"); role != "" {
+		if !models.IsValidContactRole(role) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "Role must be one of: primary, billing, technical"))
+			return
+		}
+		query = query.Where("role = ?", role)
+	}
+
+	result := query.Delete(&models.CustomerContact{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to unlink contact", result.Error.Error()))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Link not found", "Contact is not linked to the customer"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Contact unlinked successfully", nil))
+}
+
+// findCustomer loads the customer of the principal's tenant referenced by the path
+func (h *CustomerHandler) findCustomer(c *gin.Context) (*models.Customer, bool) {
+	if _, exists := currentPrincipal(c); !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return nil, false
+	}
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid customer ID", err.Error()))
+		return nil, false
+	}
+
+	var customer models.Customer
+	if err := tenantDB(c, h.db).First(&customer, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Customer not found", "Customer with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve customer", err.Error()))
+		return nil, false
+	}
+
+	return &customer, true
+}
+
+// validVATID checks the check digits of a VAT ID of an EU member state,
+// answering with an error response if they don't match. Valid VAT IDs are
+// returned without separators; VAT IDs of other countries are kept as entered.
+func validVATID(c *gin.Context, vatID string) (string, bool) {
+	vatID = strings.TrimSpace(vatID)
+	if vatID == "" {
+		return "", true
+	}
+
+	id, err := vat.ParseID(vatID)
+	if errors.Is(err, vat.ErrUnknownPrefix) {
+		return vatID, true
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid VAT ID", err.Error()))
+		return "", false
+	}
+	return id.String(), true
+}
@@ -18,12 +18,13 @@ func NewEmailHandler(db *gorm.DB) *EmailHandler {
 	return &EmailHandler{db: db}
 }
 
-// GetEmails retrieves all emails with pagination
+// GetEmails retrieves all emails with pagination and tenant isolation
 // @Summary Get all emails
-// @Description Get a paginated list of all emails
+// @Description Get a paginated list of emails of the authenticated tenant
 // @Tags emails
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param status query string false "Filter by email status"
@@ -37,7 +38,7 @@ func (h *EmailHandler) GetEmails(c *gin.Context) {
 	var emails []models.Email
 	var total int64
 
-	query := h.db.Model(&models.Email{})
+	query := tenantDB(c, h.db).Model(&models.Email{})
 
 	// Filter by status if provided
 	if status := c.Query("status"); status != "" {
@@ -75,12 +76,13 @@ func (h *EmailHandler) GetEmails(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse("Emails retrieved successfully", response))
 }
 
-// GetEmail retrieves a specific email by ID
+// GetEmail retrieves a specific email by ID with tenant isolation
 // @Summary Get email by ID
-// @Description Get a specific email by its ID
+// @Description Get a specific email by its ID within the authenticated tenant
 // @Tags emails
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Email ID"
 // @Success 200 {object} models.APIResponse{data=models.EmailResponse}
 // @Failure 400 {object} models.ErrorResponse
@@ -94,7 +96,7 @@ func (h *EmailHandler) GetEmail(c *gin.Context) {
 	}
 
 	var email models.Email
-	if err := h.db.First(&email, id).Error; err != nil {
+	if err := tenantDB(c, h.db).First(&email, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Email not found", "Email with specified ID does not exist"))
 			return
@@ -108,11 +110,12 @@ func (h *EmailHandler) GetEmail(c *gin.Context) {
 
 // SendEmail creates and queues an email for sending
 // @Summary Send an email
-// @Description Create and queue an email for sending
+// @Description Create and queue an email for sending on behalf of the authenticated tenant
 // @Tags emails
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param request body models.EmailSendRequest true "Email send data"
 // @Success 201 {object} models.APIResponse{data=models.EmailResponse}
 // @Failure 400 {object} models.ErrorResponse
@@ -133,7 +136,7 @@ func (h *EmailHandler) SendEmail(c *gin.Context) {
 		Status:   "pending",
 	}
 
-	if err := h.db.Create(&email).Error; err != nil {
+	if err := tenantDB(c, h.db).Create(&email).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create email", err.Error()))
 		return
 	}
@@ -147,10 +150,11 @@ func (h *EmailHandler) SendEmail(c *gin.Context) {
 
 // GetEmailStats retrieves email statistics
 // @Summary Get email statistics
-// @Description Get email statistics including counts by status
+// @Description Get email statistics of the authenticated tenant including counts by status
 // @Tags emails
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Success 200 {object} models.APIResponse{data=object}
 // @Failure 500 {object} models.ErrorResponse
 // @Router /emails/stats [get]
@@ -164,15 +168,16 @@ func (h *EmailHandler) GetEmailStats(c *gin.Context) {
 	}
 
 	var stats EmailStats
+	db := tenantDB(c, h.db)
 
 	// Count total emails
-	h.db.Model(&models.Email{}).Count(&stats.Total)
+	db.Model(&models.Email{}).Count(&stats.Total)
 
 	// Count by status
-	h.db.Model(&models.Email{}).Where("status = ?", "pending").Count(&stats.Pending)
-	h.db.Model(&models.Email{}).Where("status = ?", "sent").Count(&stats.Sent)
-	h.db.Model(&models.Email{}).Where("status = ?", "delivered").Count(&stats.Delivered)
-	h.db.Model(&models.Email{}).Where("status = ?", "failed").Count(&stats.Failed)
+	db.Model(&models.Email{}).Where("status = ?", "pending").Count(&stats.Pending)
+	db.Model(&models.Email{}).Where("status = ?", "sent").Count(&stats.Sent)
+	db.Model(&models.Email{}).Where("status = ?", "delivered").Count(&stats.Delivered)
+	db.Model(&models.Email{}).Where("status = ?", "failed").Count(&stats.Failed)
 
 	c.JSON(http.StatusOK, models.SuccessResponse("Email statistics retrieved successfully", stats))
 }
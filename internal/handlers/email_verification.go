@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// emailVerificationPurpose scopes signed tokens to email verification links
+const emailVerificationPurpose = "email_verification"
+
+// VerifyEmail confirms a user's email address using a signed verification link
+// @Summary Verify email address
+// @Description Confirm the email address of an account using the signed token from the verification email
+// @Tags auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/verify-email [get]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", "Token parameter is required"))
+		return
+	}
+
+	subject, err := auth.ParseSignedToken(emailVerificationPurpose, token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid verification link", err.Error()))
+		return
+	}
+
+	// The subject binds the link to the user and the address it was sent to
+	userID, email, err := parseVerificationSubject(subject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid verification link", err.Error()))
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid verification link", "User not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve user", err.Error()))
+		return
+	}
+
+	if !strings.EqualFold(user.Email, email) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid verification link", "Email address has changed since the link was sent"))
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		if err := h.db.Model(&user).Update("email_verified_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify email", err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Email verified successfully", user.ToResponse()))
+}
+
+// ResendVerification sends a new verification email to the current user
+// @Summary Resend verification email
+// @Description Send a new email verification link to the authenticated user. Requests are throttled
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	if user.IsEmailVerified() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Email already verified", "The email address is already verified"))
+		return
+	}
+
+	cooldown := time.Duration(h.cfg.VerificationResendSecond) * time.Second
+	if user.VerificationSentAt != nil {
+		if wait := time.Until(user.VerificationSentAt.Add(cooldown)); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, models.ErrorResponseFunc("Too many requests", "Please wait before requesting another verification email"))
+			return
+		}
+	}
+
+	if err := h.sendVerificationEmail(user); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to send verification email", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Verification email sent", nil))
+}
+
+// sendVerificationEmail records the send time and emails a signed verification link
+func (h *AuthHandler) sendVerificationEmail(user *models.User) error {
+	ttl := time.Duration(h.cfg.EmailVerificationExpiryHour) * time.Hour
+	token, err := auth.GenerateSignedToken(emailVerificationPurpose, fmt.Sprintf("%d:%s", user.ID, user.Email), ttl)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := h.db.Model(user).Update("verification_sent_at", now).Error; err != nil {
+		return err
+	}
+
+	verificationURL := h.frontendURL("/verify-email", url.Values{"token": {token}})
+	recipientName := user.FirstName + " " + user.LastName
+	to := user.Email
+	userID := user.ID
+
+	go func() {
+		if err := h.emailService.SendVerificationEmail(to, recipientName, verificationURL); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", userID, err)
+		}
+	}()
+
+	return nil
+}
+
+// parseVerificationSubject splits a verification subject into user ID and email
+func parseVerificationSubject(subject string) (uint, string, error) {
+	parts := strings.SplitN(subject, ":", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed token subject")
+	}
+
+	userID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed token subject")
+	}
+
+	return uint(userID), parts[1], nil
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// rejectLimit answers with 402 and the limit that was hit if the tenant's plan
+// allows no further resource of the kind. It returns true when the request
+// was rejected.
+func rejectLimit(c *gin.Context, entitlements *services.EntitlementService, tenantID uint, limit string) bool {
+	err := entitlements.CheckLimit(tenantID, limit)
+	if err == nil {
+		return false
+	}
+
+	var entitlementErr *services.EntitlementError
+	if errors.As(err, &entitlementErr) {
+		c.JSON(entitlementErr.HTTPStatus(), models.ErrorResponseWithDetails("Plan limit reached", entitlementErr.Error(), entitlementErr))
+		return true
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check plan limit", err.Error()))
+	return true
+}
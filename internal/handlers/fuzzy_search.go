@@ -9,6 +9,7 @@ import (
 
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
 	"github.com/gin-gonic/gin"
 )
 
@@ -78,15 +79,19 @@ func (h *FuzzySearchHandler) Search(c *gin.Context) {
 
 	// Get user context for permission filtering
 	var userID *uint
-	var organizationID *uint
+	var tenantID *uint
 
 	if userInterface, exists := c.Get("user"); exists {
 		if user, ok := userInterface.(*models.User); ok {
 			userID = &user.ID
-			organizationID = &user.TenantID
 		}
 	}
 
+	// Only the resolved tenant is searched; without one there are no results
+	if id, ok := tenancy.FromContext(c.Request.Context()); ok {
+		tenantID = &id
+	}
+
 	// Build search options
 	searchOptions := services.SearchOptions{
 		Query:               req.Query,
@@ -97,7 +102,7 @@ func (h *FuzzySearchHandler) Search(c *gin.Context) {
 		Offset:              req.Offset,
 		Limit:               req.Limit,
 		UserID:              userID,
-		TenantID:      organizationID,
+		TenantID:            tenantID,
 		IncludeCount:        req.IncludeCount,
 		IncludeAggregations: req.IncludeAggregations,
 		HighlightFields:     req.HighlightFields,
@@ -150,15 +155,19 @@ func (h *FuzzySearchHandler) QuickSearch(c *gin.Context) {
 
 	// Get user context
 	var userID *uint
-	var organizationID *uint
+	var tenantID *uint
 
 	if userInterface, exists := c.Get("user"); exists {
 		if user, ok := userInterface.(*models.User); ok {
 			userID = &user.ID
-			organizationID = &user.TenantID
 		}
 	}
 
+	// Only the resolved tenant is searched; without one there are no results
+	if id, ok := tenancy.FromContext(c.Request.Context()); ok {
+		tenantID = &id
+	}
+
 	// Build search options
 	searchOptions := services.SearchOptions{
 		Query:          query,
@@ -166,7 +175,7 @@ func (h *FuzzySearchHandler) QuickSearch(c *gin.Context) {
 		Offset:         offset,
 		Limit:          limit,
 		UserID:         userID,
-		TenantID: organizationID,
+		TenantID:       tenantID,
 	}
 
 	// Perform search
@@ -216,15 +225,19 @@ func (h *FuzzySearchHandler) SearchInEntity(c *gin.Context) {
 
 	// Get user context
 	var userID *uint
-	var organizationID *uint
+	var tenantID *uint
 
 	if userInterface, exists := c.Get("user"); exists {
 		if user, ok := userInterface.(*models.User); ok {
 			userID = &user.ID
-			organizationID = &user.TenantID
 		}
 	}
 
+	// Only the resolved tenant is searched; without one there are no results
+	if id, ok := tenancy.FromContext(c.Request.Context()); ok {
+		tenantID = &id
+	}
+
 	// Build search options
 	searchOptions := services.SearchOptions{
 		Query:               req.Query,
@@ -235,7 +248,7 @@ func (h *FuzzySearchHandler) SearchInEntity(c *gin.Context) {
 		Offset:              req.Offset,
 		Limit:               req.Limit,
 		UserID:              userID,
-		TenantID:      organizationID,
+		TenantID:            tenantID,
 		IncludeCount:        req.IncludeCount,
 		IncludeAggregations: req.IncludeAggregations,
 		HighlightFields:     req.HighlightFields,
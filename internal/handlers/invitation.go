@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// invitationPurpose scopes signed tokens to invitation links
+const invitationPurpose = "invitation"
+
+// errInvitationInvalid is returned when an invitation link is unknown, superseded, used, revoked or expired
+var errInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+// GetInvitations retrieves the invitations of the admin's tenant
+// @Summary Get all invitations
+// @Description Get a paginated list of invitations for the authenticated admin's tenant
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (pending, accepted, revoked, expired)"
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/invitations [get]
+func (h *AuthHandler) GetInvitations(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	admin := userInterface.(*models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var invitations []models.Invitation
+	var total int64
+
+	query := h.db.Model(&models.Invitation{}).Where("tenant_id = ?", admin.TenantID)
+
+	// Filter by status if provided
+	now := time.Now()
+	switch c.Query("status") {
+	case models.InvitationStatusPending:
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case models.InvitationStatusAccepted:
+		query = query.Where("accepted_at IS NOT NULL")
+	case models.InvitationStatusRevoked:
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NOT NULL")
+	case models.InvitationStatusExpired:
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count invitations", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve invitations", err.Error()))
+		return
+	}
+
+	// Convert to response format
+	var responses []models.InvitationResponse
+	for _, invitation := range invitations {
+		responses = append(responses, invitation.ToResponse())
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invitations retrieved successfully", response))
+}
+
+// CreateInvitation invites an email address to join the admin's tenant
+// @Summary Invite a user
+// @Description Send an invitation link to an email address. The recipient joins the tenant with the given role once they accept
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.InvitationCreateRequest true "Invitation data"
+// @Success 201 {object} models.APIResponse{data=models.InvitationResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /admin/invitations [post]
+func (h *AuthHandler) CreateInvitation(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	admin := userInterface.(*models.User)
+
+	var req models.InvitationCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Set default role if not provided
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+	if !canAssignRole(admin, req.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "Role "+req.Role+" can't be assigned"))
+		return
+	}
+
+	// Email addresses are unique across tenants
+	var count int64
+	if err := h.db.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", req.Email).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check user", err.Error()))
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("User already exists", "An account with this email already exists"))
+		return
+	}
+
+	if err := h.db.Model(&models.Invitation{}).
+		Where("tenant_id = ? AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", admin.TenantID, req.Email, time.Now()).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check invitations", err.Error()))
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Invitation already pending", "Resend the pending invitation instead"))
+		return
+	}
+
+	if rejectLimit(c, h.entitlements, admin.TenantID, services.LimitUsers) {
+		return
+	}
+
+	invitation := models.Invitation{
+		TenantID:    admin.TenantID,
+		InvitedByID: admin.ID,
+		Email:       req.Email,
+		Role:        req.Role,
+	}
+	if err := h.sendInvitation(&invitation, admin); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("Invitation sent successfully", invitation.ToResponse()))
+}
+
+// ResendInvitation sends a fresh link for an invitation of the admin's tenant
+// @Summary Resend an invitation
+// @Description Send a new invitation link with a new expiry. Earlier links stop working
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} models.APIResponse{data=models.InvitationResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/invitations/{id}/resend [post]
+func (h *AuthHandler) ResendInvitation(c *gin.Context) {
+	admin, invitation, ok := h.tenantInvitation(c)
+	if !ok {
+		return
+	}
+
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invitation closed", "Invitation was already "+invitation.Status()))
+		return
+	}
+
+	if err := h.sendInvitation(invitation, admin); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to resend invitation", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invitation resent successfully", invitation.ToResponse()))
+}
+
+// RevokeInvitation revokes a pending invitation of the admin's tenant
+// @Summary Revoke an invitation
+// @Description Revoke an invitation by ID within the authenticated admin's tenant. Its link stops working immediately
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} models.APIResponse{data=models.InvitationResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/invitations/{id} [delete]
+func (h *AuthHandler) RevokeInvitation(c *gin.Context) {
+	_, invitation, ok := h.tenantInvitation(c)
+	if !ok {
+		return
+	}
+
+	if invitation.AcceptedAt != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invitation closed", "Invitation was already accepted"))
+		return
+	}
+
+	if invitation.RevokedAt == nil {
+		now := time.Now()
+		if err := h.db.Model(invitation).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke invitation", err.Error()))
+			return
+		}
+		invitation.RevokedAt = &now
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invitation revoked successfully", invitation.ToResponse()))
+}
+
+// AcceptInvitation creates the invited user's account
+// @Summary Accept an invitation
+// @Description Create an account in the inviting tenant using the signed token from the invitation email. The email address counts as verified
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.InvitationAcceptRequest true "Invitation token and account data"
+// @Success 201 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /auth/invitations/accept [post]
+func (h *AuthHandler) AcceptInvitation(c *gin.Context) {
+	var req models.InvitationAcceptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	invitation, err := h.invitationForToken(req.Token)
+	if err != nil {
+		if errors.Is(err, errInvitationInvalid) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid invitation link", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve invitation", err.Error()))
+		return
+	}
+
+	// Check if username or email already exists
+	var count int64
+	if err := h.db.Unscoped().Model(&models.User{}).
+		Where("username = ? OR LOWER(email) = ?", req.Username, invitation.Email).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check user", err.Error()))
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("User already exists", "Username or email already taken"))
+		return
+	}
+
+	if rejectLimit(c, h.entitlements, invitation.TenantID, services.LimitUsers) {
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to hash password", err.Error()))
+		return
+	}
+
+	// The invitation link proves ownership of the email address
+	now := time.Now()
+	user := models.User{
+		Username:        req.Username,
+		Email:           invitation.Email,
+		PasswordHash:    string(hashedPassword),
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		Role:            invitation.Role,
+		TenantID:        invitation.TenantID,
+		Active:          true,
+		EmailVerifiedAt: &now,
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		// Consume the invitation; a concurrent request using the same link loses here
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_user_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errInvitationInvalid) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid invitation link", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create user", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("Invitation accepted successfully", user.ToResponse()))
+}
+
+// tenantInvitation loads the invitation from the id path parameter, restricted to
+// the current admin's tenant. It writes the error response when it returns false.
+func (h *AuthHandler) tenantInvitation(c *gin.Context) (*models.User, *models.Invitation, bool) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return nil, nil, false
+	}
+	admin := userInterface.(*models.User)
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid invitation ID", err.Error()))
+		return nil, nil, false
+	}
+
+	var invitation models.Invitation
+	if err := h.db.Where("id = ? AND tenant_id = ?", id, admin.TenantID).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Invitation not found", "Invitation with specified ID does not exist"))
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve invitation", err.Error()))
+		return nil, nil, false
+	}
+
+	return admin, &invitation, true
+}
+
+// sendInvitation issues a new link for the invitation, saves it and emails the link
+func (h *AuthHandler) sendInvitation(invitation *models.Invitation, inviter *models.User) error {
+	var tenant models.Tenant
+	if err := h.db.First(&tenant, invitation.TenantID).Error; err != nil {
+		return err
+	}
+
+	// The invitation needs an ID before a token can be bound to it
+	if invitation.ID == 0 {
+		if err := h.db.Create(invitation).Error; err != nil {
+			return err
+		}
+	}
+
+	ttl := time.Duration(h.cfg.InvitationExpiryHour) * time.Hour
+	token, err := auth.GenerateSignedToken(invitationPurpose, strconv.FormatUint(uint64(invitation.ID), 10), ttl)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	invitation.TokenHash = auth.HashToken(token)
+	invitation.ExpiresAt = now.Add(ttl)
+	invitation.SentAt = now
+	if err := h.db.Save(invitation).Error; err != nil {
+		return err
+	}
+
+	invitationURL := h.frontendURL("/accept-invitation", url.Values{"token": {token}})
+	inviterName := inviter.FirstName + " " + inviter.LastName
+	to := invitation.Email
+	invitationID := invitation.ID
+
+	go func() {
+		if err := h.emailService.SendInvitationEmail(to, inviterName, tenant.Name, invitationURL); err != nil {
+			log.Printf("Failed to send invitation %d: %v", invitationID, err)
+		}
+	}()
+
+	return nil
+}
+
+// invitationForToken returns the pending invitation an invitation link belongs to
+func (h *AuthHandler) invitationForToken(token string) (*models.Invitation, error) {
+	subject, err := auth.ParseSignedToken(invitationPurpose, token)
+	if err != nil {
+		return nil, errInvitationInvalid
+	}
+	invitationID, err := strconv.ParseUint(subject, 10, 32)
+	if err != nil {
+		return nil, errInvitationInvalid
+	}
+
+	var invitation models.Invitation
+	if err := h.db.First(&invitation, uint(invitationID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvitationInvalid
+		}
+		return nil, err
+	}
+
+	// Only the most recently sent link is valid
+	if invitation.TokenHash != auth.HashToken(token) || invitation.Status() != models.InvitationStatusPending {
+		return nil, errInvitationInvalid
+	}
+
+	return &invitation, nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InvoiceHandler struct {
+	db            *gorm.DB
+	invoices      *services.InvoiceService
+	subscriptions *services.SubscriptionService
+}
+
+// NewInvoiceHandler creates a new invoice handler
+func NewInvoiceHandler(db *gorm.DB, invoices *services.InvoiceService, subscriptions *services.SubscriptionService) *InvoiceHandler {
+	return &InvoiceHandler{
+		db:            db,
+		invoices:      invoices,
+		subscriptions: subscriptions,
+	}
+}
+
+// GetInvoices retrieves the invoices of the tenant with pagination
+// @Summary Get all invoices
+// @Description Get a paginated list of invoices of the authenticated tenant, newest first
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param customer_id query int false "Filter by customer"
+// @Param status query string false "Filter by status (open, paid, void)"
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invoices [get]
+func (h *InvoiceHandler) GetInvoices(c *gin.Context) {
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var invoices []models.Invoice
+	var total int64
+
+	query := tenantDB(c, h.db).Model(&models.Invoice{})
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count invoices", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Offset(offset).Limit(limit).Order("issued_at DESC, id DESC").Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve invoices", err.Error()))
+		return
+	}
+
+	responses := make([]models.InvoiceResponse, 0, len(invoices))
+	for _, invoice := range invoices {
+		responses = append(responses, invoice.ToResponse())
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invoices retrieved successfully", response))
+}
+
+// GetInvoice retrieves a specific invoice by ID
+// @Summary Get invoice by ID
+// @Description Get an invoice of the authenticated tenant with its line items
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.APIResponse{data=models.InvoiceResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /invoices/{id} [get]
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	invoice, ok := h.findInvoice(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invoice retrieved successfully", invoice.ToResponse()))
+}
+
+// DownloadInvoice returns the PDF of an invoice
+// @Summary Download invoice
+// @Description Download the PDF of an invoice of the authenticated tenant, rendering it if it hasn't been rendered yet
+// @Tags invoices
+// @Produce application/pdf
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /invoices/{id}/download [get]
+func (h *InvoiceHandler) DownloadInvoice(c *gin.Context) {
+	invoice, ok := h.findInvoice(c)
+	if !ok {
+		return
+	}
+
+	pdf, err := h.invoices.PDF(invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to render invoice", err.Error()))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.Number+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// MarkInvoicePaid records the payment of an invoice
+// @Summary Mark invoice as paid
+// @Description Record that an open invoice of the authenticated tenant has been paid, at the given time or now. A past due subscription of a tenant billed through the customer becomes active again once none of the customer's invoices is overdue
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Param request body models.InvoicePaymentRequest false "Payment time"
+// @Success 200 {object} models.APIResponse{data=models.InvoiceResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/invoices/{id}/pay [post]
+func (h *InvoiceHandler) MarkInvoicePaid(c *gin.Context) {
+	invoice, ok := h.findInvoice(c)
+	if !ok {
+		return
+	}
+
+	var req models.InvoicePaymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+			return
+		}
+	}
+	paidAt := time.Now()
+	if req.PaidAt != nil {
+		paidAt = *req.PaidAt
+	}
+
+	if err := h.invoices.MarkPaid(invoice, paidAt); err != nil {
+		if errors.Is(err, services.ErrInvoiceNotOpen) {
+			c.JSON(http.StatusConflict, models.ErrorResponseFunc("Invoice is not open", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to mark invoice as paid", err.Error()))
+		return
+	}
+
+	// Reactivate the subscription of a tenant billed through the customer;
+	// the scheduler retries if this fails
+	if _, err := h.subscriptions.SyncPayments(time.Now(), invoice.CustomerID); err != nil {
+		log.Printf("Failed to sync subscription payments of customer %d: %v", invoice.CustomerID, err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Invoice marked as paid", invoice.ToResponse()))
+}
+
+// findInvoice loads the invoice named by the id path parameter within the
+// tenant, answering with an error response if that fails
+func (h *InvoiceHandler) findInvoice(c *gin.Context) (*models.Invoice, bool) {
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid invoice ID", err.Error()))
+		return nil, false
+	}
+
+	var invoice models.Invoice
+	if err := tenantDB(c, h.db).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&invoice, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Invoice not found", "Invoice with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve invoice", err.Error()))
+		return nil, false
+	}
+	return &invoice, true
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS returns the public keys for verifying access tokens
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens signed with RS256 or EdDSA, identified by kid. The response uses the standard JWKS format (RFC 7517) rather than the API envelope
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Verifiers may cache the keys; new keys are published before they sign
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.PublicJWKS())
+}
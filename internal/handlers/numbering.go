@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type NumberingHandler struct {
+	numbering *services.NumberingService
+}
+
+// NewNumberingHandler creates a new numbering handler
+func NewNumberingHandler(numbering *services.NumberingService) *NumberingHandler {
+	return &NumberingHandler{numbering: numbering}
+}
+
+// GetNumberingSchemes retrieves how the admin's tenant numbers its documents
+// @Summary Get numbering schemes
+// @Description Get the numbering schemes of invoices and credit notes of the authenticated admin's tenant with the next number of each
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.NumberingSchemeResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/numbering [get]
+func (h *NumberingHandler) GetNumberingSchemes(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	schemes, err := h.numbering.Schemes(principal.TenantID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve numbering schemes", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Numbering schemes retrieved successfully", schemes))
+}
+
+// UpdateNumberingScheme changes how the admin's tenant numbers documents of a kind
+// @Summary Update numbering scheme
+// @Description Change the prefix, yearly reset or zero padding of the invoice or credit note numbers of the authenticated admin's tenant. Numbers already issued stay as they are
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param kind path string true "Document kind (invoice, credit_note)"
+// @Param request body models.NumberingSchemeUpdateRequest true "Numbering scheme"
+// @Success 200 {object} models.APIResponse{data=models.NumberingSchemeResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/numbering/{kind} [put]
+func (h *NumberingHandler) UpdateNumberingScheme(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	kind := c.Param("kind")
+	if !models.IsValidNumberingKind(kind) {
+		c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Numbering scheme not found", "Unknown document kind: "+kind))
+		return
+	}
+
+	var req models.NumberingSchemeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	scheme, err := h.numbering.UpdateScheme(principal.TenantID, kind, req)
+	if errors.Is(err, services.ErrNumberingStarted) {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Numbering has started", err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update numbering scheme", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Numbering scheme updated successfully", scheme))
+}
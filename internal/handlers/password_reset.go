@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// errResetTokenInvalid is returned when a reset token is unknown, used or expired
+var errResetTokenInvalid = errors.New("reset token is invalid or has expired")
+
+// ForgotPassword sends a password reset link to the given email address
+// @Summary Request password reset
+// @Description Send a single-use password reset link. The response is identical whether or not an account exists for the email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	// The lookup, token and email all happen in the background, so neither
+	// failures nor response timing reveal whether an account exists
+	go h.sendPasswordReset(req.Email, c.ClientIP())
+
+	c.JSON(http.StatusOK, models.SuccessResponse("If an account exists for this email, a password reset link has been sent", nil))
+}
+
+// sendPasswordReset issues a reset token for the active account with the given
+// email and mails the link. Failures are only logged
+func (h *AuthHandler) sendPasswordReset(email, requestIP string) {
+	var user models.User
+	if err := h.db.Where("email = ? AND active = ?", email, true).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Password reset lookup failed: %v", err)
+		}
+		return
+	}
+
+	token, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Only the most recently requested link stays valid
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		resetToken := models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: now.Add(time.Duration(h.cfg.PasswordResetExpiryMinute) * time.Minute),
+			RequestIP: requestIP,
+		}
+		return tx.Create(&resetToken).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	resetURL := h.frontendURL("/reset-password", url.Values{"token": {token}})
+	recipientName := user.FirstName + " " + user.LastName
+	if err := h.emailService.SendPasswordResetEmail(user.Email, recipientName, resetURL); err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+}
+
+// ResetPassword sets a new password using a password reset token
+// @Summary Reset password
+// @Description Set a new password using a reset token. All active sessions of the user are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to hash password", err.Error()))
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(req.Token), now).
+			First(&resetToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+
+		// Consume the token; a concurrent request using the same token loses here
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND active = ?", resetToken.UserID, true).
+			Update("password_hash", string(hashedPassword))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errResetTokenInvalid) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid reset token", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to reset password", err.Error()))
+		return
+	}
+
+	// Log the user out everywhere now that the password changed
+	if err := h.tokenService.RevokeAllForUser(resetToken.UserID, "Password reset"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke sessions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Password reset successfully", nil))
+}
+
+// frontendURL builds an absolute link into the frontend application
+func (h *AuthHandler) frontendURL(path string, query url.Values) string {
+	link := strings.TrimRight(h.cfg.FrontendURL, "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -78,6 +79,17 @@ func (h *PlanHandler) GetPlans(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse("Plans retrieved successfully", response))
 }
 
+// GetPlanFeatures lists the features plans can carry
+// @Summary Get plan features
+// @Description Get the definitions of the flags, quotas and enum values plans can carry, e.g. to build a pricing page
+// @Tags plans
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=[]models.FeatureDefinition}
+// @Router /plans/features [get]
+func (h *PlanHandler) GetPlanFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse("Plan features retrieved successfully", models.FeatureDefinitions()))
+}
+
 // GetPlan retrieves a specific plan by ID
 // @Summary Get plan by ID
 // @Description Get a specific plan by its ID
@@ -110,7 +122,7 @@ func (h *PlanHandler) GetPlan(c *gin.Context) {
 
 // CreatePlan creates a new plan
 // @Summary Create a new plan
-// @Description Create a new subscription plan
+// @Description Create a new subscription plan; plans are shared by all tenants, so only super-admins manage them
 // @Tags plans
 // @Accept json
 // @Produce json
@@ -118,6 +130,7 @@ func (h *PlanHandler) GetPlan(c *gin.Context) {
 // @Param request body models.PlanCreateRequest true "Plan creation data"
 // @Success 201 {object} models.APIResponse{data=models.PlanResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 409 {object} models.ErrorResponse
 // @Router /plans [post]
 func (h *PlanHandler) CreatePlan(c *gin.Context) {
@@ -127,6 +140,15 @@ func (h *PlanHandler) CreatePlan(c *gin.Context) {
 		return
 	}
 
+	if err := req.Features.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid features", err.Error()))
+		return
+	}
+	if req.Price.IsNegative() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid price", "Price cannot be negative"))
+		return
+	}
+
 	// Check if plan with same slug exists
 	var existingPlan models.Plan
 	if err := h.db.Where("slug = ?", req.Slug).First(&existingPlan).Error; err == nil {
@@ -135,8 +157,8 @@ func (h *PlanHandler) CreatePlan(c *gin.Context) {
 	}
 
 	// Set default values
-	if req.Currency == "" {
-		req.Currency = "EUR"
+	if req.Price.Currency == "" {
+		req.Price.Currency = money.DefaultCurrency
 	}
 	if req.InvoicePeriod == "" {
 		req.InvoicePeriod = "monthly"
@@ -158,7 +180,6 @@ func (h *PlanHandler) CreatePlan(c *gin.Context) {
 		Slug:          req.Slug,
 		Description:   req.Description,
 		Price:         req.Price,
-		Currency:      req.Currency,
 		InvoicePeriod: req.InvoicePeriod,
 		MaxUsers:      req.MaxUsers,
 		MaxClients:    req.MaxClients,
@@ -185,6 +206,7 @@ func (h *PlanHandler) CreatePlan(c *gin.Context) {
 // @Param request body models.PlanUpdateRequest true "Plan update data"
 // @Success 200 {object} models.APIResponse{data=models.PlanResponse}
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /plans/{id} [put]
 func (h *PlanHandler) UpdatePlan(c *gin.Context) {
@@ -200,6 +222,17 @@ func (h *PlanHandler) UpdatePlan(c *gin.Context) {
 		return
 	}
 
+	if req.Features != nil {
+		if err := req.Features.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid features", err.Error()))
+			return
+		}
+	}
+	if req.Price != nil && req.Price.IsNegative() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid price", "Price cannot be negative"))
+		return
+	}
+
 	var plan models.Plan
 	if err := h.db.First(&plan, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -218,11 +251,11 @@ func (h *PlanHandler) UpdatePlan(c *gin.Context) {
 		plan.Description = req.Description
 	}
 	if req.Price != nil {
+		if req.Price.Currency == "" {
+			req.Price.Currency = plan.Price.Currency
+		}
 		plan.Price = *req.Price
 	}
-	if req.Currency != "" {
-		plan.Currency = req.Currency
-	}
 	if req.InvoicePeriod != "" {
 		plan.InvoicePeriod = req.InvoicePeriod
 	}
@@ -232,8 +265,8 @@ func (h *PlanHandler) UpdatePlan(c *gin.Context) {
 	if req.MaxClients != nil {
 		plan.MaxClients = *req.MaxClients
 	}
-	if req.Features != "" {
-		plan.Features = req.Features
+	if req.Features != nil {
+		plan.Features = *req.Features
 	}
 	if req.Active != nil {
 		plan.Active = *req.Active
@@ -256,6 +289,7 @@ func (h *PlanHandler) UpdatePlan(c *gin.Context) {
 // @Param id path int true "Plan ID"
 // @Success 200 {object} models.APIResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /plans/{id} [delete]
 func (h *PlanHandler) DeletePlan(c *gin.Context) {
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSessions lists the active login sessions of the current user
+// @Summary List sessions
+// @Description List the devices the current user is logged in on. The session of the current request is marked as current
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.SessionResponse}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	responses, err := h.activeSessions(user.ID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve sessions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Sessions retrieved successfully", responses))
+}
+
+// RevokeSession logs the current user out of one of their sessions
+// @Summary Revoke session
+// @Description Log out a session of the current user by ID. Its access and refresh tokens stop working immediately
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid session ID", err.Error()))
+		return
+	}
+
+	h.revokeUserSession(c, user.ID, id, "Session revoked by user")
+}
+
+// RevokeOtherSessions logs the current user out everywhere else
+// @Summary Revoke other sessions
+// @Description Log out every session of the current user except the one making this request
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/sessions [delete]
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	sessionID := currentSessionID(c)
+	if sessionID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("No session found", "Token is not bound to a session, log in again"))
+		return
+	}
+
+	if err := h.tokenService.RevokeOtherSessions(user.ID, sessionID, "Logged out from another session"); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("No session found", "Current session does not exist"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke sessions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Other sessions revoked successfully", nil))
+}
+
+// GetUserSessions lists the active sessions of a user in the admin's tenant
+// @Summary List user sessions
+// @Description List the active sessions of a user within the authenticated admin's tenant
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse{data=[]models.SessionResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/sessions [get]
+func (h *AuthHandler) GetUserSessions(c *gin.Context) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return
+	}
+
+	responses, err := h.activeSessions(target.ID, currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve sessions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Sessions retrieved successfully", responses))
+}
+
+// RevokeUserSession revokes a session of a user in the admin's tenant
+// @Summary Revoke user session
+// @Description Log out a session of a user within the authenticated admin's tenant
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param session_id path int true "Session ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/sessions/{session_id} [delete]
+func (h *AuthHandler) RevokeUserSession(c *gin.Context) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return
+	}
+
+	sessionID, err := utils.ValidateID(c, "session_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid session ID", err.Error()))
+		return
+	}
+
+	h.revokeUserSession(c, target.ID, sessionID, "Session revoked by admin")
+}
+
+// RevokeUserSessions revokes every session of a user in the admin's tenant
+// @Summary Revoke all user sessions
+// @Description Log a user within the authenticated admin's tenant out of every session
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/sessions [delete]
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return
+	}
+
+	if err := h.tokenService.RevokeAllForUser(target.ID, "All sessions revoked by admin"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke sessions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Sessions revoked successfully", nil))
+}
+
+// activeSessions returns the active sessions of a user, most recently used first
+func (h *AuthHandler) activeSessions(userID, currentID uint) ([]models.SessionResponse, error) {
+	var sessions []models.Session
+	if err := h.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response := session.ToResponse()
+		response.Current = session.ID == currentID
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// revokeUserSession revokes a session after checking it belongs to the user
+func (h *AuthHandler) revokeUserSession(c *gin.Context, userID, sessionID uint, reason string) {
+	var session models.Session
+	if err := h.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Session not found", "Session with specified ID does not exist"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve session", err.Error()))
+		return
+	}
+
+	if err := h.tokenService.RevokeSession(&session, reason); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke session", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Session revoked successfully", nil))
+}
+
+// tenantUser loads the user from the id path parameter, restricted to the
+// current admin's tenant. It writes the error response when it returns false.
+func tenantUser(c *gin.Context, db *gorm.DB) (*models.User, bool) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return nil, false
+	}
+	admin := userInterface.(*models.User)
+
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid user ID", err.Error()))
+		return nil, false
+	}
+
+	var target models.User
+	if err := db.Where("id = ? AND tenant_id = ?", id, admin.TenantID).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("User not found", "User with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve user", err.Error()))
+		return nil, false
+	}
+
+	return &target, true
+}
+
+// currentSessionID returns the session of the access token used for the request
+func currentSessionID(c *gin.Context) uint {
+	claimsInterface, exists := c.Get("claims")
+	if !exists {
+		return 0
+	}
+	claims, ok := claimsInterface.(*auth.JWTClaims)
+	if !ok {
+		return 0
+	}
+	return claims.SessionID
+}
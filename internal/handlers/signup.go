@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// maxTenantSlugLength keeps slugs usable as DNS labels, including a numeric suffix
+const maxTenantSlugLength = 50
+
+// tenantSlugPattern matches slugs chosen by the signing-up organisation
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var (
+	// errSignupConflict is returned when the organisation, username or email is already taken
+	errSignupConflict = errors.New("already taken")
+	// errSlugTaken is returned when an explicitly requested slug is in use
+	errSlugTaken = errors.New("slug is already taken")
+)
+
+// Signup provisions a new organisation with its first admin
+// @Summary Sign up a new organisation
+// @Description Create a tenant, its first admin user with default settings a billing customer and a subscription to the chosen plan in a single transaction, then send a welcome email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.SignupRequest true "Organisation, owner and billing data"
+// @Success 201 {object} models.APIResponse{data=models.SignupResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /signup [post]
+func (h *AuthHandler) Signup(c *gin.Context) {
+	if !h.cfg.TenantSignup {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Signup disabled", "Self-service signup is not available"))
+		return
+	}
+
+	var req models.SignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	req.OrganizationName = strings.TrimSpace(req.OrganizationName)
+	if req.Slug != "" && (!tenantSlugPattern.MatchString(req.Slug) || len(req.Slug) > maxTenantSlugLength) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid slug", "Slug must be lowercase letters, digits and single dashes"))
+		return
+	}
+	vatID, ok := validVATID(c, req.VAT)
+	if !ok {
+		return
+	}
+
+	var plan models.Plan
+	if err := h.db.Where("id = ? AND active = ?", req.PlanID, true).First(&plan).Error; err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Plan not found", "Invalid plan ID"))
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to hash password", err.Error()))
+		return
+	}
+
+	billingEmail := req.BillingEmail
+	if billingEmail == "" {
+		billingEmail = req.Email
+	}
+
+	var tenant models.Tenant
+	var user models.User
+	var customer models.Customer
+	var subscription *models.Subscription
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Tenant{}).Where("name = ?", req.OrganizationName).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("organisation name %w", errSignupConflict)
+		}
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ? OR email = ?", req.Username, req.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("username or email %w", errSignupConflict)
+		}
+
+		slug, err := uniqueTenantSlug(tx, req.OrganizationName, req.Slug)
+		if err != nil {
+			return err
+		}
+
+		tenant = models.Tenant{
+			Name:   req.OrganizationName,
+			Slug:   slug,
+			PlanID: &plan.ID,
+		}
+		if err := tx.Create(&tenant).Error; err != nil {
+			return err
+		}
+
+		subscription, err = h.subscriptions.Subscribe(tx, tenant.ID, &plan)
+		if err != nil {
+			return err
+		}
+
+		user = models.User{
+			Username:     req.Username,
+			Email:        req.Email,
+			PasswordHash: string(hashedPassword),
+			FirstName:    req.FirstName,
+			LastName:     req.LastName,
+			Role:         models.RoleAdmin,
+			TenantID:     tenant.ID,
+			Active:       true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		settings := models.UserSettings{
+			UserID:   user.ID,
+			Language: "en",
+			Timezone: "UTC",
+			Theme:    "light",
+			Settings: "{}",
+		}
+		if err := tx.Omit("User").Create(&settings).Error; err != nil {
+			return err
+		}
+
+		customer = models.Customer{
+			Name:     req.OrganizationName,
+			Email:    billingEmail,
+			Street:   req.Street,
+			Zip:      req.Zip,
+			City:     req.City,
+			Country:  req.Country,
+			TaxID:    req.TaxID,
+			VAT:      vatID,
+			PlanID:   plan.ID,
+			TenantID: tenant.ID,
+			Status:   "active",
+			Active:   true,
+		}
+		return tenancy.WithTenant(tx, tenant.ID).Create(&customer).Error
+	})
+	if err != nil {
+		if errors.Is(err, errSignupConflict) || errors.Is(err, errSlugTaken) {
+			c.JSON(http.StatusConflict, models.ErrorResponseFunc("Signup conflict", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to sign up", err.Error()))
+		return
+	}
+
+	recipientName := user.FirstName + " " + user.LastName
+	to := user.Email
+	go func() {
+		if err := h.emailService.SendWelcomeEmail(to, recipientName); err != nil {
+			log.Printf("Failed to send welcome email to %s: %v", to, err)
+		}
+	}()
+
+	// Send the email verification link
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	response := models.SignupResponse{
+		Tenant:       tenant.ToResponse(),
+		User:         user.ToResponse(),
+		Customer:     customer.ToResponse(),
+		Subscription: subscription.ToResponse(),
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("Signup completed successfully", response))
+}
+
+// uniqueTenantSlug returns the requested slug if it is free, or derives a free
+// slug from the organisation name by appending a number
+func uniqueTenantSlug(tx *gorm.DB, name, requested string) (string, error) {
+	taken := func(slug string) (bool, error) {
+		var count int64
+		err := tx.Unscoped().Model(&models.Tenant{}).Where("slug = ?", slug).Count(&count).Error
+		return count > 0, err
+	}
+
+	if requested != "" {
+		exists, err := taken(requested)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", errSlugTaken
+		}
+		return requested, nil
+	}
+
+	base := utils.Slugify(name)
+	if len(base) > maxTenantSlugLength {
+		base = strings.TrimRight(base[:maxTenantSlugLength], "-")
+	}
+	if base == "" {
+		base = "tenant"
+	}
+
+	for i := 1; i <= 100; i++ {
+		slug := base
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		exists, err := taken(slug)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+	}
+
+	return "", fmt.Errorf("no free slug for %q", name)
+}
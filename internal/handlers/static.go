@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -100,7 +101,7 @@ func (h *StaticHandler) ServeTemplate(c *gin.Context) {
 
 // ServeLogo serves the company logo
 // @Summary Get company logo
-// @Description Serve the company logo in various formats
+// @Description Serve the company logo in various formats, or the resolved tenant's own logo if it has one
 // @Tags static
 // @Param format query string false "Logo format (svg|png|jpg)" default(svg)
 // @Success 200 "Logo file"
@@ -125,6 +126,14 @@ func (h *StaticHandler) ServeLogo(c *gin.Context) {
 
 	fullPath := filepath.Join(h.staticDir, "images", filename)
 
+	// White-label tenants can provide their own logo under images/tenants/<slug>/
+	if tenantInterface, exists := c.Get("tenant"); exists {
+		tenantPath := filepath.Join(h.staticDir, "images", "tenants", tenantInterface.(*models.Tenant).Slug, filename)
+		if _, err := os.Stat(tenantPath); err == nil {
+			fullPath = tenantPath
+		}
+	}
+
 	// Check if file exists
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
 		// Fallback to default SVG logo
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionHandler struct {
+	subscriptions *services.SubscriptionService
+}
+
+// NewSubscriptionHandler creates a new subscription handler
+func NewSubscriptionHandler(subscriptions *services.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{subscriptions: subscriptions}
+}
+
+// GetSubscription retrieves the current subscription of the admin's tenant
+// @Summary Get subscription
+// @Description Get the current subscription of the authenticated admin's tenant with its state and billing period
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.SubscriptionResponse}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/subscription [get]
+func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	subscription, err := h.subscriptions.Current(principal.TenantID)
+	h.respond(c, "Subscription retrieved successfully", subscription, err)
+}
+
+// StartSubscription subscribes the admin's tenant to a plan
+// @Summary Start subscription
+// @Description Subscribe the authenticated admin's tenant to a plan. The subscription starts trialing if a trial period is configured
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SubscriptionCreateRequest true "Plan"
+// @Success 201 {object} models.APIResponse{data=models.SubscriptionResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/subscription [post]
+func (h *SubscriptionHandler) StartSubscription(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	var req models.SubscriptionCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	subscription, err := h.subscriptions.Start(principal.TenantID, req.PlanID)
+	if err == nil {
+		c.JSON(http.StatusCreated, models.SuccessResponse("Subscription started successfully", subscription.ToResponse()))
+		return
+	}
+	h.respond(c, "", nil, err)
+}
+
+// ChangePlan upgrades or downgrades the subscription of the admin's tenant
+// @Summary Change subscription plan
+// @Description Switch the subscription of the authenticated admin's tenant to another plan, now or when the current billing period ends. Choosing the current plan drops a scheduled change
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SubscriptionChangeRequest true "Plan and timing"
+// @Success 200 {object} models.APIResponse{data=models.SubscriptionResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/subscription/change [post]
+func (h *SubscriptionHandler) ChangePlan(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	var req models.SubscriptionChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	subscription, err := h.subscriptions.ChangePlan(principal.TenantID, req.PlanID, req.AtPeriodEnd)
+	h.respond(c, "Subscription plan changed successfully", subscription, err)
+}
+
+// CancelSubscription cancels the subscription of the admin's tenant
+// @Summary Cancel subscription
+// @Description Cancel the subscription of the authenticated admin's tenant now, or at the end of the current billing period so it can still be resumed
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SubscriptionCancelRequest false "Timing"
+// @Success 200 {object} models.APIResponse{data=models.SubscriptionResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/subscription/cancel [post]
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	var req models.SubscriptionCancelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+			return
+		}
+	}
+
+	subscription, err := h.subscriptions.Cancel(principal.TenantID, req.AtPeriodEnd)
+	h.respond(c, "Subscription canceled successfully", subscription, err)
+}
+
+// ResumeSubscription keeps a subscription scheduled to cancel at period end
+// @Summary Resume subscription
+// @Description Withdraw the scheduled cancellation of the authenticated admin's tenant's subscription
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.SubscriptionResponse}
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/subscription/resume [post]
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	principal, exists := currentPrincipal(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+
+	subscription, err := h.subscriptions.Resume(principal.TenantID)
+	h.respond(c, "Subscription resumed successfully", subscription, err)
+}
+
+// respond answers with the subscription, or maps a subscription service error
+// to its status code
+func (h *SubscriptionHandler) respond(c *gin.Context, message string, subscription *models.Subscription, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, models.SuccessResponse(message, subscription.ToResponse()))
+	case errors.Is(err, services.ErrNoSubscription):
+		c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Subscription not found", err.Error()))
+	case errors.Is(err, services.ErrPlanUnavailable):
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Plan not found", err.Error()))
+	case errors.Is(err, services.ErrSubscriptionExists), errors.Is(err, services.ErrSubscriptionState):
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Subscription conflict", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update subscription", err.Error()))
+	}
+}
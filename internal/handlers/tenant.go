@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TenantHandler struct {
+	db  *gorm.DB
+	cfg config.TenantConfig
+}
+
+// NewTenantHandler creates a new tenant handler
+func NewTenantHandler(db *gorm.DB, cfg config.TenantConfig) *TenantHandler {
+	return &TenantHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// UpdateTenantSecurity updates the security settings of the admin's tenant
+// @Summary Update tenant security settings
+// @Description Require two-factor authentication for every user of the authenticated admin's tenant
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TenantSecurityUpdateRequest true "Security settings"
+// @Success 200 {object} models.APIResponse{data=models.TenantResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenant/security [put]
+func (h *TenantHandler) UpdateTenantSecurity(c *gin.Context) {
+	var req models.TenantSecurityUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	var tenant models.Tenant
+	if err := h.db.First(&tenant, user.TenantID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Tenant not found", err.Error()))
+		return
+	}
+
+	if err := h.db.Model(&tenant).Update("require_mfa", *req.RequireMFA).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update tenant", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Tenant security settings updated successfully", tenant.ToResponse()))
+}
+
+// GetTenants retrieves all tenants for super-admins
+// @Summary Get all tenants
+// @Description Get a paginated list of tenants with their usage counters
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Search in name and slug"
+// @Param status query string false "Filter by status (active, suspended)"
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/tenants [get]
+func (h *TenantHandler) GetTenants(c *gin.Context) {
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var tenants []models.Tenant
+	var total int64
+
+	query := h.db.Model(&models.Tenant{})
+
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(slug) LIKE ?", pattern, pattern)
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count tenants", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Offset(offset).Limit(limit).Order("name ASC").Find(&tenants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve tenants", err.Error()))
+		return
+	}
+
+	ids := make([]uint, 0, len(tenants))
+	for _, tenant := range tenants {
+		ids = append(ids, tenant.ID)
+	}
+	usage, err := h.tenantUsage(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count tenant usage", err.Error()))
+		return
+	}
+
+	// Convert to response format
+	var responses []models.TenantDetailResponse
+	for _, tenant := range tenants {
+		responses = append(responses, models.TenantDetailResponse{
+			TenantResponse: tenant.ToResponse(),
+			Usage:          usage[tenant.ID],
+		})
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Tenants retrieved successfully", response))
+}
+
+// GetTenant retrieves a tenant by ID for super-admins
+// @Summary Get tenant by ID
+// @Description Get a specific tenant with its usage counters
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Success 200 {object} models.APIResponse{data=models.TenantDetailResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenants/{id} [get]
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	tenant, ok := h.findTenant(c)
+	if !ok {
+		return
+	}
+
+	h.respondWithTenant(c, http.StatusOK, "Tenant retrieved successfully", tenant)
+}
+
+// CreateTenant creates a new tenant
+// @Summary Create a new tenant
+// @Description Create a new tenant, optionally on a plan
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tenant body models.TenantCreateRequest true "Tenant data"
+// @Success 201 {object} models.APIResponse{data=models.TenantDetailResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/tenants [post]
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req models.TenantCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if h.rejectInvalidTenant(c, 0, req.Name, req.Slug, req.PlanID) {
+		return
+	}
+
+	tenant := models.Tenant{
+		Name:   req.Name,
+		Slug:   req.Slug,
+		PlanID: req.PlanID,
+		Status: models.TenantStatusActive,
+	}
+
+	if err := h.db.Create(&tenant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create tenant", err.Error()))
+		return
+	}
+
+	h.respondWithTenant(c, http.StatusCreated, "Tenant created successfully", &tenant)
+}
+
+// UpdateTenant renames a tenant, changes its slug, plan or billing customer
+// @Summary Update tenant
+// @Description Update a tenant's name, slug, plan or billing customer, a customer of the super-admin's tenant whose invoices pay the subscription
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Param tenant body models.TenantUpdateRequest true "Tenant update data"
+// @Success 200 {object} models.APIResponse{data=models.TenantDetailResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/tenants/{id} [put]
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	tenant, ok := h.findTenant(c)
+	if !ok {
+		return
+	}
+
+	var req models.TenantUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if h.rejectInvalidTenant(c, tenant.ID, req.Name, req.Slug, req.PlanID) {
+		return
+	}
+
+	// Tenants are billed as customers of the super-admin's own tenant
+	if req.BillingCustomerID != nil && *req.BillingCustomerID != 0 {
+		var customer models.Customer
+		if err := tenantDB(c, h.db).Select("id").First(&customer, *req.BillingCustomerID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Customer not found", "Invalid billing customer ID"))
+			return
+		}
+	}
+
+	// Update fields
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Slug != "" {
+		updates["slug"] = req.Slug
+	}
+	if req.PlanID != nil {
+		updates["plan_id"] = *req.PlanID
+	}
+	if req.BillingCustomerID != nil {
+		if *req.BillingCustomerID == 0 {
+			updates["billing_customer_id"] = nil
+		} else {
+			updates["billing_customer_id"] = *req.BillingCustomerID
+		}
+	}
+
+	if len(updates) > 0 {
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(tenant).Updates(updates).Error; err != nil {
+				return err
+			}
+			if req.PlanID == nil {
+				return nil
+			}
+			// Move the current subscription along so renewals keep the plan
+			return tenancy.WithTenant(tx, tenant.ID).Model(&models.Subscription{}).
+				Where("status NOT IN ?", []string{models.SubscriptionStatusCanceled, models.SubscriptionStatusExpired}).
+				Updates(map[string]interface{}{"plan_id": *req.PlanID, "pending_plan_id": nil}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update tenant", err.Error()))
+			return
+		}
+	}
+
+	h.respondWithTenant(c, http.StatusOK, "Tenant updated successfully", tenant)
+}
+
+// SuspendTenant suspends a tenant, locking out all of its users
+// @Summary Suspend tenant
+// @Description Suspend a tenant; its users can no longer log in or use existing tokens and API keys
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Success 200 {object} models.APIResponse{data=models.TenantDetailResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenants/{id}/suspend [post]
+func (h *TenantHandler) SuspendTenant(c *gin.Context) {
+	tenant, ok := h.findTenant(c)
+	if !ok || h.rejectOwnTenant(c, tenant) {
+		return
+	}
+
+	now := time.Now()
+	if err := h.db.Model(tenant).Updates(map[string]interface{}{
+		"status":       models.TenantStatusSuspended,
+		"suspended_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to suspend tenant", err.Error()))
+		return
+	}
+
+	h.respondWithTenant(c, http.StatusOK, "Tenant suspended successfully", tenant)
+}
+
+// ReactivateTenant lifts the suspension of a tenant
+// @Summary Reactivate tenant
+// @Description Reactivate a suspended tenant so its users can log in again
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Success 200 {object} models.APIResponse{data=models.TenantDetailResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenants/{id}/reactivate [post]
+func (h *TenantHandler) ReactivateTenant(c *gin.Context) {
+	tenant, ok := h.findTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(tenant).Updates(map[string]interface{}{
+		"status":       models.TenantStatusActive,
+		"suspended_at": nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to reactivate tenant", err.Error()))
+		return
+	}
+
+	h.respondWithTenant(c, http.StatusOK, "Tenant reactivated successfully", tenant)
+}
+
+// DeleteTenant soft deletes a tenant
+// @Summary Delete tenant
+// @Description Soft delete a tenant; its users can no longer log in
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenants/{id} [delete]
+func (h *TenantHandler) DeleteTenant(c *gin.Context) {
+	tenant, ok := h.findTenant(c)
+	if !ok || h.rejectOwnTenant(c, tenant) {
+		return
+	}
+
+	if err := h.db.Delete(tenant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to delete tenant", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Tenant deleted successfully", nil))
+}
+
+// findTenant loads the tenant referenced by the path, writing an error response if it doesn't exist
+func (h *TenantHandler) findTenant(c *gin.Context) (*models.Tenant, bool) {
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid tenant ID", err.Error()))
+		return nil, false
+	}
+
+	var tenant models.Tenant
+	if err := h.db.First(&tenant, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Tenant not found", "Tenant with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve tenant", err.Error()))
+		return nil, false
+	}
+
+	return &tenant, true
+}
+
+// rejectOwnTenant refuses to lock the super-admin out of their own tenant
+func (h *TenantHandler) rejectOwnTenant(c *gin.Context, tenant *models.Tenant) bool {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return true
+	}
+	if userInterface.(*models.User).TenantID == tenant.ID {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid operation", "You cannot suspend or delete your own tenant"))
+		return true
+	}
+	return false
+}
+
+// rejectInvalidTenant validates the slug and plan and checks that the name and
+// slug are not used by another tenant, including deleted ones
+func (h *TenantHandler) rejectInvalidTenant(c *gin.Context, tenantID uint, name, slug string, planID *uint) bool {
+	if slug != "" && (!tenantSlugPattern.MatchString(slug) || len(slug) > maxTenantSlugLength) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid slug", "Slug must be lowercase letters, digits and single dashes"))
+		return true
+	}
+
+	if planID != nil {
+		var plan models.Plan
+		if err := h.db.First(&plan, *planID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Plan not found", "Invalid plan ID"))
+			return true
+		}
+	}
+
+	if name == "" && slug == "" {
+		return false
+	}
+
+	var count int64
+	if err := h.db.Unscoped().Model(&models.Tenant{}).
+		Where("id <> ?", tenantID).
+		Where("(name = ? AND ? <> '') OR (slug = ? AND ? <> '')", name, name, slug, slug).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check tenant", err.Error()))
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Tenant already exists", "Name or slug is already taken"))
+		return true
+	}
+
+	return false
+}
+
+// respondWithTenant writes the tenant together with its usage counters
+func (h *TenantHandler) respondWithTenant(c *gin.Context, status int, message string, tenant *models.Tenant) {
+	usage, err := h.tenantUsage([]uint{tenant.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count tenant usage", err.Error()))
+		return
+	}
+
+	c.JSON(status, models.SuccessResponse(message, models.TenantDetailResponse{
+		TenantResponse: tenant.ToResponse(),
+		Usage:          usage[tenant.ID],
+	}))
+}
+
+// tenantUsage counts the users and customers of the given tenants
+func (h *TenantHandler) tenantUsage(tenantIDs []uint) (map[uint]models.TenantUsage, error) {
+	usage := make(map[uint]models.TenantUsage, len(tenantIDs))
+	if len(tenantIDs) == 0 {
+		return usage, nil
+	}
+
+	type tenantCount struct {
+		TenantID uint
+		Count    int64
+	}
+
+	var users []tenantCount
+	if err := h.db.Model(&models.User{}).Select("tenant_id, COUNT(*) AS count").
+		Where("tenant_id IN ?", tenantIDs).Group("tenant_id").Scan(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range users {
+		u := usage[row.TenantID]
+		u.Users = row.Count
+		usage[row.TenantID] = u
+	}
+
+	var customers []tenantCount
+	if err := tenancy.AllTenants(h.db).Model(&models.Customer{}).Select("tenant_id, COUNT(*) AS count").
+		Where("tenant_id IN ?", tenantIDs).Group("tenant_id").Scan(&customers).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range customers {
+		u := usage[row.TenantID]
+		u.Customers = row.Count
+		usage[row.TenantID] = u
+	}
+
+	var contacts []tenantCount
+	if err := tenancy.AllTenants(h.db).Model(&models.Contact{}).Select("tenant_id, COUNT(*) AS count").
+		Where("tenant_id IN ?", tenantIDs).Group("tenant_id").Scan(&contacts).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range contacts {
+		u := usage[row.TenantID]
+		u.Contacts = row.Count
+		usage[row.TenantID] = u
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// domainPattern matches lowercase fully qualified host names
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// domainVerificationTimeout bounds the DNS lookup of a verification record
+const domainVerificationTimeout = 5 * time.Second
+
+// GetDomains retrieves the custom domains of the admin's tenant
+// @Summary Get custom domains
+// @Description Get the custom domains of the authenticated admin's tenant with their verification records
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.TenantDomainResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/tenant/domains [get]
+func (h *TenantHandler) GetDomains(c *gin.Context) {
+	var domains []models.TenantDomain
+	if err := tenantDB(c, h.db).Order("domain ASC").Find(&domains).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve domains", err.Error()))
+		return
+	}
+
+	responses := make([]models.TenantDomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		responses = append(responses, domain.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Domains retrieved successfully", responses))
+}
+
+// AddDomain adds a custom domain to the admin's tenant
+// @Summary Add custom domain
+// @Description Add a custom domain to the authenticated admin's tenant. It resolves to the tenant once verified through the returned DNS TXT record
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TenantDomainCreateRequest true "Domain"
+// @Success 201 {object} models.APIResponse{data=models.TenantDomainResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/tenant/domains [post]
+func (h *TenantHandler) AddDomain(c *gin.Context) {
+	var req models.TenantDomainCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	if !domainPattern.MatchString(name) || len(name) > 253 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid domain", "Domain must be a fully qualified host name"))
+		return
+	}
+	if base := strings.ToLower(h.cfg.BaseDomain); base != "" && (name == base || strings.HasSuffix(name, "."+base)) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid domain", "Subdomains of the platform are assigned by slug"))
+		return
+	}
+
+	// Domains are unique across all tenants
+	var count int64
+	if err := tenancy.AllTenants(h.db).Model(&models.TenantDomain{}).Where("domain = ?", name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check domain", err.Error()))
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Domain already exists", "Domain is already registered"))
+		return
+	}
+
+	token, err := auth.GenerateOpaqueToken(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate verification token", err.Error()))
+		return
+	}
+
+	domain := models.TenantDomain{
+		Domain:            name,
+		VerificationToken: token,
+	}
+	if err := tenantDB(c, h.db).Create(&domain).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to add domain", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("Domain added successfully", domain.ToResponse()))
+}
+
+// VerifyDomain verifies ownership of a custom domain
+// @Summary Verify custom domain
+// @Description Check the DNS TXT record of a custom domain and activate the domain if it contains the verification token
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Domain ID"
+// @Success 200 {object} models.APIResponse{data=models.TenantDomainResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /admin/tenant/domains/{id}/verify [post]
+func (h *TenantHandler) VerifyDomain(c *gin.Context) {
+	domain, ok := h.tenantDomain(c)
+	if !ok {
+		return
+	}
+
+	if !domain.IsVerified() {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), domainVerificationTimeout)
+		defer cancel()
+
+		records, _ := net.DefaultResolver.LookupTXT(ctx, models.TenantDomainVerificationPrefix+domain.Domain)
+		verified := false
+		for _, record := range records {
+			if strings.TrimSpace(record) == domain.VerificationToken {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			c.JSON(http.StatusUnprocessableEntity, models.ErrorResponseFunc("Domain not verified", "TXT record "+models.TenantDomainVerificationPrefix+domain.Domain+" does not contain the verification token"))
+			return
+		}
+
+		now := time.Now()
+		if err := tenantDB(c, h.db).Model(domain).Update("verified_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify domain", err.Error()))
+			return
+		}
+		domain.VerifiedAt = &now
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Domain verified successfully", domain.ToResponse()))
+}
+
+// RemoveDomain removes a custom domain from the admin's tenant
+// @Summary Remove custom domain
+// @Description Remove a custom domain; it no longer resolves to the tenant
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Domain ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/tenant/domains/{id} [delete]
+func (h *TenantHandler) RemoveDomain(c *gin.Context) {
+	domain, ok := h.tenantDomain(c)
+	if !ok {
+		return
+	}
+
+	if err := tenantDB(c, h.db).Delete(domain).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to remove domain", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Domain removed successfully", nil))
+}
+
+// tenantDomain loads a custom domain of the admin's tenant referenced by the path
+func (h *TenantHandler) tenantDomain(c *gin.Context) (*models.TenantDomain, bool) {
+	id, err := utils.ValidateID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid domain ID", err.Error()))
+		return nil, false
+	}
+
+	var domain models.TenantDomain
+	if err := tenantDB(c, h.db).First(&domain, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Domain not found", "Domain with specified ID does not exist"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve domain", err.Error()))
+		return nil, false
+	}
+
+	return &domain, true
+}
@@ -3,16 +3,30 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/bootstrap"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/database"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/middleware"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/router"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/vat"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -23,6 +37,9 @@ func setupTestDB() *gorm.DB {
 	if err != nil {
 		panic("failed to connect database")
 	}
+	if err := tenancy.Register(db); err != nil {
+		panic("failed to register tenancy callbacks")
+	}
 
 	// Run migrations
 	database.Migrate(db)
@@ -50,6 +67,16 @@ func setupTestConfig() config.Config {
 			Secret:     "test-secret",
 			ExpiryHour: 24,
 		},
+		Auth: config.AuthConfig{
+			LoginFreeAttempts:    3,
+			LoginBackoffSecond:   1,
+			LoginMaxAttempts:     10,
+			LoginIPMaxAttempts:   100,
+			LoginLockoutMinute:   15,
+			OpenRegistration:     true,
+			TenantSignup:         true,
+			InvitationExpiryHour: 168,
+		},
 	}
 }
 
@@ -104,8 +131,7 @@ func TestGetPlans(t *testing.T) {
 		Name:          "Test Plan",
 		Slug:          "test-plan",
 		Description:   "A test plan",
-		Price:         29.99,
-		Currency:      "EUR",
+		Price:         money.New(2999, "EUR"),
 		InvoicePeriod: "monthly",
 		Active:        true,
 	}
@@ -132,24 +158,24 @@ func TestUserRegistration(t *testing.T) {
 
 	db := setupTestDB()
 
-	// Create a test organization
-	org := models.Organization{
-		Name: "Test Organization",
-		Slug: "test-org",
+	// Create a test tenant
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
 	}
-	db.Create(&org)
+	db.Create(&tenant)
 
 	cfg := setupTestConfig()
 	r := router.SetupRouter(db, cfg)
 
 	// Create registration request
 	regReq := models.UserCreateRequest{
-		Username:       "testuser",
-		Email:          "test@example.com",
-		Password:       "password123",
-		FirstName:      "Test",
-		LastName:       "User",
-		OrganizationID: org.ID,
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
 	}
 
 	jsonData, _ := json.Marshal(regReq)
@@ -173,21 +199,21 @@ func TestUserLogin(t *testing.T) {
 
 	db := setupTestDB()
 
-	// Create a test organization
-	org := models.Organization{
-		Name: "Test Organization",
-		Slug: "test-org",
+	// Create a test tenant
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
 	}
-	db.Create(&org)
+	db.Create(&tenant)
 
 	// Create a test user
 	regReq := models.UserCreateRequest{
-		Username:       "testuser",
-		Email:          "test@example.com",
-		Password:       "password123",
-		FirstName:      "Test",
-		LastName:       "User",
-		OrganizationID: org.ID,
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
 	}
 
 	cfg := setupTestConfig()
@@ -221,3 +247,2607 @@ func TestUserLogin(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, response.Success)
 }
+
+// createTestAdmin inserts an admin directly, as admins can't self-register
+func createTestAdmin(t *testing.T, db *gorm.DB, tenantID uint, username, password string) models.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	admin := models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: string(hash),
+		FirstName:    "Test",
+		LastName:     "Admin",
+		Role:         models.RoleAdmin,
+		TenantID:     tenantID,
+		Active:       true,
+	}
+	assert.NoError(t, db.Create(&admin).Error)
+	return admin
+}
+
+// loginTestUser logs in with the given credentials and returns the token pair
+func loginTestUser(t *testing.T, r *gin.Engine, username, password string) models.LoginResponse {
+	jsonData, _ := json.Marshal(models.LoginRequest{Username: username, Password: password})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data
+}
+
+// refreshTestToken exchanges a refresh token and returns the recorder
+func refreshTestToken(r *gin.Engine, refreshToken string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(models.RefreshTokenRequest{RefreshToken: refreshToken})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestRefreshTokenRotation tests refresh token rotation and reuse detection
+func TestRefreshTokenRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	regReq := models.UserCreateRequest{
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
+	}
+	jsonData, _ := json.Marshal(regReq)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	login := loginTestUser(t, r, "testuser", "password123")
+	assert.NotEmpty(t, login.Token)
+	assert.NotEmpty(t, login.RefreshToken)
+
+	// First refresh rotates the token
+	w = refreshTestToken(r, login.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var refreshed struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, login.RefreshToken, refreshed.Data.RefreshToken)
+
+	// Replaying the rotated token is rejected and revokes the family
+	w = refreshTestToken(r, login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = refreshTestToken(r, refreshed.Data.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The access token issued with the revoked family is blacklisted
+	req, _ = http.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+refreshed.Data.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestTwoFactorLogin tests TOTP enrolment and the MFA login challenge
+func TestTwoFactorLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	regReq := models.UserCreateRequest{
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
+	}
+	jsonData, _ := json.Marshal(regReq)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	login := loginTestUser(t, r, "testuser", "password123")
+
+	// Start enrolment
+	req, _ = http.NewRequest("POST", "/api/v1/auth/2fa/setup", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var setup struct {
+		Data models.TwoFactorSetupResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &setup))
+	assert.Contains(t, setup.Data.OTPAuthURL, "otpauth://totp/")
+
+	// Confirm with a code from the authenticator
+	code, err := auth.GenerateTOTPCode(setup.Data.Secret, time.Now())
+	assert.NoError(t, err)
+	jsonData, _ = json.Marshal(models.TwoFactorCodeRequest{Code: code})
+	req, _ = http.NewRequest("POST", "/api/v1/auth/2fa/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var confirmed struct {
+		Data models.RecoveryCodesResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	assert.Len(t, confirmed.Data.RecoveryCodes, 10)
+
+	// Login now returns a challenge instead of tokens
+	jsonData, _ = json.Marshal(models.LoginRequest{Username: "testuser", Password: "password123"})
+	req, _ = http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var challenge struct {
+		Data models.MFAChallengeResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	assert.True(t, challenge.Data.MFARequired)
+	assert.NotEmpty(t, challenge.Data.ChallengeToken)
+
+	verify := func(verifyReq models.MFAVerifyRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(verifyReq)
+		req, _ := http.NewRequest("POST", "/api/v1/auth/2fa/verify", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The code used for confirmation can't be replayed
+	w = verify(models.MFAVerifyRequest{ChallengeToken: challenge.Data.ChallengeToken, Code: code})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A recovery code completes the login exactly once
+	recoveryCode := confirmed.Data.RecoveryCodes[0]
+	w = verify(models.MFAVerifyRequest{ChallengeToken: challenge.Data.ChallengeToken, RecoveryCode: recoveryCode})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var verified struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &verified))
+	assert.NotEmpty(t, verified.Data.Token)
+	assert.True(t, verified.Data.User.TwoFactorEnabled)
+
+	w = verify(models.MFAVerifyRequest{ChallengeToken: challenge.Data.ChallengeToken, RecoveryCode: recoveryCode})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A tenant suspended after the password step can't complete the login
+	db.Model(&tenant).Update("status", models.TenantStatusSuspended)
+	w = verify(models.MFAVerifyRequest{ChallengeToken: challenge.Data.ChallengeToken, RecoveryCode: confirmed.Data.RecoveryCodes[1]})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Tenant suspended")
+
+	// Neither can a user deactivated after the password step
+	db.Model(&tenant).Update("status", models.TenantStatusActive)
+	db.Model(&models.User{}).Where("username = ?", "testuser").Update("active", false)
+	w = verify(models.MFAVerifyRequest{ChallengeToken: challenge.Data.ChallengeToken, RecoveryCode: confirmed.Data.RecoveryCodes[2]})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Account disabled")
+}
+
+// TestTenantRequiresTwoFactor tests that unenrolled users are limited to enrolment
+func TestTenantRequiresTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+
+	tenant := models.Tenant{
+		Name:       "Test Tenant",
+		Slug:       "test-tenant",
+		RequireMFA: true,
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	regReq := models.UserCreateRequest{
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
+	}
+	jsonData, _ := json.Marshal(regReq)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	login := loginTestUser(t, r, "testuser", "password123")
+
+	req, _ = http.NewRequest("GET", "/api/v1/customers", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/2fa/setup", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestAPIKeyAccess tests creating, using and revoking a tenant API key
+func TestAPIKeyAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, tenant.ID, "admin", "password123")
+	login := loginTestUser(t, r, "admin", "password123")
+
+	// Create a read-only key for customers
+	jsonData, _ := json.Marshal(models.APIKeyCreateRequest{Name: "Accounting export", Scopes: []string{models.ScopeCustomersRead}})
+	req, _ := http.NewRequest("POST", "/api/v1/admin/api-keys", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Data models.APIKeyCreateResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Data.Key)
+
+	var stored models.APIKey
+	assert.NoError(t, db.First(&stored, created.Data.ID).Error)
+	assert.NotEqual(t, created.Data.Key, stored.KeyHash)
+
+	apiRequest := func(method, path, header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The key works with both header forms within its scope
+	w = apiRequest("GET", "/api/v1/customers", "X-API-Key", created.Data.Key)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = apiRequest("GET", "/api/v1/customers", "Authorization", "ApiKey "+created.Data.Key)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Out-of-scope, user-only and admin routes are rejected
+	w = apiRequest("POST", "/api/v1/customers", "X-API-Key", created.Data.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = apiRequest("GET", "/api/v1/auth/me", "X-API-Key", created.Data.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = apiRequest("GET", "/api/v1/admin/api-keys", "X-API-Key", created.Data.Key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.NoError(t, db.First(&stored, created.Data.ID).Error)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// Revoked keys are rejected
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api/v1/admin/api-keys/%d", created.Data.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = apiRequest("GET", "/api/v1/customers", "X-API-Key", created.Data.Key)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestJWKS tests the public key set endpoint
+func TestJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// HS256 secrets are never published
+	var jwks auth.JWKS
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+	assert.Empty(t, jwks.Keys)
+}
+
+// TestSessionManagement tests listing sessions and logging out other devices
+func TestSessionManagement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	regReq := models.UserCreateRequest{
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
+	}
+	jsonData, _ := json.Marshal(regReq)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	laptop := loginTestUser(t, r, "testuser", "password123")
+	phone := loginTestUser(t, r, "testuser", "password123")
+
+	getSessions := func(token string) []models.SessionResponse {
+		req, _ := http.NewRequest("GET", "/api/v1/auth/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data []models.SessionResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	sessions := getSessions(laptop.Token)
+	assert.Len(t, sessions, 2)
+	current := 0
+	for _, session := range sessions {
+		if session.Current {
+			current++
+		}
+	}
+	assert.Equal(t, 1, current)
+
+	// Log out everywhere else from the laptop
+	req, _ = http.NewRequest("DELETE", "/api/v1/auth/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+laptop.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The phone's access and refresh tokens are revoked
+	req, _ = http.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+phone.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = refreshTestToken(r, phone.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	sessions = getSessions(laptop.Token)
+	assert.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+}
+
+// TestLoginThrottling tests lockout after repeated failures and admin unlock
+func TestLoginThrottling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	cfg.Auth.LoginFreeAttempts = 5
+	cfg.Auth.LoginMaxAttempts = 3
+	r := router.SetupRouter(db, cfg)
+
+	regReq := models.UserCreateRequest{
+		Username:  "testuser",
+		Email:     "test@example.com",
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		TenantID:  tenant.ID,
+	}
+	jsonData, _ := json.Marshal(regReq)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	createTestAdmin(t, db, tenant.ID, "admin", "password123")
+
+	login := func(username, password string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(models.LoginRequest{Username: username, Password: password})
+		req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Wrong passwords and unknown users get the same response
+	wrongPassword := login("testuser", "wrong")
+	unknownUser := login("nobody", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.Code)
+	assert.Equal(t, wrongPassword.Body.String(), unknownUser.Body.String())
+
+	// The account is locked after the maximum number of failures, even for the right password
+	login("test@example.com", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, login("testuser", "wrong").Code)
+
+	w = login("testuser", "password123")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Other accounts are unaffected
+	admin := loginTestUser(t, r, "admin", "password123")
+
+	var user models.User
+	assert.NoError(t, db.Where("username = ?", "testuser").First(&user).Error)
+
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/unlock", user.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusOK, login("testuser", "password123").Code)
+}
+
+// TestPasswordReset tests forgot password requests and single-use reset tokens
+func TestPasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	cfg.Auth.PasswordResetExpiryMinute = 30
+	r := router.SetupRouter(db, cfg)
+	user := createTestAdmin(t, db, tenant.ID, "alice", "password123")
+
+	request := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/v1/auth"+path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Known and unknown addresses get the same answer
+	known := request("/forgot-password", models.ForgotPasswordRequest{Email: user.Email})
+	unknown := request("/forgot-password", models.ForgotPasswordRequest{Email: "nobody@example.com"})
+	assert.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+
+	// issueLink stands in for the link from the reset email, which is sent
+	// in the background
+	issueLink := func() string {
+		var resetToken models.PasswordResetToken
+		assert.Eventually(t, func() bool {
+			return db.Where("user_id = ? AND used_at IS NULL", user.ID).Last(&resetToken).Error == nil
+		}, time.Second, 10*time.Millisecond)
+		token, err := auth.GenerateOpaqueToken(32)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Model(&resetToken).Update("token_hash", auth.HashToken(token)).Error)
+		return token
+	}
+	token := issueLink()
+	laptop := loginTestUser(t, r, "alice", "password123")
+
+	w := request("/reset-password", models.ResetPasswordRequest{Token: token, NewPassword: "newpassword123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Tokens work once
+	w = request("/reset-password", models.ResetPasswordRequest{Token: token, NewPassword: "otherpassword123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Existing sessions and their tokens are revoked
+	assert.Equal(t, http.StatusUnauthorized, refreshTestToken(r, laptop.RefreshToken).Code)
+	req, _ := http.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+laptop.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var session models.Session
+	assert.NoError(t, db.Where("user_id = ?", user.ID).First(&session).Error)
+	assert.NotNil(t, session.RevokedAt)
+	loginTestUser(t, r, "alice", "newpassword123")
+
+	// Expired tokens are rejected
+	assert.Equal(t, http.StatusOK, request("/forgot-password", models.ForgotPasswordRequest{Email: user.Email}).Code)
+	token = issueLink()
+	assert.NoError(t, db.Model(&models.PasswordResetToken{}).Where("token_hash = ?", auth.HashToken(token)).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	w = request("/reset-password", models.ResetPasswordRequest{Token: token, NewPassword: "otherpassword123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	loginTestUser(t, r, "alice", "newpassword123")
+
+	// Failing to issue a token doesn't give the account away either
+	assert.NoError(t, db.Migrator().DropTable(&models.PasswordResetToken{}))
+	known = request("/forgot-password", models.ForgotPasswordRequest{Email: user.Email})
+	assert.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, unknown.Body.String(), known.Body.String())
+}
+
+// TestEmailVerification tests verification links, resend throttling and
+// blocking unverified users
+func TestEmailVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	cfg.Auth.RequireEmailVerification = true
+	cfg.Auth.EmailVerificationExpiryHour = 48
+	cfg.Auth.VerificationResendSecond = 60
+	r := router.SetupRouter(db, cfg)
+	user := createTestAdmin(t, db, tenant.ID, "alice", "password123")
+	login := loginTestUser(t, r, "alice", "password123")
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/v1/auth"+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	verify := func(token string) *httptest.ResponseRecorder {
+		return request("GET", "/verify-email?token="+url.QueryEscape(token), "")
+	}
+	// link stands in for the link from the verification email
+	link := func(subject string) string {
+		token, err := auth.GenerateSignedToken("email_verification", subject, time.Hour)
+		assert.NoError(t, err)
+		return token
+	}
+
+	// Unverified users only reach the resend endpoint
+	assert.Equal(t, http.StatusForbidden, request("GET", "/me", login.Token).Code)
+
+	// Resending is throttled
+	assert.Equal(t, http.StatusOK, request("POST", "/resend-verification", login.Token).Code)
+	w := request("POST", "/resend-verification", login.Token)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Tampered links and links sent to a previous address are rejected
+	token := link(fmt.Sprintf("%d:%s", user.ID, user.Email))
+	payload, signature, _ := strings.Cut(token, ".")
+	tampered := payload + "." + strings.Map(func(r rune) rune {
+		if r == 'A' {
+			return 'B'
+		}
+		return 'A'
+	}, signature)
+	assert.Equal(t, http.StatusBadRequest, verify(tampered).Code)
+	assert.Equal(t, http.StatusBadRequest, verify(link(fmt.Sprintf("%d:%s", user.ID, "old@example.com"))).Code)
+	assert.NoError(t, db.First(&user, user.ID).Error)
+	assert.Nil(t, user.EmailVerifiedAt)
+
+	w = verify(token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, db.First(&user, user.ID).Error)
+	assert.NotNil(t, user.EmailVerifiedAt)
+	assert.Equal(t, http.StatusOK, request("GET", "/me", login.Token).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/resend-verification", login.Token).Code)
+}
+
+// TestUserManagement tests tenant-scoped user administration and the plan's user limit
+func TestUserManagement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Small", Slug: "small", Price: money.New(900, "EUR"), MaxUsers: 2}
+	db.Create(&plan)
+	tenant := models.Tenant{
+		Name:   "Test Tenant",
+		Slug:   "test-tenant",
+		PlanID: &plan.ID,
+	}
+	db.Create(&tenant)
+	otherTenant := models.Tenant{Name: "Other Tenant", Slug: "other-tenant"}
+	db.Create(&otherTenant)
+	outsider := models.User{Username: "outsider", Email: "outsider@example.com", PasswordHash: "x", FirstName: "Out", LastName: "Sider", Role: "user", TenantID: otherTenant.ID, Active: true}
+	db.Create(&outsider)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	adminUser := createTestAdmin(t, db, tenant.ID, "admin", "password123")
+	admin := loginTestUser(t, r, "admin", "password123")
+
+	adminRequest := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1/admin/users"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	newUser := func(username string) models.TenantUserCreateRequest {
+		return models.TenantUserCreateRequest{
+			Username:  username,
+			Email:     username + "@example.com",
+			Password:  "password123",
+			FirstName: "Team",
+			LastName:  "Member",
+		}
+	}
+
+	w := adminRequest("POST", "", newUser("alice"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.UserResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, tenant.ID, created.Data.TenantID)
+	assert.Equal(t, "user", created.Data.Role)
+	alicePath := fmt.Sprintf("/%d", created.Data.ID)
+
+	// Super-admin can't be granted by a tenant admin
+	superAdmin := newUser("mallory")
+	superAdmin.Role = "super-admin"
+	assert.Equal(t, http.StatusBadRequest, adminRequest("POST", "", superAdmin).Code)
+
+	// The plan allows two active users
+	assert.Equal(t, http.StatusPaymentRequired, adminRequest("POST", "", newUser("bob")).Code)
+
+	// Search only sees the admin's tenant
+	w = adminRequest("GET", "?search=ALI", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Data       []models.UserResponse     `json:"data"`
+			Pagination models.PaginationResponse `json:"pagination"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Data.Pagination.Total)
+	assert.Equal(t, http.StatusNotFound, adminRequest("GET", fmt.Sprintf("/%d", outsider.ID), nil).Code)
+
+	// Role change and deactivation free a seat
+	w = adminRequest("PUT", alicePath, models.UserUpdateRequest{Role: "admin"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, adminRequest("POST", alicePath+"/deactivate", nil).Code)
+	w = adminRequest("POST", "", newUser("bob"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	bobPath := fmt.Sprintf("/%d", created.Data.ID)
+
+	assert.Equal(t, http.StatusPaymentRequired, adminRequest("POST", alicePath+"/activate", nil).Code)
+
+	// Deleting bob lets alice back in; restoring bob then hits the limit
+	assert.Equal(t, http.StatusOK, adminRequest("DELETE", bobPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest("GET", bobPath, nil).Code)
+	assert.Equal(t, http.StatusOK, adminRequest("POST", alicePath+"/activate", nil).Code)
+	assert.Equal(t, http.StatusPaymentRequired, adminRequest("POST", bobPath+"/restore", nil).Code)
+
+	// Admins can't lock themselves out
+	assert.Equal(t, http.StatusBadRequest, adminRequest("DELETE", fmt.Sprintf("/%d", adminUser.ID), nil).Code)
+}
+
+// TestInvitations tests inviting users into a tenant and accepting the invitation
+func TestInvitations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{
+		Name: "Test Tenant",
+		Slug: "test-tenant",
+	}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, tenant.ID, "admin", "password123")
+	admin := loginTestUser(t, r, "admin", "password123")
+
+	request := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Self-registration can't grant admin
+	w := request("POST", "/auth/register", "", models.UserCreateRequest{
+		Username: "eve", Email: "eve@example.com", Password: "password123", FirstName: "Eve", LastName: "User", Role: "admin", TenantID: tenant.ID,
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	invite := func(email, role string) models.InvitationResponse {
+		w := request("POST", "/admin/invitations", admin.Token, models.InvitationCreateRequest{Email: email, Role: role})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data models.InvitationResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	// issueLink stands in for the link from the invitation email
+	issueLink := func(invitationID uint) string {
+		token, err := auth.GenerateSignedToken("invitation", fmt.Sprint(invitationID), time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Model(&models.Invitation{}).Where("id = ?", invitationID).Update("token_hash", auth.HashToken(token)).Error)
+		return token
+	}
+
+	accept := func(token, username string) *httptest.ResponseRecorder {
+		return request("POST", "/auth/invitations/accept", "", models.InvitationAcceptRequest{
+			Token: token, Username: username, Password: "password123", FirstName: "Invited", LastName: "User",
+		})
+	}
+
+	invitation := invite("Bob@Example.com", "admin")
+	assert.Equal(t, models.InvitationStatusPending, invitation.Status)
+	assert.Equal(t, "bob@example.com", invitation.Email)
+	assert.Equal(t, http.StatusConflict, request("POST", "/admin/invitations", admin.Token, models.InvitationCreateRequest{Email: "bob@example.com"}).Code)
+
+	token := issueLink(invitation.ID)
+	w = accept(token, "bob")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var accepted struct {
+		Data models.UserResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.Equal(t, tenant.ID, accepted.Data.TenantID)
+	assert.Equal(t, "admin", accepted.Data.Role)
+	assert.True(t, accepted.Data.EmailVerified)
+
+	// The link works once
+	assert.Equal(t, http.StatusBadRequest, accept(token, "bob2").Code)
+	loginTestUser(t, r, "bob", "password123")
+
+	// Resending replaces the link, revoking closes the invitation
+	invitation = invite("carol@example.com", "")
+	oldToken := issueLink(invitation.ID)
+	assert.Equal(t, http.StatusOK, request("POST", fmt.Sprintf("/admin/invitations/%d/resend", invitation.ID), admin.Token, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, accept(oldToken, "carol").Code)
+
+	token = issueLink(invitation.ID)
+	assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/admin/invitations/%d", invitation.ID), admin.Token, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, accept(token, "carol").Code)
+
+	w = request("GET", "/admin/invitations?status=revoked", admin.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Pagination models.PaginationResponse `json:"pagination"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Data.Pagination.Total)
+
+	// Open registration can be switched off
+	cfg.Auth.OpenRegistration = false
+	r = router.SetupRouter(db, cfg)
+	w = request("POST", "/auth/register", "", models.UserCreateRequest{
+		Username: "dave", Email: "dave@example.com", Password: "password123", FirstName: "Dave", LastName: "User", TenantID: tenant.ID,
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestSignup tests provisioning of a new organisation through self-service signup
+func TestSignup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Starter", Slug: "starter", Price: money.New(1900, "EUR"), MaxUsers: 5, Active: true}
+	db.Create(&plan)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	signup := func(req models.SignupRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest("POST", "/api/v1/signup", bytes.NewBuffer(jsonData))
+		httpReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httpReq)
+		return w
+	}
+
+	signupReq := models.SignupRequest{
+		OrganizationName: "Müller & Söhne GmbH",
+		PlanID:           plan.ID,
+		Username:         "owner",
+		Email:            "owner@example.com",
+		Password:         "password123",
+		FirstName:        "Olga",
+		LastName:         "Owner",
+		Country:          "DE",
+	}
+	w := signup(signupReq)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.SignupResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "mueller-and-soehne-gmbh", response.Data.Tenant.Slug)
+	assert.Equal(t, "admin", response.Data.User.Role)
+	assert.Equal(t, response.Data.Tenant.ID, response.Data.User.TenantID)
+	assert.Equal(t, plan.ID, response.Data.Customer.PlanID)
+	assert.Equal(t, "owner@example.com", response.Data.Customer.Email)
+	if assert.NotNil(t, response.Data.Tenant.PlanID) {
+		assert.Equal(t, plan.ID, *response.Data.Tenant.PlanID)
+	}
+
+	var settings models.UserSettings
+	assert.NoError(t, db.Where("user_id = ?", response.Data.User.ID).First(&settings).Error)
+	assert.Equal(t, "en", settings.Language)
+
+	// The owner can administer the new tenant right away
+	owner := loginTestUser(t, r, "owner", "password123")
+	req, _ := http.NewRequest("GET", "/api/v1/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer "+owner.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Same organisation name is rejected, a similar one gets a numbered slug
+	signupReq.Username, signupReq.Email = "owner2", "owner2@example.com"
+	assert.Equal(t, http.StatusConflict, signup(signupReq).Code)
+
+	signupReq.OrganizationName = "Mueller and Soehne GmbH"
+	w = signup(signupReq)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "mueller-and-soehne-gmbh-2", response.Data.Tenant.Slug)
+
+	// A taken username rolls back the whole signup
+	signupReq.OrganizationName = "Another Org"
+	assert.Equal(t, http.StatusConflict, signup(signupReq).Code)
+	var count int64
+	db.Model(&models.Tenant{}).Where("name = ?", "Another Org").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Explicit slugs must be free
+	signupReq.Username, signupReq.Email = "owner3", "owner3@example.com"
+	signupReq.Slug = "mueller-and-soehne-gmbh"
+	assert.Equal(t, http.StatusConflict, signup(signupReq).Code)
+}
+
+// TestTenantAdministration tests super-admin tenant management and suspension
+func TestTenantAdministration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	platform := models.Tenant{Name: "Platform", Slug: "platform"}
+	db.Create(&platform)
+	customerTenant := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&customerTenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	superAdminUser := createTestAdmin(t, db, platform.ID, "root", "password123")
+	db.Model(&superAdminUser).Update("role", models.RoleSuperAdmin)
+	createTestAdmin(t, db, customerTenant.ID, "acme-admin", "password123")
+	tenancy.WithTenant(db, customerTenant.ID).Create(&models.Customer{Name: "Acme", Email: "billing@acme.example", Status: "active", Active: true})
+
+	superAdmin := loginTestUser(t, r, "root", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1/admin/tenants"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Tenant admins can't administer tenants
+	assert.Equal(t, http.StatusForbidden, request(acmeAdmin.Token, "GET", "", nil).Code)
+
+	w := request(superAdmin.Token, "GET", "?search=acm", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Data       []models.TenantDetailResponse `json:"data"`
+			Pagination models.PaginationResponse     `json:"pagination"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	if assert.Len(t, list.Data.Data, 1) {
+		assert.Equal(t, int64(1), list.Data.Data[0].Usage.Users)
+		assert.Equal(t, int64(1), list.Data.Data[0].Usage.Customers)
+	}
+
+	// Create, rename and change slug
+	w = request(superAdmin.Token, "POST", "", models.TenantCreateRequest{Name: "Globex", Slug: "globex"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var detail struct {
+		Data models.TenantDetailResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, models.TenantStatusActive, detail.Data.Status)
+	globexPath := fmt.Sprintf("/%d", detail.Data.ID)
+
+	assert.Equal(t, http.StatusConflict, request(superAdmin.Token, "POST", "", models.TenantCreateRequest{Name: "Other", Slug: "acme"}).Code)
+	assert.Equal(t, http.StatusBadRequest, request(superAdmin.Token, "POST", "", models.TenantCreateRequest{Name: "Bad", Slug: "Bad Slug"}).Code)
+
+	w = request(superAdmin.Token, "PUT", globexPath, models.TenantUpdateRequest{Name: "Globex Corp", Slug: "globex-corp"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "Globex Corp", detail.Data.Name)
+	assert.Equal(t, "globex-corp", detail.Data.Slug)
+
+	// Suspension locks out existing tokens and new logins
+	acmePath := fmt.Sprintf("/%d", customerTenant.ID)
+	w = request(superAdmin.Token, "POST", acmePath+"/suspend", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, models.TenantStatusSuspended, detail.Data.Status)
+	assert.NotNil(t, detail.Data.SuspendedAt)
+
+	req, _ := http.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeAdmin.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	loginReq, _ := json.Marshal(models.LoginRequest{Username: "acme-admin", Password: "password123"})
+	req, _ = http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(loginReq))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.Equal(t, http.StatusOK, request(superAdmin.Token, "POST", acmePath+"/reactivate", nil).Code)
+	loginTestUser(t, r, "acme-admin", "password123")
+
+	// Super-admins can't lock themselves out
+	platformPath := fmt.Sprintf("/%d", platform.ID)
+	assert.Equal(t, http.StatusBadRequest, request(superAdmin.Token, "POST", platformPath+"/suspend", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, request(superAdmin.Token, "DELETE", platformPath, nil).Code)
+
+	assert.Equal(t, http.StatusOK, request(superAdmin.Token, "DELETE", globexPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(superAdmin.Token, "GET", globexPath, nil).Code)
+}
+
+// TestRowLevelSecurity tests that PostgreSQL row-level security keeps other
+// tenants' rows from a handler that forgets its tenant filter. It needs a
+// PostgreSQL database in TEST_POSTGRES_DSN, accessed by a role that is neither
+// superuser nor BYPASSRLS.
+func TestRowLevelSecurity(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to PostgreSQL: %v", err)
+	}
+	var bypassesPolicies bool
+	db.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypassesPolicies)
+	if bypassesPolicies {
+		t.Skip("row-level security doesn't apply to superuser and BYPASSRLS roles")
+	}
+
+	assert.NoError(t, tenancy.Register(db))
+	assert.NoError(t, tenancy.EnableRowLevelSecurity(db))
+	assert.NoError(t, database.Migrate(db))
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	tenantA := models.Tenant{Name: "RLS A " + suffix, Slug: "rls-a-" + suffix}
+	tenantB := models.Tenant{Name: "RLS B " + suffix, Slug: "rls-b-" + suffix}
+	db.Create(&tenantA)
+	db.Create(&tenantB)
+	assert.NoError(t, tenancy.WithTenant(db, tenantA.ID).Create(&models.Customer{Name: "A", Email: "a@example.com"}).Error)
+	assert.NoError(t, tenancy.WithTenant(db, tenantB.ID).Create(&models.Customer{Name: "B", Email: "b@example.com"}).Error)
+
+	// Inserting a row for another tenant is rejected by the policy
+	assert.Error(t, db.Exec("INSERT INTO customers (name, email, plan_id, tenant_id) VALUES ('X', 'x@example.com', 0, ?)", tenantB.ID).Error)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+	createTestAdmin(t, db, tenantA.ID, "rls-admin-"+suffix, "password123")
+	admin := loginTestUser(t, r, "rls-admin-"+suffix, "password123")
+
+	// Table queries skip the tenancy callbacks, so this handler relies on
+	// row-level security alone
+	r.GET("/api/v1/leaky-customers", middleware.AuthMiddleware(db, cfg.Auth), func(c *gin.Context) {
+		var rows []map[string]interface{}
+		if err := db.WithContext(c.Request.Context()).Table("customers").Find(&rows).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve customers", err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, rows)
+	})
+
+	req, _ := http.NewRequest("GET", "/api/v1/leaky-customers", nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rows []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rows))
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "A", rows[0]["name"])
+	}
+
+	// Without a tenant no customer is visible at all
+	var count int64
+	db.Table("customers").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// TestTenantResolution tests resolving the tenant from subdomains, custom domains and the X-Tenant header
+func TestTenantResolution(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+	verifiedAt := time.Now()
+	tenancy.WithTenant(db, acme.ID).Create(&models.TenantDomain{Domain: "portal.acme.example", VerificationToken: "t1", VerifiedAt: &verifiedAt})
+	tenancy.WithTenant(db, globex.ID).Create(&models.TenantDomain{Domain: "shop.globex.example", VerificationToken: "t2"})
+
+	cfg := setupTestConfig()
+	cfg.Tenant.BaseDomain = "ourapp.test"
+	r := router.SetupRouter(db, cfg)
+	r.GET("/api/v1/resolved-tenant", func(c *gin.Context) {
+		slug := ""
+		if tenant, exists := c.Get("tenant"); exists {
+			slug = tenant.(*models.Tenant).Slug
+		}
+		c.String(http.StatusOK, slug)
+	})
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	admin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(method, path, host, tenantHeader, token string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Host = host
+		req.Header.Set("Content-Type", "application/json")
+		if tenantHeader != "" {
+			req.Header.Set("X-Tenant", tenantHeader)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	resolved := func(host, tenantHeader string) string {
+		w := request("GET", "/api/v1/resolved-tenant", host, tenantHeader, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code, host+tenantHeader)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "acme", resolved("acme.ourapp.test", ""))
+	assert.Equal(t, "acme", resolved("ACME.ourapp.test:8080", ""))
+	assert.Equal(t, "acme", resolved("portal.acme.example", ""))
+	assert.Equal(t, "globex", resolved("", "globex"))
+	assert.Equal(t, "globex", resolved("acme.ourapp.test", "globex"))
+	assert.Equal(t, "", resolved("ourapp.test", ""))
+	assert.Equal(t, "", resolved("www.ourapp.test", ""))
+	assert.Equal(t, "", resolved("shop.globex.example", "")) // not verified
+
+	assert.Equal(t, http.StatusNotFound, request("GET", "/api/v1/plans", "initech.ourapp.test", "", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", "/api/v1/plans", "", "initech", "", nil).Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/plans", "acme.ourapp.test", "", "", nil).Code)
+
+	// Tokens are only accepted for their own tenant
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/auth/me", "acme.ourapp.test", "", admin.Token, nil).Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/auth/me", "ourapp.test", "", admin.Token, nil).Code)
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/auth/me", "globex.ourapp.test", "", admin.Token, nil).Code)
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/auth/me", "", "globex", admin.Token, nil).Code)
+
+	// Custom domain management
+	w := request("POST", "/api/v1/admin/tenant/domains", "", "", admin.Token, models.TenantDomainCreateRequest{Domain: "Crm.Acme.Example"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.TenantDomainResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "crm.acme.example", created.Data.Domain)
+	assert.Equal(t, "_ae-saas-verification.crm.acme.example", created.Data.VerificationName)
+	assert.NotEmpty(t, created.Data.VerificationToken)
+	assert.False(t, created.Data.Verified)
+
+	assert.Equal(t, http.StatusConflict, request("POST", "/api/v1/admin/tenant/domains", "", "", admin.Token, models.TenantDomainCreateRequest{Domain: "shop.globex.example"}).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/v1/admin/tenant/domains", "", "", admin.Token, models.TenantDomainCreateRequest{Domain: "evil.ourapp.test"}).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/v1/admin/tenant/domains", "", "", admin.Token, models.TenantDomainCreateRequest{Domain: "not a domain"}).Code)
+
+	w = request("GET", "/api/v1/admin/tenant/domains", "", "", admin.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []models.TenantDomainResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+
+	// Domains of other tenants are out of reach
+	var globexDomain models.TenantDomain
+	tenancy.WithTenant(db, globex.ID).First(&globexDomain)
+	assert.Equal(t, http.StatusNotFound, request("DELETE", fmt.Sprintf("/api/v1/admin/tenant/domains/%d", globexDomain.ID), "", "", admin.Token, nil).Code)
+	assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/api/v1/admin/tenant/domains/%d", created.Data.ID), "", "", admin.Token, nil).Code)
+}
+
+// TestContactsTenantIsolation tests that contacts are tenant-owned and can be
+// linked to customers in roles
+func TestContactsTenantIsolation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+	globexAdmin := loginTestUser(t, r, "globex-admin", "password123")
+
+	customer := models.Customer{Name: "Acme Retail", Status: "active", Active: true}
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&customer).Error)
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(acmeAdmin.Token, "POST", "/contacts", models.ContactCreateRequest{FirstName: "Jane", LastName: "Doe", Email: "jane@acme.example"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.ContactResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, acme.ID, created.Data.TenantID)
+	contactPath := fmt.Sprintf("/contacts/%d", created.Data.ID)
+	customerPath := fmt.Sprintf("/customers/%d/contacts", customer.ID)
+
+	// Other tenants can neither see nor link the contact
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "GET", contactPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "DELETE", contactPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "POST", customerPath, models.CustomerContactRequest{ContactID: created.Data.ID, Role: models.ContactRoleBilling}).Code)
+
+	w = request(globexAdmin.Token, "GET", "/contacts", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Data []models.ContactResponse `json:"data"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Empty(t, list.Data.Data)
+
+	// The same contact can hold several roles for a customer
+	assert.Equal(t, http.StatusCreated, request(acmeAdmin.Token, "POST", customerPath, models.CustomerContactRequest{ContactID: created.Data.ID, Role: models.ContactRoleBilling}).Code)
+	assert.Equal(t, http.StatusCreated, request(acmeAdmin.Token, "POST", customerPath, models.CustomerContactRequest{ContactID: created.Data.ID, Role: models.ContactRoleTechnical}).Code)
+	assert.Equal(t, http.StatusConflict, request(acmeAdmin.Token, "POST", customerPath, models.CustomerContactRequest{ContactID: created.Data.ID, Role: models.ContactRoleBilling}).Code)
+	assert.Equal(t, http.StatusBadRequest, request(acmeAdmin.Token, "POST", customerPath, models.CustomerContactRequest{ContactID: created.Data.ID, Role: "owner"}).Code)
+
+	w = request(acmeAdmin.Token, "GET", fmt.Sprintf("/customers/%d", customer.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var customerResponse struct {
+		Data models.CustomerResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &customerResponse))
+	assert.Len(t, customerResponse.Data.Contacts, 2)
+
+	w = request(acmeAdmin.Token, "GET", contactPath, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Len(t, created.Data.Customers, 2)
+
+	w = request(acmeAdmin.Token, "GET", customerPath, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var linked struct {
+		Data []models.ContactResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &linked))
+	assert.Len(t, linked.Data, 1)
+
+	// Unlinking a single role keeps the other one
+	linkPath := fmt.Sprintf("%s/%d", customerPath, created.Data.ID)
+	assert.Equal(t, http.StatusOK, request(acmeAdmin.Token, "DELETE", linkPath+"?role=technical", nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(acmeAdmin.Token, "DELETE", linkPath+"?role=technical", nil).Code)
+
+	// Deleting the contact removes its remaining links
+	assert.Equal(t, http.StatusOK, request(acmeAdmin.Token, "DELETE", contactPath, nil).Code)
+	w = request(acmeAdmin.Token, "GET", fmt.Sprintf("/customers/%d", customer.ID), nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &customerResponse))
+	assert.Empty(t, customerResponse.Data.Contacts)
+}
+
+// TestSearchTenantIsolation tests that fuzzy search only ever covers the
+// resolved tenant and hides private entities from anonymous searches
+func TestSearchTenantIsolation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+
+	search := func(req *http.Request) services.SearchResponse {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response services.SearchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Without a resolved tenant there is nothing to search
+	req, _ := http.NewRequest("GET", "/api/v1/search/quick?q=example", nil)
+	assert.Empty(t, search(req).Results)
+
+	// Anonymous searches of a tenant skip entities that require a login
+	req, _ = http.NewRequest("GET", "/api/v1/search/quick?q=example&types=users", nil)
+	req.Header.Set(middleware.TenantHeader, "acme")
+	assert.Empty(t, search(req).Results)
+
+	// Logged-in users only find their own tenant's rows
+	body, _ := json.Marshal(gin.H{"query": "example", "entity_types": []string{"users"}})
+	req, _ = http.NewRequest("POST", "/api/v1/search", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+acmeAdmin.Token)
+	response := search(req)
+	if assert.Len(t, response.Results, 1) {
+		assert.Equal(t, "acme-admin@example.com", response.Results[0].Data.(map[string]interface{})["email"])
+	}
+}
+
+// TestTenantEmailsAndNewsletters tests that emails and newsletter lists are
+// kept apart per tenant
+func TestTenantEmailsAndNewsletters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+	globexAdmin := loginTestUser(t, r, "globex-admin", "password123")
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	submitForm := func(tenantSlug string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(models.ContactFormRequest{
+			Name:       "John Doe",
+			Email:      "john@example.com",
+			Subject:    "Pricing",
+			Message:    "Hello",
+			Newsletter: true,
+			Source:     "website",
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/contact/form", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if tenantSlug != "" {
+			req.Header.Set(middleware.TenantHeader, tenantSlug)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Emails are only visible to the sending tenant
+	w := request(acmeAdmin.Token, "POST", "/emails/send", models.EmailSendRequest{To: "jane@example.com", From: "info@acme.example", Subject: "Hi", Body: "Hello"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var email struct {
+		Data models.EmailResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &email))
+	emailPath := fmt.Sprintf("/emails/%d", email.Data.ID)
+	assert.Equal(t, http.StatusOK, request(acmeAdmin.Token, "GET", emailPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "GET", emailPath, nil).Code)
+
+	w = request(globexAdmin.Token, "GET", "/emails/stats", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		Data struct {
+			Total int64 `json:"total"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(0), stats.Data.Total)
+
+	// The form needs a tenant, and the same email subscribes to each tenant's list
+	assert.Equal(t, http.StatusBadRequest, submitForm("").Code)
+	assert.Equal(t, http.StatusOK, submitForm("acme").Code)
+	assert.Equal(t, http.StatusOK, submitForm("acme").Code)
+	assert.Equal(t, http.StatusOK, submitForm("globex").Code)
+
+	listNewsletter := func(token string) []models.Newsletter {
+		w := request(token, "GET", "/contact/newsletter", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var newsletters []models.Newsletter
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &newsletters))
+		return newsletters
+	}
+	acmeList := listNewsletter(acmeAdmin.Token)
+	if assert.Len(t, acmeList, 1) {
+		assert.Equal(t, acme.ID, acmeList[0].TenantID)
+	}
+
+	// Unsubscribing from one tenant's list keeps the other subscription
+	assert.Equal(t, http.StatusOK, request(globexAdmin.Token, "DELETE", "/contact/newsletter/unsubscribe?email=john@example.com", nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "DELETE", "/contact/newsletter/unsubscribe?email=john@example.com", nil).Code)
+	assert.Empty(t, listNewsletter(globexAdmin.Token))
+	assert.Len(t, listNewsletter(acmeAdmin.Token), 1)
+}
+
+// TestPlanEntitlements tests that plan limits and features are enforced
+func TestPlanEntitlements(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Starter", Slug: "starter", Price: money.New(900, "EUR"), MaxUsers: 5, MaxClients: 1, Features: models.PlanFeatures{
+		Flags:  map[string]bool{"pdf": false},
+		Quotas: map[string]int{"contacts": 1},
+	}}
+	db.Create(&plan)
+	tenant := models.Tenant{Name: "Acme", Slug: "acme", PlanID: &plan.ID}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, tenant.ID, "acme-admin", "password123")
+	admin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var refused struct {
+		Data services.EntitlementError `json:"data"`
+	}
+
+	// MaxClients limits customers
+	customer := models.CustomerCreateRequest{Name: "Retail", Email: "retail@example.com", PlanID: plan.ID}
+	assert.Equal(t, http.StatusCreated, request("POST", "/customers", customer).Code)
+	w := request("POST", "/customers", customer)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &refused))
+	assert.Equal(t, services.LimitCustomers, refused.Data.Limit)
+	assert.Equal(t, "Starter", refused.Data.Plan)
+	assert.Equal(t, 1, refused.Data.Allowed)
+	assert.Equal(t, int64(1), refused.Data.Current)
+
+	// The contacts quota comes from the plan's features
+	contact := models.ContactCreateRequest{FirstName: "Jane", LastName: "Doe"}
+	assert.Equal(t, http.StatusCreated, request("POST", "/contacts", contact).Code)
+	w = request("POST", "/contacts", contact)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &refused))
+	assert.Equal(t, services.LimitContacts, refused.Data.Limit)
+
+	// PDF generation needs the pdf feature
+	w = request("GET", "/pdf/config", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &refused))
+	assert.Equal(t, "pdf", refused.Data.Feature)
+
+	plan.Features.Flags["pdf"] = true
+	db.Save(&plan)
+	assert.Equal(t, http.StatusOK, request("GET", "/pdf/config", nil).Code)
+}
+
+// TestPlanFeatures tests that plan features are validated and returned as typed JSON
+func TestPlanFeatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{Name: "Platform", Slug: "platform"}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	r := router.SetupRouter(db, cfg)
+
+	superAdminUser := createTestAdmin(t, db, tenant.ID, "admin", "password123")
+	assert.NoError(t, db.Model(&superAdminUser).Update("role", models.RoleSuperAdmin).Error)
+	admin := loginTestUser(t, r, "admin", "password123")
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+
+	requestAs := func(token, method, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/v1"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	request := func(method, path string, body string) *httptest.ResponseRecorder {
+		return requestAs(admin.Token, method, path, body)
+	}
+
+	// Unknown features, wrong kinds and invalid values are rejected
+	for _, features := range []string{
+		`{"teleport": true}`,
+		`{"pdf": 3}`,
+		`{"contacts": -1}`,
+		`{"contacts": 1.5}`,
+		`{"support": "phone"}`,
+	} {
+		w := request("POST", "/admin/plans", `{"name": "Pro", "slug": "pro", "price": {"amount": 4900}, "features": `+features+`}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, features)
+	}
+
+	// Prices are minor units of a valid currency, EUR unless given
+	for _, price := range []string{`49`, `{"amount": -1}`, `{"amount": 4900, "currency": "EURO"}`} {
+		w := request("POST", "/admin/plans", `{"name": "Pro", "slug": "pro", "price": `+price+`}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, price)
+	}
+
+	w := request("POST", "/admin/plans", `{"name": "Pro", "slug": "pro", "price": {"amount": 4900}, "features": {"pdf": true, "contacts": 500, "support": "priority"}}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data struct {
+			ID       uint                   `json:"id"`
+			Price    money.Money            `json:"price"`
+			Features map[string]interface{} `json:"features"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, money.New(4900, "EUR"), created.Data.Price)
+	assert.Equal(t, map[string]interface{}{"pdf": true, "contacts": float64(500), "support": "priority"}, created.Data.Features)
+
+	// Features are typed when read back
+	var plan models.Plan
+	assert.NoError(t, db.First(&plan, created.Data.ID).Error)
+	assert.True(t, plan.Features.Enabled("pdf"))
+	quota, ok := plan.Features.Quota("contacts")
+	assert.True(t, ok)
+	assert.Equal(t, 500, quota)
+	assert.Equal(t, "priority", plan.Features.EnumValue("support"))
+
+	planPath := fmt.Sprintf("/admin/plans/%d", created.Data.ID)
+	assert.Equal(t, http.StatusBadRequest, request("PUT", planPath, `{"features": {"support": "phone"}}`).Code)
+	assert.Equal(t, http.StatusOK, request("PUT", planPath, `{"features": {"pdf": false}}`).Code)
+	assert.NoError(t, db.First(&plan, created.Data.ID).Error)
+	assert.False(t, plan.Features.Enabled("pdf"))
+	assert.False(t, plan.Features.Enabled("contacts"))
+
+	// A new price keeps the plan's currency unless it names one
+	assert.Equal(t, http.StatusOK, request("PUT", planPath, `{"price": {"amount": 5900}}`).Code)
+	assert.NoError(t, db.First(&plan, created.Data.ID).Error)
+	assert.Equal(t, money.New(5900, "EUR"), plan.Price)
+	assert.Equal(t, http.StatusOK, request("PUT", planPath, `{"price": {"amount": 6900, "currency": "usd"}}`).Code)
+	assert.NoError(t, db.First(&plan, created.Data.ID).Error)
+	assert.Equal(t, money.New(6900, "USD"), plan.Price)
+
+	// Plans are shared by all tenants, so tenant admins can't change them
+	assert.Equal(t, http.StatusForbidden, requestAs(acmeAdmin.Token, "POST", "/admin/plans", `{"name": "Free for Acme", "slug": "acme", "price": {"amount": 0}}`).Code)
+	assert.Equal(t, http.StatusForbidden, requestAs(acmeAdmin.Token, "PUT", planPath, `{"max_users": 1000, "features": {"pdf": true}}`).Code)
+	assert.Equal(t, http.StatusForbidden, requestAs(acmeAdmin.Token, "DELETE", planPath, "").Code)
+	assert.NoError(t, db.First(&plan, created.Data.ID).Error)
+	assert.False(t, plan.Features.Enabled("pdf"))
+
+	// The feature catalogue is public
+	req, _ := http.NewRequest("GET", "/api/v1/plans/features", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var catalogue struct {
+		Data []models.FeatureDefinition `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &catalogue))
+	assert.NotEmpty(t, catalogue.Data)
+}
+
+// TestSubscriptionLifecycle tests plan changes, cancellation and the scheduled state transitions of subscriptions
+func TestSubscriptionLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	basic := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(900, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&basic)
+	pro := models.Plan{Name: "Pro", Slug: "pro", Price: money.New(29000, "EUR"), InvoicePeriod: "yearly", Active: true}
+	db.Create(&pro)
+	tenant := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{TrialDays: 14, PastDueGraceDays: 7}
+	r := router.SetupRouter(db, cfg)
+	subscriptions := services.NewSubscriptionService(db, bootstrap.SubscriptionConfig(cfg.Billing))
+
+	createTestAdmin(t, db, tenant.ID, "acme-admin", "password123")
+	admin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1/admin/subscription"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var response struct {
+		Data models.SubscriptionResponse `json:"data"`
+	}
+	tenantPlan := func() uint {
+		var current models.Tenant
+		assert.NoError(t, db.First(&current, tenant.ID).Error)
+		if current.PlanID == nil {
+			return 0
+		}
+		return *current.PlanID
+	}
+
+	assert.Equal(t, http.StatusNotFound, request("GET", "", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "", models.SubscriptionCreateRequest{PlanID: 999}).Code)
+
+	// New subscriptions start with the configured trial
+	w := request("POST", "", models.SubscriptionCreateRequest{PlanID: basic.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.SubscriptionStatusTrialing, response.Data.Status)
+	if assert.NotNil(t, response.Data.TrialEndsAt) {
+		assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), *response.Data.TrialEndsAt, time.Minute)
+	}
+	assert.Equal(t, basic.ID, tenantPlan())
+	assert.Equal(t, http.StatusConflict, request("POST", "", models.SubscriptionCreateRequest{PlanID: basic.ID}).Code)
+
+	// Upgrades apply now, downgrades can wait for the end of the period
+	w = request("POST", "/change", models.SubscriptionChangeRequest{PlanID: pro.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, pro.ID, response.Data.PlanID)
+	assert.Equal(t, pro.ID, tenantPlan())
+
+	w = request("POST", "/change", models.SubscriptionChangeRequest{PlanID: basic.ID, AtPeriodEnd: true})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, pro.ID, response.Data.PlanID)
+	if assert.NotNil(t, response.Data.PendingPlanID) {
+		assert.Equal(t, basic.ID, *response.Data.PendingPlanID)
+	}
+	assert.Equal(t, pro.ID, tenantPlan())
+
+	// The trial ends into an active subscription on the pending plan
+	trialEnd := *response.Data.TrialEndsAt
+	processed, err := subscriptions.ProcessDue(trialEnd.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	w = request("GET", "", nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.SubscriptionStatusActive, response.Data.Status)
+	assert.Equal(t, basic.ID, response.Data.PlanID)
+	assert.Nil(t, response.Data.PendingPlanID)
+	assert.WithinDuration(t, trialEnd, response.Data.CurrentPeriodStart, time.Second)
+	assert.WithinDuration(t, trialEnd.AddDate(0, 1, 0), response.Data.CurrentPeriodEnd, time.Second)
+	assert.Equal(t, basic.ID, tenantPlan())
+
+	// A cancellation at period end can be resumed until the period ends
+	assert.Equal(t, http.StatusConflict, request("POST", "/resume", nil).Code)
+	w = request("POST", "/cancel", models.SubscriptionCancelRequest{AtPeriodEnd: true})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Data.CancelAtPeriodEnd)
+	assert.Equal(t, models.SubscriptionStatusActive, response.Data.Status)
+	w = request("POST", "/resume", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Data.CancelAtPeriodEnd)
+	assert.Nil(t, response.Data.CanceledAt)
+
+	// Periods renew until the scheduled cancellation takes effect
+	periodEnd := response.Data.CurrentPeriodEnd
+	processed, err = subscriptions.ProcessDue(periodEnd.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	w = request("GET", "", nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.WithinDuration(t, periodEnd.AddDate(0, 1, 0), response.Data.CurrentPeriodEnd, time.Second)
+
+	assert.Equal(t, http.StatusOK, request("POST", "/cancel", models.SubscriptionCancelRequest{AtPeriodEnd: true}).Code)
+	_, err = subscriptions.ProcessDue(response.Data.CurrentPeriodEnd.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, request("GET", "", nil).Code)
+
+	var canceled models.Subscription
+	assert.NoError(t, tenancy.WithTenant(db, tenant.ID).First(&canceled, response.Data.ID).Error)
+	assert.Equal(t, models.SubscriptionStatusCanceled, canceled.Status)
+	assert.NotNil(t, canceled.EndedAt)
+
+	// Past due subscriptions expire after the grace period
+	w = request("POST", "", models.SubscriptionCreateRequest{PlanID: basic.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	_, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 15))
+	assert.NoError(t, err)
+	_, err = subscriptions.MarkPastDue(tenant.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, request("POST", "/change", models.SubscriptionChangeRequest{PlanID: pro.ID}).Code)
+
+	processed, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	processed, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	var expired int64
+	tenancy.WithTenant(db, tenant.ID).Model(&models.Subscription{}).Where("status = ?", models.SubscriptionStatusExpired).Count(&expired)
+	assert.Equal(t, int64(1), expired)
+
+	// Ended subscriptions no longer entitle the tenant to its plan
+	createCustomer := func(name string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(models.CustomerCreateRequest{Name: name, Email: "billing@example.com", PlanID: basic.ID})
+		req, _ := http.NewRequest("POST", "/api/v1/customers", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, basic.ID, tenantPlan())
+	w = createCustomer("Expired Retail")
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Contains(t, w.Body.String(), `"subscription":"expired"`)
+
+	// Cancellation without a body ends the subscription now
+	assert.Equal(t, http.StatusCreated, request("POST", "", models.SubscriptionCreateRequest{PlanID: basic.ID}).Code)
+	assert.Equal(t, http.StatusCreated, createCustomer("Active Retail").Code)
+	w = request("POST", "/cancel", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.SubscriptionStatusCanceled, response.Data.Status)
+	assert.NotNil(t, response.Data.EndedAt)
+	w = createCustomer("Canceled Retail")
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Contains(t, w.Body.String(), `"subscription":"canceled"`)
+}
+
+// TestSubscriptionPayments tests that subscriptions of tenants billed through
+// a customer of the platform tenant follow the payment of its invoices
+func TestSubscriptionPayments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	basic := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(900, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&basic)
+	platform := models.Tenant{Name: "Platform", Slug: "platform"}
+	db.Create(&platform)
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{PastDueGraceDays: 7}
+	r := router.SetupRouter(db, cfg)
+	subscriptions := services.NewSubscriptionService(db, bootstrap.SubscriptionConfig(cfg.Billing))
+
+	root := createTestAdmin(t, db, platform.ID, "root", "password123")
+	assert.NoError(t, db.Model(&root).Update("role", models.RoleSuperAdmin).Error)
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	superAdmin := loginTestUser(t, r, "root", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	subscriptionStatus := func() string {
+		subscription, err := subscriptions.Current(acme.ID)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		return subscription.Status
+	}
+	assert.Equal(t, http.StatusCreated, request(acmeAdmin.Token, "POST", "/admin/subscription", models.SubscriptionCreateRequest{PlanID: basic.ID}).Code)
+
+	// Tenants are billed as customers of the super-admin's tenant
+	billing := models.Customer{Name: "Acme", Email: "billing@acme.example", PlanID: basic.ID, Status: "active", Active: true}
+	assert.NoError(t, tenancy.WithTenant(db, platform.ID).Create(&billing).Error)
+	foreign := models.Customer{Name: "Acme Retail", Email: "retail@acme.example", PlanID: basic.ID, Status: "active", Active: true}
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&foreign).Error)
+	tenantPath := fmt.Sprintf("/admin/tenants/%d", acme.ID)
+	assert.Equal(t, http.StatusBadRequest, request(superAdmin.Token, "PUT", tenantPath, models.TenantUpdateRequest{BillingCustomerID: &foreign.ID}).Code)
+	w := request(superAdmin.Token, "PUT", tenantPath, models.TenantUpdateRequest{BillingCustomerID: &billing.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"billing_customer_id":%d`, billing.ID))
+
+	invoice := models.Invoice{CustomerID: billing.ID, PlanID: basic.ID, Number: "INV-000001", Status: models.InvoiceStatusOpen,
+		IssuedAt: time.Now(), DueAt: time.Now().AddDate(0, 0, 14), PeriodStart: time.Now(), PeriodEnd: time.Now().AddDate(0, 1, 0),
+		Subtotal: money.New(900, "EUR"), TaxAmount: money.New(0, "EUR"), Total: money.New(900, "EUR")}
+	assert.NoError(t, tenancy.WithTenant(db, platform.ID).Create(&invoice).Error)
+
+	// Open invoices that aren't due yet leave the subscription active
+	processed, err := subscriptions.ProcessDue(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, models.SubscriptionStatusActive, subscriptionStatus())
+
+	// An overdue invoice makes it past due
+	processed, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 15))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, models.SubscriptionStatusPastDue, subscriptionStatus())
+
+	// Paying the invoice reactivates it
+	assert.Equal(t, http.StatusOK, request(superAdmin.Token, "POST", fmt.Sprintf("/admin/invoices/%d/pay", invoice.ID), nil).Code)
+	assert.Equal(t, models.SubscriptionStatusActive, subscriptionStatus())
+	processed, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 15))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+
+	// Unlinked tenants no longer follow the customer's invoices
+	unlink := uint(0)
+	w = request(superAdmin.Token, "PUT", tenantPath, models.TenantUpdateRequest{BillingCustomerID: &unlink})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"billing_customer_id":null`)
+	invoice.ID, invoice.Number, invoice.Items = 0, "INV-000002", nil
+	invoice.PeriodStart = invoice.PeriodEnd
+	assert.NoError(t, tenancy.WithTenant(db, platform.ID).Create(&invoice).Error)
+	processed, err = subscriptions.ProcessDue(time.Now().AddDate(0, 0, 15))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, models.SubscriptionStatusActive, subscriptionStatus())
+}
+
+// testInvoiceMailer records the invoices it is asked to send
+type testInvoiceMailer struct {
+	sent []string
+}
+
+func (m *testInvoiceMailer) SendInvoiceEmail(to, recipientName, invoiceNumber, amount, dueDate string, pdf []byte) error {
+	m.sent = append(m.sent, invoiceNumber)
+	return nil
+}
+
+// TestInvoices tests recurring invoice generation and the invoice endpoints
+func TestInvoices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(999, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&plan)
+	free := models.Plan{Name: "Free", Slug: "free", Price: money.New(0, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&free)
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	now := time.Now()
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{TaxRate: 19, PaymentTermDays: 14, InvoiceNumberPrefix: "INV-", NumberPadding: 6,
+		InvoiceBillingStart: now.AddDate(0, -6, 0)}
+	cfg.Company = config.CompanyConfig{Name: "AE SaaS Basic"}
+	cfg.PDF.OutputDir = t.TempDir()
+	r := router.SetupRouter(db, cfg)
+	mailer := &testInvoiceMailer{}
+	numbering := services.NewNumberingService(db, bootstrap.NumberingConfig(cfg.Billing))
+	invoices := services.NewInvoiceService(db, numbering, bootstrap.NewPDFService(cfg.PDF), mailer, bootstrap.InvoiceConfig(cfg))
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+	globexAdmin := loginTestUser(t, r, "globex-admin", "password123")
+
+	customer := models.Customer{Name: "Acme Retail", Email: "billing@acme.example", PlanID: plan.ID, Status: "active", Active: true, CreatedAt: now.AddDate(0, -2, -1)}
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&customer).Error)
+	freeCustomer := models.Customer{Name: "Acme Free", Email: "free@acme.example", PlanID: free.ID, Status: "active", Active: true, CreatedAt: now.AddDate(0, -2, 0)}
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&freeCustomer).Error)
+
+	request := func(token, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/invoices"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Every billing period since the billing start is invoiced once, in advance
+	issued, err := invoices.GenerateDue(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, issued)
+	issued, err = invoices.GenerateDue(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, issued)
+
+	w := request(acmeAdmin.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Data       []models.InvoiceResponse  `json:"data"`
+			Pagination models.PaginationResponse `json:"pagination"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 3, list.Data.Pagination.Total)
+	if assert.Len(t, list.Data.Data, 3) {
+		latest := list.Data.Data[0]
+		assert.Equal(t, customer.ID, latest.CustomerID)
+		assert.Equal(t, "INV-000003", latest.Number)
+		assert.Equal(t, models.InvoiceStatusOpen, latest.Status)
+		assert.Equal(t, money.New(999, "EUR"), latest.Subtotal)
+		assert.Equal(t, 19.0, latest.TaxRate)
+		assert.Equal(t, money.New(190, "EUR"), latest.TaxAmount)
+		assert.Equal(t, money.New(1189, "EUR"), latest.Total)
+		assert.WithinDuration(t, latest.IssuedAt.Add(14*24*time.Hour), latest.DueAt, time.Second)
+		assert.WithinDuration(t, customer.CreatedAt.AddDate(0, 2, 0), latest.PeriodStart, time.Second)
+		assert.WithinDuration(t, customer.CreatedAt.AddDate(0, 3, 0), latest.PeriodEnd, time.Second)
+		if assert.Len(t, latest.Items, 1) {
+			assert.Equal(t, money.New(999, "EUR"), latest.Items[0].Total)
+		}
+	}
+
+	w = request(acmeAdmin.Token, "?customer_id="+fmt.Sprint(freeCustomer.ID))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 0, list.Data.Pagination.Total)
+
+	var invoice models.Invoice
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Where("number = ?", "INV-000001").First(&invoice).Error)
+	path := fmt.Sprintf("/%d", invoice.ID)
+
+	w = request(acmeAdmin.Token, path)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var single struct {
+		Data models.InvoiceResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &single))
+	assert.Equal(t, "INV-000001", single.Data.Number)
+	assert.Nil(t, single.Data.SentAt)
+
+	// Invoices of other tenants are invisible
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, path).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, path+"/download").Code)
+	w = request(globexAdmin.Token, "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 0, list.Data.Pagination.Total)
+
+	// Rendered invoices are downloaded and delivered from the stored PDF
+	pdfPath := cfg.PDF.OutputDir + "/invoice.pdf"
+	assert.NoError(t, os.WriteFile(pdfPath, []byte("%PDF-1.4 test"), 0644))
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Model(&invoice).Update("pdf_path", pdfPath).Error)
+
+	w = request(acmeAdmin.Token, path+"/download")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "INV-000001.pdf")
+	assert.Equal(t, "%PDF-1.4 test", w.Body.String())
+
+	assert.NoError(t, invoices.DeliverPending())
+	assert.Equal(t, []string{"INV-000001"}, mailer.sent)
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).First(&invoice, invoice.ID).Error)
+	assert.NotNil(t, invoice.SentAt)
+
+	// Without a billing start, customers are first invoiced for their current
+	// period instead of every period since they were created
+	current := services.NewInvoiceService(db, numbering, bootstrap.NewPDFService(cfg.PDF), mailer, services.InvoiceConfig{})
+	veteran := models.Customer{Name: "Acme Wholesale", Email: "wholesale@acme.example", PlanID: plan.ID, Status: "active", Active: true, CreatedAt: now.AddDate(-2, 0, -1)}
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&veteran).Error)
+	issued, err = current.GenerateDue(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	var first models.Invoice
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Where("customer_id = ?", veteran.ID).First(&first).Error)
+	assert.Equal(t, "INV-000004", first.Number)
+	assert.False(t, first.PeriodStart.After(now))
+	assert.True(t, first.PeriodEnd.After(now))
+
+	// A period is invoiced only once, also when runs overlap
+	_, err = current.Issue(&veteran, &plan, first.PeriodStart, now)
+	assert.ErrorIs(t, err, services.ErrPeriodInvoiced)
+	duplicate := first
+	duplicate.ID, duplicate.Number, duplicate.Items = 0, "INV-999999", nil
+	assert.Error(t, tenancy.WithTenant(db, acme.ID).Create(&duplicate).Error)
+
+	// A failing customer doesn't stop the others from being invoiced
+	assert.NoError(t, db.Exec(fmt.Sprintf(
+		"CREATE TRIGGER fail_veteran BEFORE INSERT ON invoices WHEN NEW.customer_id = %d BEGIN SELECT RAISE(ABORT, 'failed'); END",
+		veteran.ID)).Error)
+	issued, err = current.GenerateDue(first.PeriodEnd.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	var next models.Invoice
+	assert.NoError(t, tenancy.WithTenant(db, acme.ID).Where("customer_id = ?", customer.ID).Order("id DESC").First(&next).Error)
+	assert.Equal(t, "INV-000005", next.Number)
+}
+
+// TestInvoiceNumbering tests the numbering scheme endpoints
+func TestInvoiceNumbering(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	tenant := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&tenant)
+
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{InvoiceNumberPrefix: "INV-", CreditNoteNumberPrefix: "CN-", NumberPadding: 6}
+	r := router.SetupRouter(db, cfg)
+	numbering := services.NewNumberingService(db, bootstrap.NumberingConfig(cfg.Billing))
+
+	createTestAdmin(t, db, tenant.ID, "acme-admin", "password123")
+	admin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1/admin/numbering"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	next := func(kind string, date time.Time) string {
+		var number string
+		err := tenancy.WithTenant(db, tenant.ID).Transaction(func(tx *gorm.DB) error {
+			var err error
+			number, err = numbering.Next(tx, kind, date)
+			return err
+		})
+		assert.NoError(t, err)
+		return number
+	}
+
+	w := request("GET", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var schemes struct {
+		Data []models.NumberingSchemeResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &schemes))
+	if assert.Len(t, schemes.Data, 2) {
+		assert.Equal(t, "INV-000001", schemes.Data[0].NextNumber)
+		assert.Equal(t, "CN-000001", schemes.Data[1].NextNumber)
+	}
+
+	assert.Equal(t, http.StatusNotFound, request("PUT", "/receipt", models.NumberingSchemeUpdateRequest{}).Code)
+	padding := 20
+	assert.Equal(t, http.StatusBadRequest, request("PUT", "/invoice", models.NumberingSchemeUpdateRequest{Padding: &padding}).Code)
+
+	// With yearly reset, every year has a sequence of its own
+	now := time.Now()
+	prefix, yearReset, padding := "RE-", true, 4
+	w = request("PUT", "/invoice", models.NumberingSchemeUpdateRequest{Prefix: &prefix, YearReset: &yearReset, Padding: &padding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var scheme struct {
+		Data models.NumberingSchemeResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scheme))
+	assert.Equal(t, fmt.Sprintf("RE-%d-0001", now.Year()), scheme.Data.NextNumber)
+
+	// Invoices and credit notes are numbered in separate sequences
+	lastYear := now.AddDate(-1, 0, 0)
+	assert.Equal(t, fmt.Sprintf("RE-%d-0001", now.Year()), next(models.NumberingKindInvoice, now))
+	assert.Equal(t, fmt.Sprintf("RE-%d-0001", lastYear.Year()), next(models.NumberingKindInvoice, lastYear))
+	assert.Equal(t, fmt.Sprintf("RE-%d-0002", now.Year()), next(models.NumberingKindInvoice, now))
+	assert.Equal(t, "CN-000001", next(models.NumberingKindCreditNote, now))
+
+	// Once numbers are handed out, a new prefix or yearly reset could repeat
+	// one, e.g. RE-2026-0001 again with prefix RE-2026- and no yearly reset
+	prefix, yearReset = fmt.Sprintf("RE-%d-", now.Year()), false
+	w = request("PUT", "/invoice", models.NumberingSchemeUpdateRequest{Prefix: &prefix, YearReset: &yearReset})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, http.StatusConflict, request("PUT", "/invoice", models.NumberingSchemeUpdateRequest{Prefix: &prefix}).Code)
+	assert.Equal(t, http.StatusConflict, request("PUT", "/credit_note", models.NumberingSchemeUpdateRequest{YearReset: &yearReset, Prefix: &prefix}).Code)
+
+	// The padding can still change, and so can the unchanged prefix be resent
+	prefix, padding = "RE-", 6
+	w = request("PUT", "/invoice", models.NumberingSchemeUpdateRequest{Prefix: &prefix, Padding: &padding})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scheme))
+	assert.Equal(t, fmt.Sprintf("RE-%d-000003", now.Year()), scheme.Data.NextNumber)
+	assert.Equal(t, fmt.Sprintf("RE-%d-000003", now.Year()), next(models.NumberingKindInvoice, now))
+}
+
+// TestInvoiceNumberingConcurrency tests that invoice numbers handed out by
+// parallel transactions are consecutive without gaps, on SQLite and, if
+// TEST_POSTGRES_DSN is set, on PostgreSQL
+func TestInvoiceNumberingConcurrency(t *testing.T) {
+	sqliteDB, err := gorm.Open(sqlite.Open("file:"+t.TempDir()+"/numbering.db?_busy_timeout=10000"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Run("sqlite", func(t *testing.T) { testNumberingConcurrency(t, sqliteDB) })
+
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		postgresDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("failed to connect to PostgreSQL: %v", err)
+		}
+		t.Run("postgres", func(t *testing.T) { testNumberingConcurrency(t, postgresDB) })
+	}
+}
+
+func testNumberingConcurrency(t *testing.T, db *gorm.DB) {
+	assert.NoError(t, tenancy.Register(db))
+	assert.NoError(t, database.Migrate(db))
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	tenant := models.Tenant{Name: "Numbering " + suffix, Slug: "numbering-" + suffix}
+	assert.NoError(t, db.Create(&tenant).Error)
+	numbering := services.NewNumberingService(db, services.NumberingConfig{
+		Invoice: models.NumberingScheme{Prefix: "INV-", Padding: 4},
+	})
+	issue := func(fail bool) (string, error) {
+		var number string
+		err := tenancy.WithTenant(db, tenant.ID).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if number, err = numbering.Next(tx, models.NumberingKindInvoice, time.Now()); err != nil {
+				return err
+			}
+			if fail {
+				return fmt.Errorf("invoice not stored")
+			}
+			return nil
+		})
+		return number, err
+	}
+
+	const parallel = 20
+	numbers := make(chan string, parallel)
+	errs := make(chan error, parallel)
+	for i := 0; i < parallel; i++ {
+		go func(fail bool) {
+			number, err := issue(fail)
+			if err != nil {
+				errs <- err
+				return
+			}
+			numbers <- number
+		}(i%5 == 0)
+	}
+
+	issued := map[string]bool{}
+	failed := 0
+	for i := 0; i < parallel; i++ {
+		select {
+		case number := <-numbers:
+			assert.False(t, issued[number], "number %s handed out twice", number)
+			issued[number] = true
+		case err := <-errs:
+			assert.EqualError(t, err, "invoice not stored")
+			failed++
+		}
+	}
+
+	// Numbers of rolled back transactions are handed out again
+	assert.Equal(t, parallel/5, failed)
+	for i := 1; i <= len(issued); i++ {
+		assert.True(t, issued[fmt.Sprintf("INV-%04d", i)], "number INV-%04d missing", i)
+	}
+	number, err := issue(false)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("INV-%04d", len(issued)+1), number)
+}
+
+// TestCreditNotes tests cancelling and crediting invoices and the customer balance
+func TestCreditNotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(5000, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&plan)
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{InvoiceNumberPrefix: "INV-", CreditNoteNumberPrefix: "CN-", NumberPadding: 6}
+	cfg.PDF.OutputDir = t.TempDir()
+	r := router.SetupRouter(db, cfg)
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+	globexAdmin := loginTestUser(t, r, "globex-admin", "password123")
+
+	acmeDB := tenancy.WithTenant(db, acme.ID)
+	customer := models.Customer{Name: "Acme Retail", Email: "billing@acme.example", PlanID: plan.ID, Status: "active", Active: true}
+	assert.NoError(t, acmeDB.Create(&customer).Error)
+	newInvoice := func(number, status string, items ...models.InvoiceLineItem) models.Invoice {
+		invoice := models.Invoice{CustomerID: customer.ID, PlanID: plan.ID, Number: number, Status: status, IssuedAt: time.Now(),
+			DueAt: time.Now(), PeriodStart: time.Now(), PeriodEnd: time.Now(), TaxRate: 19, Items: items}
+		for _, item := range items {
+			invoice.Subtotal = invoice.Subtotal.Add(item.Total)
+		}
+		invoice.TaxAmount = invoice.Subtotal.Percent(invoice.TaxRate)
+		invoice.Total = invoice.Subtotal.Add(invoice.TaxAmount)
+		assert.NoError(t, acmeDB.Create(&invoice).Error)
+		return invoice
+	}
+	invoice := newInvoice("INV-000001", models.InvoiceStatusOpen,
+		models.InvoiceLineItem{Position: 1, Description: "Seats", Quantity: 3, UnitPrice: money.New(1000, "EUR"), Total: money.New(3000, "EUR")},
+		models.InvoiceLineItem{Position: 2, Description: "Basic plan", Quantity: 1, UnitPrice: money.New(5000, "EUR"), Total: money.New(5000, "EUR")})
+
+	request := func(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest(method, "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var note struct {
+		Data models.CreditNoteResponse `json:"data"`
+	}
+	var balance struct {
+		Data []models.CustomerBalance `json:"data"`
+	}
+	balancePath := fmt.Sprintf("/customers/%d/balance", customer.ID)
+	invoicePath := fmt.Sprintf("/invoices/%d", invoice.ID)
+	adminInvoicePath := "/admin" + invoicePath
+
+	// Issued invoices can't be changed or deleted
+	assert.ErrorIs(t, acmeDB.Model(&invoice).Update("total_amount", 1).Error, models.ErrDocumentFinalized)
+	assert.ErrorIs(t, acmeDB.Delete(&invoice).Error, models.ErrDocumentFinalized)
+	assert.ErrorIs(t, acmeDB.Model(&invoice.Items[0]).Update("quantity", 1).Error, models.ErrDocumentFinalized)
+	tampered := invoice
+	tampered.Number, tampered.Total = "HACK", money.New(1, "EUR")
+	assert.ErrorIs(t, acmeDB.Omit("Items").Save(&tampered).Error, models.ErrDocumentFinalized)
+	assert.ErrorIs(t, acmeDB.Model(&invoice).Updates(models.Invoice{Number: "HACK"}).Error, models.ErrDocumentFinalized)
+	assert.NoError(t, acmeDB.First(&tampered, invoice.ID).Error)
+	assert.Equal(t, "INV-000001", tampered.Number)
+	assert.Equal(t, invoice.Total, tampered.Total)
+
+	// Only admins cancel and credit invoices
+	clerk := createTestAdmin(t, db, acme.ID, "acme-clerk", "password123")
+	assert.NoError(t, db.Model(&clerk).Update("role", models.RoleUser).Error)
+	acmeClerk := loginTestUser(t, r, "acme-clerk", "password123")
+	assert.Equal(t, http.StatusOK, request(acmeClerk.Token, "GET", invoicePath, nil).Code)
+	assert.Equal(t, http.StatusForbidden, request(acmeClerk.Token, "POST", adminInvoicePath+"/cancel", nil).Code)
+	assert.Equal(t, http.StatusForbidden, request(acmeClerk.Token, "POST", adminInvoicePath+"/credit-notes", models.CreditNoteCreateRequest{
+		Items: []models.CreditNoteItemRequest{{Position: 1, Quantity: 1}},
+	}).Code)
+
+	// Lines are credited partially, up to what is left of them
+	w := request(acmeAdmin.Token, "POST", adminInvoicePath+"/credit-notes", models.CreditNoteCreateRequest{
+		Reason: "Seat removed", Items: []models.CreditNoteItemRequest{{Position: 1, Quantity: 1}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &note))
+	assert.Equal(t, "CN-000001", note.Data.Number)
+	assert.False(t, note.Data.Cancellation)
+	assert.Equal(t, money.New(1000, "EUR"), note.Data.Subtotal)
+	assert.Equal(t, money.New(190, "EUR"), note.Data.TaxAmount)
+	assert.Equal(t, money.New(1190, "EUR"), note.Data.Total)
+	firstNoteID := note.Data.ID
+
+	var issued models.CreditNote
+	assert.NoError(t, acmeDB.First(&issued, firstNoteID).Error)
+	issued.Number, issued.Total = "HACK", money.New(1, "EUR")
+	assert.ErrorIs(t, acmeDB.Save(&issued).Error, models.ErrDocumentFinalized)
+	assert.NoError(t, acmeDB.First(&issued, firstNoteID).Error)
+	assert.Equal(t, "CN-000001", issued.Number)
+
+	for _, items := range [][]models.CreditNoteItemRequest{
+		{{Position: 1, Quantity: 3}},
+		{{Position: 9, Quantity: 1}},
+		{{Position: 1, Quantity: 0}},
+		{},
+	} {
+		w = request(acmeAdmin.Token, "POST", adminInvoicePath+"/credit-notes", models.CreditNoteCreateRequest{Items: items})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "items %v", items)
+	}
+
+	w = request(acmeAdmin.Token, "GET", balancePath, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	if assert.Len(t, balance.Data, 1) {
+		assert.Equal(t, money.New(9520, "EUR"), balance.Data[0].Invoiced)
+		assert.Equal(t, money.New(1190, "EUR"), balance.Data[0].Credited)
+		assert.Equal(t, money.New(8330, "EUR"), balance.Data[0].Outstanding)
+	}
+
+	// Cancelling credits the rest and voids the unpaid invoice
+	w = request(acmeAdmin.Token, "POST", adminInvoicePath+"/cancel", models.InvoiceCancelRequest{Reason: "Customer left"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &note))
+	assert.Equal(t, "CN-000002", note.Data.Number)
+	assert.True(t, note.Data.Cancellation)
+	assert.Equal(t, money.New(7000, "EUR"), note.Data.Subtotal)
+	assert.Equal(t, money.New(8330, "EUR"), note.Data.Total)
+	if assert.Len(t, note.Data.Items, 2) {
+		assert.Equal(t, 2.0, note.Data.Items[0].Quantity)
+		assert.Equal(t, money.New(2000, "EUR"), note.Data.Items[0].Total)
+	}
+	assert.NoError(t, acmeDB.First(&invoice, invoice.ID).Error)
+	assert.Equal(t, models.InvoiceStatusVoid, invoice.Status)
+	assert.Equal(t, http.StatusConflict, request(acmeAdmin.Token, "POST", adminInvoicePath+"/cancel", nil).Code)
+
+	// Cancelling a paid invoice leaves a credit owed to the customer
+	paid := newInvoice("INV-000002", models.InvoiceStatusPaid,
+		models.InvoiceLineItem{Position: 1, Description: "Basic plan", Quantity: 1, UnitPrice: money.New(5000, "EUR"), Total: money.New(5000, "EUR")})
+	w = request(acmeAdmin.Token, "POST", fmt.Sprintf("/admin/invoices/%d/cancel", paid.ID), nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, acmeDB.First(&paid, paid.ID).Error)
+	assert.Equal(t, models.InvoiceStatusPaid, paid.Status)
+
+	w = request(acmeAdmin.Token, "GET", balancePath, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	if assert.Len(t, balance.Data, 1) {
+		assert.Equal(t, money.New(15470, "EUR"), balance.Data[0].Invoiced)
+		assert.Equal(t, money.New(15470, "EUR"), balance.Data[0].Credited)
+		assert.Equal(t, money.New(5950, "EUR"), balance.Data[0].Paid)
+		assert.Equal(t, money.New(-5950, "EUR"), balance.Data[0].Outstanding)
+	}
+
+	w = request(acmeAdmin.Token, "GET", fmt.Sprintf("/credit-notes?invoice_id=%d", invoice.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data struct {
+			Pagination models.PaginationResponse `json:"pagination"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, list.Data.Pagination.Total)
+
+	// Credit notes of other tenants are invisible
+	notePath := fmt.Sprintf("/credit-notes/%d", firstNoteID)
+	assert.Equal(t, http.StatusOK, request(acmeAdmin.Token, "GET", notePath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "GET", notePath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "POST", adminInvoicePath+"/cancel", nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, "GET", balancePath, nil).Code)
+
+	// Rendered credit notes are downloaded from the stored PDF
+	pdfPath := cfg.PDF.OutputDir + "/credit_note.pdf"
+	assert.NoError(t, os.WriteFile(pdfPath, []byte("%PDF-1.4 credit note"), 0644))
+	assert.NoError(t, acmeDB.Model(&models.CreditNote{ID: firstNoteID}).Update("pdf_path", pdfPath).Error)
+	w = request(acmeAdmin.Token, "GET", notePath+"/download", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "CN-000001.pdf")
+	assert.Equal(t, "%PDF-1.4 credit note", w.Body.String())
+}
+
+// TestInvoicePayment tests that admins mark open invoices as paid, which
+// settles them in the customer balance
+func TestInvoicePayment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(5000, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&plan)
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+	globex := models.Tenant{Name: "Globex", Slug: "globex"}
+	db.Create(&globex)
+
+	r := router.SetupRouter(db, setupTestConfig())
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	createTestAdmin(t, db, globex.ID, "globex-admin", "password123")
+	clerk := createTestAdmin(t, db, acme.ID, "acme-clerk", "password123")
+	assert.NoError(t, db.Model(&clerk).Update("role", models.RoleUser).Error)
+	acmeAdmin := loginTestUser(t, r, "acme-admin", "password123")
+	globexAdmin := loginTestUser(t, r, "globex-admin", "password123")
+	acmeClerk := loginTestUser(t, r, "acme-clerk", "password123")
+
+	acmeDB := tenancy.WithTenant(db, acme.ID)
+	customer := models.Customer{Name: "Acme Retail", Email: "billing@acme.example", PlanID: plan.ID, Status: "active", Active: true}
+	assert.NoError(t, acmeDB.Create(&customer).Error)
+	newInvoice := func(number, status string) models.Invoice {
+		invoice := models.Invoice{CustomerID: customer.ID, PlanID: plan.ID, Number: number, Status: status, IssuedAt: time.Now(),
+			DueAt: time.Now(), PeriodStart: time.Now(), PeriodEnd: time.Now(), TaxRate: 19,
+			Subtotal: money.New(5000, "EUR"), TaxAmount: money.New(950, "EUR"), Total: money.New(5950, "EUR")}
+		assert.NoError(t, acmeDB.Create(&invoice).Error)
+		return invoice
+	}
+	open := newInvoice("INV-000001", models.InvoiceStatusOpen)
+
+	request := func(token, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			jsonData, _ := json.Marshal(body)
+			buf.Write(jsonData)
+		}
+		req, _ := http.NewRequest("POST", "/api/v1"+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	payPath := fmt.Sprintf("/admin/invoices/%d/pay", open.ID)
+
+	// Only admins of the invoice's tenant record payments
+	assert.Equal(t, http.StatusForbidden, request(acmeClerk.Token, payPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, request(globexAdmin.Token, payPath, nil).Code)
+
+	paidAt := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	w := request(acmeAdmin.Token, payPath, models.InvoicePaymentRequest{PaidAt: &paidAt})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var payment struct {
+		Data models.InvoiceResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &payment))
+	assert.Equal(t, models.InvoiceStatusPaid, payment.Data.Status)
+	if assert.NotNil(t, payment.Data.PaidAt) {
+		assert.True(t, paidAt.Equal(*payment.Data.PaidAt))
+	}
+
+	// Payments settle the balance
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/customers/%d/balance", customer.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+acmeAdmin.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var balance struct {
+		Data []models.CustomerBalance `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	if assert.Len(t, balance.Data, 1) {
+		assert.Equal(t, money.New(5950, "EUR"), balance.Data[0].Paid)
+		assert.True(t, balance.Data[0].Outstanding.IsZero())
+	}
+
+	// Paid and void invoices can't be paid (again)
+	void := newInvoice("INV-000002", models.InvoiceStatusVoid)
+	assert.Equal(t, http.StatusConflict, request(acmeAdmin.Token, payPath, nil).Code)
+	assert.Equal(t, http.StatusConflict, request(acmeAdmin.Token, fmt.Sprintf("/admin/invoices/%d/pay", void.ID), nil).Code)
+}
+
+// TestInvoiceVAT tests that invoices charge VAT by the seller's and customer's
+// country and VAT ID, and that customer VAT IDs are validated
+func TestInvoiceVAT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB()
+	plan := models.Plan{Name: "Basic", Slug: "basic", Price: money.New(10000, "EUR"), InvoicePeriod: "monthly", Active: true}
+	db.Create(&plan)
+	acme := models.Tenant{Name: "Acme", Slug: "acme"}
+	db.Create(&acme)
+
+	cfg := setupTestConfig()
+	cfg.Billing = config.BillingConfig{TaxRate: 7, InvoiceNumberPrefix: "INV-", NumberPadding: 6,
+		VATCountry: "DE", VATRates: map[string]float64{"AT": 10}, VATOSS: true}
+	cfg.PDF.OutputDir = t.TempDir()
+	r := router.SetupRouter(db, cfg)
+	numbering := services.NewNumberingService(db, bootstrap.NumberingConfig(cfg.Billing))
+	invoices := services.NewInvoiceService(db, numbering, bootstrap.NewPDFService(cfg.PDF), &testInvoiceMailer{}, bootstrap.InvoiceConfig(cfg))
+
+	createTestAdmin(t, db, acme.ID, "acme-admin", "password123")
+	admin := loginTestUser(t, r, "acme-admin", "password123")
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/api/v1"+path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var created struct {
+		Data models.CustomerResponse `json:"data"`
+	}
+
+	// VAT IDs of member states are checked and stored without separators
+	newCustomer := func(country, vatID string) models.CustomerCreateRequest {
+		return models.CustomerCreateRequest{Name: "Customer " + country, Email: "billing@example.com", Country: country, VAT: vatID,
+			PlanID: plan.ID}
+	}
+	w := request("POST", "/customers", newCustomer("FR", "FR 41 303 265 045"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = request("POST", "/customers", newCustomer("FR", "FR 40 303 265 045"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "FR40303265045", created.Data.VAT)
+	w = request("PUT", fmt.Sprintf("/customers/%d", created.Data.ID), models.CustomerUpdateRequest{VAT: "FR40303265046"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	issue := func(country, vatID string) *models.Invoice {
+		customer := models.Customer{Name: "Customer " + country, Email: "billing@example.com", Country: country, VAT: vatID,
+			PlanID: plan.ID, Status: "active", Active: true}
+		assert.NoError(t, tenancy.WithTenant(db, acme.ID).Create(&customer).Error)
+		invoice, err := invoices.Issue(&customer, &plan, time.Now(), time.Now())
+		assert.NoError(t, err)
+		return invoice
+	}
+
+	// Domestic customers pay the seller's rate
+	invoice := issue("DE", "DE136695976")
+	assert.Equal(t, 19.0, invoice.TaxRate)
+	assert.Equal(t, money.New(11900, "EUR"), invoice.Total)
+	assert.Equal(t, "DE", invoice.TaxCountry)
+	assert.False(t, invoice.ReverseCharge)
+
+	// Businesses in other member states are invoiced under the reverse charge
+	invoice = issue("FR", "FR40303265045")
+	assert.Equal(t, 0.0, invoice.TaxRate)
+	assert.Equal(t, money.New(10000, "EUR"), invoice.Total)
+	assert.True(t, invoice.ReverseCharge)
+	assert.Equal(t, "FR40303265045", invoice.CustomerVATID)
+	assert.Equal(t, vat.NoteReverseCharge, invoice.TaxNote)
+	data := invoice.ToInvoiceData(models.CompanyInfo{Name: "AE SaaS Basic"}, &models.Customer{Name: "Customer FR"})
+	assert.Equal(t, "FR40303265045", data.Customer.VATID)
+	assert.Equal(t, true, data.TemplateData()["ReverseCharge"])
+	assert.Equal(t, vat.NoteReverseCharge, data.TemplateData()["TaxNote"])
+
+	// Consumers in other member states pay their country's rate under OSS
+	invoice = issue("FI", "")
+	assert.Equal(t, 25.5, invoice.TaxRate)
+	assert.Equal(t, "FI", invoice.TaxCountry)
+	invoice = issue("AT", "")
+	assert.Equal(t, 10.0, invoice.TaxRate)
+
+	// Customers outside the EU aren't charged VAT
+	invoice = issue("US", "")
+	assert.Equal(t, 0.0, invoice.TaxRate)
+	assert.Equal(t, "", invoice.TaxCountry)
+	assert.Equal(t, vat.NoteOutsideEU, invoice.TaxNote)
+
+	// The VAT of issued invoices can't be changed
+	err := tenancy.WithTenant(db, acme.ID).Model(invoice).Update("reverse_charge", true).Error
+	assert.ErrorIs(t, err, models.ErrDocumentFinalized)
+}
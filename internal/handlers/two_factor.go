@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// mfaChallengePurpose scopes signed tokens to the second login step
+	mfaChallengePurpose = "mfa_challenge"
+	// recoveryCodeCount is the number of recovery codes generated per user
+	recoveryCodeCount = 10
+)
+
+// SetupTwoFactor starts TOTP enrolment for the current user
+// @Summary Start two-factor enrolment
+// @Description Generate a new TOTP secret and otpauth:// URI to be shown as a QR code. Enrolment completes once a code is confirmed
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.TwoFactorSetupResponse}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	if user.IsTwoFactorEnabled() {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Two-factor authentication already enabled", "Disable it before enrolling a new authenticator"))
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate secret", err.Error()))
+		return
+	}
+
+	// The secret stays pending until a code generated from it is confirmed
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":         secret,
+		"totp_last_used_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to start two-factor setup", err.Error()))
+		return
+	}
+
+	response := models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPURI(h.cfg.TOTPIssuer, user.Email, secret),
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Two-factor setup started", response))
+}
+
+// ConfirmTwoFactor completes TOTP enrolment and returns recovery codes
+// @Summary Confirm two-factor enrolment
+// @Description Confirm the pending TOTP secret with a code from the authenticator app. Returns one-time recovery codes that are shown only once
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.APIResponse{data=models.RecoveryCodesResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	if user.IsTwoFactorEnabled() {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("Two-factor authentication already enabled", "Two-factor authentication is already confirmed"))
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Two-factor setup not started", "Call /auth/2fa/setup first"))
+		return
+	}
+
+	ok, err := h.useTOTPCode(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify code", err.Error()))
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid code", "The code is invalid or has already been used"))
+		return
+	}
+
+	var codes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to enable two-factor authentication", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Two-factor authentication enabled", models.RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user
+// @Summary Regenerate recovery codes
+// @Description Invalidate all existing recovery codes and generate new ones. Requires a current TOTP code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} models.APIResponse{data=models.RecoveryCodesResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	if !user.IsTwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Two-factor authentication not enabled", "Enrol an authenticator first"))
+		return
+	}
+
+	ok, err := h.useTOTPCode(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify code", err.Error()))
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid code", "The code is invalid or has already been used"))
+		return
+	}
+
+	var codes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate recovery codes", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Recovery codes regenerated", models.RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// DisableTwoFactor turns off two-factor authentication for the current user
+// @Summary Disable two-factor authentication
+// @Description Remove the TOTP secret and recovery codes. Requires the password and a current TOTP or recovery code. Not allowed when the tenant requires two-factor authentication
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorDisableRequest true "Password and code"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	user := userInterface.(*models.User)
+
+	if !user.IsTwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Two-factor authentication not enabled", "Nothing to disable"))
+		return
+	}
+
+	var tenant models.Tenant
+	if err := h.db.First(&tenant, user.TenantID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve tenant", err.Error()))
+		return
+	}
+	if tenant.RequireMFA {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Two-factor authentication required", "Your organization requires two-factor authentication"))
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid password", "Password is incorrect"))
+		return
+	}
+
+	ok, err := h.verifySecondFactor(user, req.Code, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify code", err.Error()))
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid code", "The code is invalid or has already been used"))
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":         "",
+			"totp_enabled_at":     nil,
+			"totp_last_used_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to disable two-factor authentication", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Two-factor authentication disabled", nil))
+}
+
+// VerifyTwoFactor completes a login that was answered with an MFA challenge
+// @Summary Verify second factor
+// @Description Exchange the challenge token from login and a TOTP or recovery code for an access and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.MFAVerifyRequest true "Challenge token and code"
+// @Success 200 {object} models.APIResponse{data=models.LoginResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", "Either code or recovery_code is required"))
+		return
+	}
+
+	subject, err := auth.ParseSignedToken(mfaChallengePurpose, req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid challenge", err.Error()))
+		return
+	}
+
+	userID, err := strconv.ParseUint(subject, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid challenge", "Malformed challenge token"))
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, uint(userID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid challenge", "User not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve user", err.Error()))
+		return
+	}
+	if !user.IsTwoFactorEnabled() {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid challenge", "Two-factor authentication is not available for this account"))
+		return
+	}
+
+	// Second factor attempts count towards the same limits as passwords
+	accountKey := services.UserAccountKey(user.ID)
+	if h.rejectThrottled(c, models.ThrottleScopeIP, c.ClientIP()) || h.rejectThrottled(c, models.ThrottleScopeAccount, accountKey) {
+		return
+	}
+
+	ok, err := h.verifySecondFactor(&user, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to verify code", err.Error()))
+		return
+	}
+	if !ok {
+		if err := h.throttleService.RecordFailure(accountKey, c.ClientIP()); err != nil {
+			log.Printf("Failed to record two-factor failure: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid code", "The code is invalid or has already been used"))
+		return
+	}
+
+	// The account may have been disabled or its tenant suspended since the
+	// password step issued the challenge
+	if h.rejectDisabledAccount(c, &user) {
+		return
+	}
+
+	if err := h.throttleService.Reset(accountKey); err != nil {
+		log.Printf("Failed to reset login throttle for user %d: %v", user.ID, err)
+	}
+
+	pair, err := h.tokenService.IssueTokenPair(&user, clientInfo(c, req.DeviceName))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to generate token", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Login successful", loginResponse(pair, &user)))
+}
+
+// mfaChallenge creates the challenge returned by login for users with two-factor authentication
+func (h *AuthHandler) mfaChallenge(user *models.User) (*models.MFAChallengeResponse, error) {
+	ttl := time.Duration(h.cfg.MFAChallengeExpiryMinute) * time.Minute
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	token, err := auth.GenerateSignedToken(mfaChallengePurpose, strconv.FormatUint(uint64(user.ID), 10), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresAt:      time.Now().Add(ttl),
+	}, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code
+func (h *AuthHandler) verifySecondFactor(user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		ok, err := h.useTOTPCode(user, code)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	if recoveryCode != "" {
+		return h.useRecoveryCode(user, recoveryCode)
+	}
+	return false, nil
+}
+
+// useTOTPCode verifies a TOTP code and records its time step so it can't be replayed
+func (h *AuthHandler) useTOTPCode(user *models.User, code string) (bool, error) {
+	step, ok := auth.VerifyTOTPCode(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	result := h.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+		Update("totp_last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	user.TOTPLastUsedStep = step
+	return true, nil
+}
+
+// useRecoveryCode consumes a matching unused recovery code
+func (h *AuthHandler) useRecoveryCode(user *models.User, code string) (bool, error) {
+	hash := auth.HashToken(auth.NormalizeRecoveryCode(code))
+
+	result := h.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a fresh set.
+// The plain codes are returned so they can be shown to the user once.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := auth.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		record := models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
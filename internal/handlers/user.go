@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	db           *gorm.DB
+	tokenService *services.TokenService
+	entitlements *services.EntitlementService
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(db *gorm.DB, tokenService *services.TokenService, entitlements *services.EntitlementService) *UserHandler {
+	return &UserHandler{
+		db:           db,
+		tokenService: tokenService,
+		entitlements: entitlements,
+	}
+}
+
+// GetUsers retrieves the users of the admin's tenant
+// @Summary Get all users
+// @Description Get a paginated list of users for the authenticated admin's tenant
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Search in username, email, first and last name"
+// @Param role query string false "Filter by role"
+// @Param active query bool false "Filter by active status"
+// @Param deleted query bool false "List deleted users instead"
+// @Success 200 {object} models.APIResponse{data=models.ListResponse}
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users [get]
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	admin := userInterface.(*models.User)
+
+	page, limit := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(page, limit)
+
+	var users []models.User
+	var total int64
+
+	query := h.db.Model(&models.User{}).Where("tenant_id = ?", admin.TenantID)
+
+	// Deleted users are only listed on request, e.g. to restore them
+	if c.Query("deleted") == "true" {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
+			pattern, pattern, pattern, pattern)
+	}
+
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	// Filter by active status if provided
+	if activeStr := c.Query("active"); activeStr != "" {
+		if activeStr == "true" {
+			query = query.Where("active = ?", true)
+		} else if activeStr == "false" {
+			query = query.Where("active = ?", false)
+		}
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to count users", err.Error()))
+		return
+	}
+
+	// Get paginated results
+	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to retrieve users", err.Error()))
+		return
+	}
+
+	// Convert to response format
+	var responses []models.UserResponse
+	for _, user := range users {
+		responses = append(responses, user.ToResponse())
+	}
+
+	response := models.ListResponse{
+		Data: responses,
+		Pagination: models.PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      int(total),
+			TotalPages: utils.CalculateTotalPages(int(total), limit),
+		},
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Users retrieved successfully", response))
+}
+
+// GetUser retrieves a user of the admin's tenant by ID
+// @Summary Get user by ID
+// @Description Get a specific user by ID within the authenticated admin's tenant
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User retrieved successfully", target.ToResponse()))
+}
+
+// CreateUser creates a user in the admin's tenant
+// @Summary Create a new user
+// @Description Create a user within the authenticated admin's tenant, subject to the plan's user limit
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TenantUserCreateRequest true "User creation data"
+// @Success 201 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /admin/users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+		return
+	}
+	admin := userInterface.(*models.User)
+
+	var req models.TenantUserCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	// Set default role if not provided
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+	if !canAssignRole(admin, req.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "Role "+req.Role+" can't be assigned"))
+		return
+	}
+
+	if h.rejectTakenLogin(c, 0, req.Username, req.Email) {
+		return
+	}
+	if rejectLimit(c, h.entitlements, admin.TenantID, services.LimitUsers) {
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to hash password", err.Error()))
+		return
+	}
+
+	user := models.User{
+		Username:     req.Username,
+		Email:        req.Email,
+		PasswordHash: string(hashedPassword),
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Role:         req.Role,
+		TenantID:     admin.TenantID,
+		Active:       true,
+	}
+
+	if err := h.db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to create user", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("User created successfully", user.ToResponse()))
+}
+
+// UpdateUser updates a user of the admin's tenant
+// @Summary Update a user
+// @Description Update profile, role or active status of a user within the authenticated admin's tenant
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.UserUpdateRequest true "User update data"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /admin/users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var req models.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", err.Error()))
+		return
+	}
+
+	admin, target, ok := h.managedUser(c)
+	if !ok {
+		return
+	}
+
+	if req.Role != "" && req.Role != target.Role {
+		if target.ID == admin.ID {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "You can't change your own role"))
+			return
+		}
+		if !canAssignRole(admin, req.Role) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid role", "Role "+req.Role+" can't be assigned"))
+			return
+		}
+	}
+
+	if req.Username != "" || req.Email != "" {
+		if h.rejectTakenLogin(c, target.ID, req.Username, req.Email) {
+			return
+		}
+	}
+
+	if req.Active != nil && *req.Active != target.Active {
+		if !*req.Active && target.ID == admin.ID {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", "You can't deactivate yourself"))
+			return
+		}
+		if *req.Active && rejectLimit(c, h.entitlements, target.TenantID, services.LimitUsers) {
+			return
+		}
+	}
+
+	// Update fields if provided
+	if req.Username != "" {
+		target.Username = req.Username
+	}
+	if req.Email != "" {
+		target.Email = req.Email
+	}
+	if req.FirstName != "" {
+		target.FirstName = req.FirstName
+	}
+	if req.LastName != "" {
+		target.LastName = req.LastName
+	}
+	if req.Role != "" {
+		target.Role = req.Role
+	}
+	deactivated := req.Active != nil && !*req.Active && target.Active
+	if req.Active != nil {
+		target.Active = *req.Active
+	}
+
+	if err := h.db.Save(target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to update user", err.Error()))
+		return
+	}
+
+	if deactivated && !h.revokeSessions(c, target.ID, "User deactivated") {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User updated successfully", target.ToResponse()))
+}
+
+// ActivateUser re-enables a deactivated user of the admin's tenant
+// @Summary Activate a user
+// @Description Allow a user within the authenticated admin's tenant to log in again, subject to the plan's user limit
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /admin/users/{id}/activate [post]
+func (h *UserHandler) ActivateUser(c *gin.Context) {
+	_, target, ok := h.managedUser(c)
+	if !ok {
+		return
+	}
+
+	if !target.Active {
+		if rejectLimit(c, h.entitlements, target.TenantID, services.LimitUsers) {
+			return
+		}
+		if err := h.db.Model(target).Update("active", true).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to activate user", err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User activated successfully", target.ToResponse()))
+}
+
+// DeactivateUser disables a user of the admin's tenant and logs them out everywhere
+// @Summary Deactivate a user
+// @Description Block logins of a user within the authenticated admin's tenant and revoke their sessions
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/deactivate [post]
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	admin, target, ok := h.managedUser(c)
+	if !ok {
+		return
+	}
+
+	if target.ID == admin.ID {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", "You can't deactivate yourself"))
+		return
+	}
+
+	if target.Active {
+		if err := h.db.Model(target).Update("active", false).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to deactivate user", err.Error()))
+			return
+		}
+		if !h.revokeSessions(c, target.ID, "User deactivated") {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User deactivated successfully", target.ToResponse()))
+}
+
+// DeleteUser deletes a user of the admin's tenant (soft delete)
+// @Summary Delete a user
+// @Description Soft delete a user within the authenticated admin's tenant and revoke their sessions
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	admin, target, ok := h.managedUser(c)
+	if !ok {
+		return
+	}
+
+	if target.ID == admin.ID {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("Invalid request", "You can't delete yourself"))
+		return
+	}
+
+	if err := h.db.Delete(target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to delete user", err.Error()))
+		return
+	}
+	if !h.revokeSessions(c, target.ID, "User deleted") {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User deleted successfully", nil))
+}
+
+// RestoreUser restores a deleted user of the admin's tenant
+// @Summary Restore a user
+// @Description Undo the deletion of a user within the authenticated admin's tenant, subject to the plan's user limit
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.APIResponse{data=models.UserResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 402 {object} models.APIResponse{data=services.EntitlementError}
+// @Router /admin/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	target, ok := tenantUser(c, h.db.Unscoped())
+	if !ok {
+		return
+	}
+
+	if !target.DeletedAt.Valid {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseFunc("User not deleted", "User with specified ID is not deleted"))
+		return
+	}
+
+	if target.Active && rejectLimit(c, h.entitlements, target.TenantID, services.LimitUsers) {
+		return
+	}
+
+	if err := h.db.Unscoped().Model(target).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to restore user", err.Error()))
+		return
+	}
+	target.DeletedAt = gorm.DeletedAt{}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("User restored successfully", target.ToResponse()))
+}
+
+// managedUser loads the user from the id path parameter and checks that the
+// current admin may manage them. It writes the error response when it returns false.
+func (h *UserHandler) managedUser(c *gin.Context) (*models.User, *models.User, bool) {
+	target, ok := tenantUser(c, h.db)
+	if !ok {
+		return nil, nil, false
+	}
+	admin := c.MustGet("user").(*models.User)
+
+	// Only super-admins manage super-admins
+	if target.Role == models.RoleSuperAdmin && admin.Role != models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Insufficient permissions", "Only super-admins can manage super-admins"))
+		return nil, nil, false
+	}
+
+	return admin, target, true
+}
+
+// rejectTakenLogin answers with 409 if the username or email belongs to another
+// user, including deleted ones. It returns true when the request was rejected.
+func (h *UserHandler) rejectTakenLogin(c *gin.Context, userID uint, username, email string) bool {
+	var count int64
+	if err := h.db.Unscoped().Model(&models.User{}).
+		Where("id <> ?", userID).
+		Where("(username <> '' AND username = ?) OR (email <> '' AND email = ?)", username, email).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check user", err.Error()))
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseFunc("User already exists", "Username or email already taken"))
+		return true
+	}
+	return false
+}
+
+// revokeSessions logs a user out everywhere. It writes the error response when it returns false.
+func (h *UserHandler) revokeSessions(c *gin.Context, userID uint, reason string) bool {
+	if err := h.tokenService.RevokeAllForUser(userID, reason); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to revoke sessions", err.Error()))
+		return false
+	}
+	return true
+}
+
+// canAssignRole checks if the admin may give a user the role. Tenant admins
+// assign user and admin; only super-admins create further super-admins.
+func canAssignRole(admin *models.User, role string) bool {
+	if !models.IsValidRole(role) {
+		return false
+	}
+	return role != models.RoleSuperAdmin || admin.Role == models.RoleSuperAdmin
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiKeyLastUsedInterval limits how often last-used tracking writes to the database
+const apiKeyLastUsedInterval = time.Minute
+
+// apiKeyFromRequest extracts an API key from the X-API-Key or Authorization header
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(tokenParts) == 2 && tokenParts[0] == "ApiKey" {
+		return tokenParts[1]
+	}
+	return ""
+}
+
+// authenticateAPIKey validates an API key and sets the principal context
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, key string) {
+	var apiKey models.APIKey
+	if err := db.Where("key_hash = ?", auth.HashToken(key)).First(&apiKey).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid API key", "API key not found"))
+		c.Abort()
+		return
+	}
+
+	if !apiKey.IsActive() {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid API key", "API key has been revoked or has expired"))
+		c.Abort()
+		return
+	}
+
+	if rejectTenantMismatch(c, apiKey.TenantID) {
+		return
+	}
+
+	var tenant models.Tenant
+	if err := db.Select("id", "status").First(&tenant, apiKey.TenantID).Error; err != nil || tenant.IsSuspended() {
+		c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Tenant suspended", "The organisation's account is suspended"))
+		c.Abort()
+		return
+	}
+
+	// Track usage, skipping the write if the key was used very recently
+	now := time.Now()
+	db.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-apiKeyLastUsedInterval)).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": c.ClientIP(),
+		})
+
+	c.Set("api_key", &apiKey)
+	c.Set("principal", &models.Principal{
+		Type:     models.PrincipalTypeAPIKey,
+		TenantID: apiKey.TenantID,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.ScopeList(),
+	})
+	c.Request = c.Request.WithContext(tenancy.NewContext(c.Request.Context(), apiKey.TenantID))
+
+	c.Next()
+}
+
+// RequireScope middleware checks if an API key may access the resource.
+// GET and HEAD requests need "<resource>:read", all others "<resource>:write".
+// Users are not restricted by scopes.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principalInterface, exists := c.Get("principal")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
+			c.Abort()
+			return
+		}
+
+		principal := principalInterface.(*models.Principal)
+
+		scope := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+
+		if !principal.HasScope(scope) {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Insufficient scope", "API key requires scope "+scope))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUser middleware rejects API keys on routes meant for logged-in users
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("user"); !exists {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("User required", "This resource is not available to API keys"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -3,16 +3,45 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
 	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT tokens and sets user context
-func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+// unverifiedEmailRoutes lists the protected routes a user can reach before
+// verifying their email address when email verification is required
+var unverifiedEmailRoutes = map[string]bool{
+	"/api/v1/auth/resend-verification": true,
+	"/api/v1/auth/logout":              true,
+}
+
+// sessionLastSeenInterval limits how often session activity tracking writes to the database
+const sessionLastSeenInterval = time.Minute
+
+// mfaEnrollmentRoutes lists the protected routes a user can reach before
+// enrolling in two-factor authentication when their tenant requires it
+var mfaEnrollmentRoutes = map[string]bool{
+	"/api/v1/auth/2fa/setup":   true,
+	"/api/v1/auth/2fa/confirm": true,
+	"/api/v1/auth/logout":      true,
+	"/api/v1/auth/me":          true,
+}
+
+// AuthMiddleware validates JWT tokens or API keys and sets the principal context.
+// Users additionally get "user", "token" and "claims" set; API keys get "api_key".
+func AuthMiddleware(db *gorm.DB, cfg config.AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// API keys are accepted via X-API-Key or "Authorization: ApiKey <key>"
+		if apiKey := apiKeyFromRequest(c); apiKey != "" {
+			authenticateAPIKey(c, db, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Authorization required", "Missing authorization header"))
@@ -23,7 +52,7 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 		// Extract token from "Bearer <token>"
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid authorization format", "Use Bearer <token> format"))
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Invalid authorization format", "Use Bearer <token> or ApiKey <key> format"))
 			c.Abort()
 			return
 		}
@@ -40,7 +69,7 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 
 		// Check if token is blacklisted
 		var blacklistedToken models.TokenBlacklist
-		if err := db.Where("token_id = ? AND expires_at > NOW()", claims.ID).First(&blacklistedToken).Error; err == nil {
+		if err := db.Where("token_id = ? AND expires_at > ?", claims.ID, time.Now()).First(&blacklistedToken).Error; err == nil {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Token blacklisted", "Token has been revoked"))
 			c.Abort()
 			return
@@ -68,31 +97,81 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Check if email address is verified
+		if cfg.RequireEmailVerification && !user.IsEmailVerified() && !unverifiedEmailRoutes[c.FullPath()] {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Email not verified", "Verify your email address to access this resource"))
+			c.Abort()
+			return
+		}
+
+		if rejectTenantMismatch(c, user.TenantID) {
+			return
+		}
+
+		// Deleted and suspended tenants lock out all their users
+		var tenant models.Tenant
+		if err := db.Select("id", "require_mfa", "status").First(&tenant, user.TenantID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Tenant not found", "Tenant associated with user not found"))
+			c.Abort()
+			return
+		}
+		if tenant.IsSuspended() {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Tenant suspended", "The organisation's account is suspended"))
+			c.Abort()
+			return
+		}
+
+		// Check if the tenant requires two-factor authentication
+		if tenant.RequireMFA && !user.IsTwoFactorEnabled() && !mfaEnrollmentRoutes[c.FullPath()] {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Two-factor authentication required", "Enrol in two-factor authentication to access this resource"))
+			c.Abort()
+			return
+		}
+
+		// Track session activity, skipping the write if the session was seen very recently
+		if claims.SessionID != 0 {
+			now := time.Now()
+			db.Model(&models.Session{}).
+				Where("id = ? AND last_seen_at < ?", claims.SessionID, now.Add(-sessionLastSeenInterval)).
+				Updates(map[string]interface{}{
+					"last_seen_at": now,
+					"ip_address":   c.ClientIP(),
+				})
+		}
+
 		// Set user and token in context
 		c.Set("user", &user)
 		c.Set("token", tokenString)
 		c.Set("claims", claims)
+		c.Set("principal", &models.Principal{
+			Type:     models.PrincipalTypeUser,
+			TenantID: user.TenantID,
+			UserID:   user.ID,
+			Role:     user.Role,
+		})
+		c.Request = c.Request.WithContext(tenancy.NewContext(c.Request.Context(), user.TenantID))
 
 		c.Next()
 	}
 }
 
-// RequireRole middleware checks if user has required role
+// RequireRole middleware checks if the principal has required role.
+// API keys have no role and are always rejected.
 func RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userInterface, exists := c.Get("user")
+		principalInterface, exists := c.Get("principal")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 			c.Abort()
 			return
 		}
 
-		user := userInterface.(*models.User)
+		principal := principalInterface.(*models.Principal)
 
-		// Check if user has any of the required roles
+		// Check if principal has any of the required roles
 		hasRole := false
 		for _, role := range requiredRoles {
-			if user.Role == role {
+			if principal.Role == role {
 				hasRole = true
 				break
 			}
@@ -113,18 +192,18 @@ func RequireAdmin() gin.HandlerFunc {
 	return RequireRole("admin", "super-admin")
 }
 
-// TenantIsolation middleware ensures data access is limited to user's organization
+// TenantIsolation middleware ensures data access is limited to the principal's organization
 func TenantIsolation() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userInterface, exists := c.Get("user")
+		principalInterface, exists := c.Get("principal")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("User not found", "User not authenticated"))
 			c.Abort()
 			return
 		}
 
-		user := userInterface.(*models.User)
-		c.Set("tenant_id", user.TenantID)
+		principal := principalInterface.(*models.Principal)
+		c.Set("tenant_id", principal.TenantID)
 
 		c.Next()
 	}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature middleware rejects requests of tenants whose current plan
+// doesn't include the feature. It runs after the middleware that selects the
+// request's tenant.
+func RequireFeature(entitlements *services.EntitlementService, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := tenancy.FromContext(c.Request.Context())
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseFunc("Tenant required", "Request is not associated with a tenant"))
+			c.Abort()
+			return
+		}
+
+		if err := entitlements.CheckFeature(tenantID, feature); err != nil {
+			var entitlementErr *services.EntitlementError
+			if errors.As(err, &entitlementErr) {
+				c.JSON(entitlementErr.HTTPStatus(), models.ErrorResponseWithDetails("Feature not available", entitlementErr.Error(), entitlementErr))
+			} else {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to check plan features", err.Error()))
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenantHeader selects the tenant of a request by slug
+const TenantHeader = "X-Tenant"
+
+// reservedSubdomains are served by the platform itself rather than a tenant
+var reservedSubdomains = map[string]bool{
+	"www": true,
+	"api": true,
+}
+
+// ResolveTenant middleware determines the tenant a request is addressed to,
+// either from the X-Tenant header, a <slug>.<base domain> host or a verified
+// custom domain. The tenant is stored in the context as "tenant" and scopes
+// database access of public routes. Requests without a tenant pass through
+// unchanged, while unknown slugs are rejected.
+func ResolveTenant(db *gorm.DB, cfg config.TenantConfig) gin.HandlerFunc {
+	baseDomain := strings.ToLower(strings.TrimPrefix(cfg.BaseDomain, "."))
+
+	return func(c *gin.Context) {
+		var tenant models.Tenant
+		var err error
+
+		if slug := strings.TrimSpace(c.GetHeader(TenantHeader)); slug != "" {
+			err = db.Where("slug = ?", strings.ToLower(slug)).First(&tenant).Error
+		} else {
+			host := requestHost(c)
+			switch {
+			case host == "" || host == baseDomain:
+				c.Next()
+				return
+			case baseDomain != "" && strings.HasSuffix(host, "."+baseDomain):
+				slug := strings.TrimSuffix(host, "."+baseDomain)
+				if reservedSubdomains[slug] || strings.Contains(slug, ".") {
+					c.Next()
+					return
+				}
+				err = db.Where("slug = ?", slug).First(&tenant).Error
+			default:
+				// Hosts that aren't a verified custom domain don't select a tenant
+				var domain models.TenantDomain
+				if tenancy.AllTenants(db).Where("domain = ? AND verified_at IS NOT NULL", host).First(&domain).Error != nil {
+					c.Next()
+					return
+				}
+				err = db.First(&tenant, domain.TenantID).Error
+			}
+		}
+
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, models.ErrorResponseFunc("Tenant not found", "No tenant is served under this address"))
+			} else {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponseFunc("Failed to resolve tenant", err.Error()))
+			}
+			c.Abort()
+			return
+		}
+		if tenant.IsSuspended() {
+			c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Tenant suspended", "The organisation's account is suspended"))
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant", &tenant)
+		c.Request = c.Request.WithContext(tenancy.NewContext(c.Request.Context(), tenant.ID))
+
+		c.Next()
+	}
+}
+
+// rejectTenantMismatch rejects authenticated requests addressed to another
+// tenant than the one the credentials belong to
+func rejectTenantMismatch(c *gin.Context, tenantID uint) bool {
+	tenantInterface, exists := c.Get("tenant")
+	if !exists || tenantInterface.(*models.Tenant).ID == tenantID {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, models.ErrorResponseFunc("Tenant mismatch", "Credentials belong to a different tenant"))
+	c.Abort()
+	return true
+}
+
+// requestHost returns the lowercased host of the request without port
+func requestHost(c *gin.Context) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API key scopes. Read scopes cover GET requests, write scopes everything else.
+const (
+	ScopeCustomersRead  = "customers:read"
+	ScopeCustomersWrite = "customers:write"
+	ScopeContactsRead   = "contacts:read"
+	ScopeContactsWrite  = "contacts:write"
+	ScopeEmailsRead     = "emails:read"
+	ScopeEmailsWrite    = "emails:write"
+	ScopeInvoicesRead   = "invoices:read"
+	ScopeInvoicesWrite  = "invoices:write"
+)
+
+// APIKeyScopes lists the scopes that can be granted to an API key
+var APIKeyScopes = []string{
+	ScopeCustomersRead,
+	ScopeCustomersWrite,
+	ScopeContactsRead,
+	ScopeContactsWrite,
+	ScopeEmailsRead,
+	ScopeEmailsWrite,
+	ScopeInvoicesRead,
+	ScopeInvoicesWrite,
+}
+
+// IsValidAPIKeyScope checks if a scope can be granted to an API key
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey represents a tenant-scoped key for machine-to-machine access.
+// Only the SHA-256 hash of the key is stored; the key itself is shown once on creation.
+type APIKey struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	TenantID    uint           `gorm:"not null;index" json:"tenant_id"`
+	CreatedByID uint           `gorm:"not null" json:"created_by_id"`
+	Name        string         `gorm:"not null" json:"name"`
+	Prefix      string         `gorm:"not null" json:"prefix"`
+	KeyHash     string         `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes      string         `gorm:"type:text" json:"scopes"` // comma-separated
+	ExpiresAt   *time.Time     `json:"expires_at"`
+	LastUsedAt  *time.Time     `json:"last_used_at"`
+	LastUsedIP  string         `json:"last_used_ip"`
+	RevokedAt   *time.Time     `json:"revoked_at"`
+}
+
+// TableName specifies the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the granted scopes
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// IsActive checks if the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// APIKeyResponse represents the API response structure for APIKey
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToResponse converts APIKey to APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		Active:     k.IsActive(),
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// APIKeyCreateRequest represents the request structure for creating an API key
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreateResponse represents a newly created API key including the plain key, shown only once
+type APIKeyCreateResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
@@ -8,22 +8,24 @@ import (
 
 // Contact represents a contact in the system
 type Contact struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	FirstName string         `gorm:"not null" json:"first_name" binding:"required"`
-	LastName  string         `gorm:"not null" json:"last_name" binding:"required"`
-	Email     string         `json:"email" binding:"omitempty,email"`
-	Phone     string         `json:"phone"`
-	Mobile    string         `json:"mobile"`
-	Street    string         `json:"street"`
-	Zip       string         `json:"zip"`
-	City      string         `json:"city"`
-	Country   string         `json:"country"`
-	Type      string         `gorm:"default:'contact'" json:"type"`
-	Notes     string         `gorm:"type:text" json:"notes"`
-	Active    bool           `gorm:"default:true" json:"active"`
+	ID        uint              `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
+	TenantID  uint              `gorm:"not null;index" json:"tenant_id"`
+	FirstName string            `gorm:"not null" json:"first_name" binding:"required"`
+	LastName  string            `gorm:"not null" json:"last_name" binding:"required"`
+	Email     string            `json:"email" binding:"omitempty,email"`
+	Phone     string            `json:"phone"`
+	Mobile    string            `json:"mobile"`
+	Street    string            `json:"street"`
+	Zip       string            `json:"zip"`
+	City      string            `json:"city"`
+	Country   string            `json:"country"`
+	Type      string            `gorm:"default:'contact'" json:"type"`
+	Notes     string            `gorm:"type:text" json:"notes"`
+	Active    bool              `gorm:"default:true" json:"active"`
+	Customers []CustomerContact `gorm:"foreignKey:ContactID" json:"customers,omitempty"`
 }
 
 // TableName specifies the table name for Contact
@@ -31,28 +33,98 @@ func (Contact) TableName() string {
 	return "contacts"
 }
 
+// TenantScoped marks Contact as belonging to a tenant
+func (Contact) TenantScoped() {}
+
+// Roles of a contact for a customer
+const (
+	ContactRolePrimary   = "primary"
+	ContactRoleBilling   = "billing"
+	ContactRoleTechnical = "technical"
+)
+
+// IsValidContactRole checks if a contact can be linked to a customer with the role
+func IsValidContactRole(role string) bool {
+	return role == ContactRolePrimary || role == ContactRoleBilling || role == ContactRoleTechnical
+}
+
+// CustomerContact links a contact to a customer in a role. A contact can be
+// linked to several customers, and to the same customer in several roles.
+type CustomerContact struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	TenantID   uint      `gorm:"not null;index" json:"tenant_id"`
+	CustomerID uint      `gorm:"not null;uniqueIndex:idx_customer_contact_role" json:"customer_id"`
+	ContactID  uint      `gorm:"not null;uniqueIndex:idx_customer_contact_role;index" json:"contact_id"`
+	Role       string    `gorm:"not null;uniqueIndex:idx_customer_contact_role" json:"role"`
+}
+
+// TableName specifies the table name for CustomerContact
+func (CustomerContact) TableName() string {
+	return "customer_contacts"
+}
+
+// TenantScoped marks CustomerContact as belonging to a tenant
+func (CustomerContact) TenantScoped() {}
+
+// CustomerContactResponse represents the API response structure for CustomerContact
+type CustomerContactResponse struct {
+	CustomerID uint      `json:"customer_id"`
+	ContactID  uint      `json:"contact_id"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ToResponse converts CustomerContact to CustomerContactResponse
+func (l *CustomerContact) ToResponse() CustomerContactResponse {
+	return CustomerContactResponse{
+		CustomerID: l.CustomerID,
+		ContactID:  l.ContactID,
+		Role:       l.Role,
+		CreatedAt:  l.CreatedAt,
+	}
+}
+
+// CustomerContactRequest represents the request structure for linking a contact to a customer
+type CustomerContactRequest struct {
+	ContactID uint   `json:"contact_id" binding:"required"`
+	Role      string `json:"role" binding:"required"`
+}
+
+// customerContactResponses converts loaded customer-contact links
+func customerContactResponses(links []CustomerContact) []CustomerContactResponse {
+	responses := make([]CustomerContactResponse, 0, len(links))
+	for _, link := range links {
+		responses = append(responses, link.ToResponse())
+	}
+	return responses
+}
+
 // ContactResponse represents the API response structure for Contact
 type ContactResponse struct {
-	ID        uint      `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
-	Mobile    string    `json:"mobile"`
-	Street    string    `json:"street"`
-	Zip       string    `json:"zip"`
-	City      string    `json:"city"`
-	Country   string    `json:"country"`
-	Type      string    `json:"type"`
-	Notes     string    `json:"notes"`
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint                      `json:"id"`
+	TenantID  uint                      `json:"tenant_id"`
+	FirstName string                    `json:"first_name"`
+	LastName  string                    `json:"last_name"`
+	Email     string                    `json:"email"`
+	Phone     string                    `json:"phone"`
+	Mobile    string                    `json:"mobile"`
+	Street    string                    `json:"street"`
+	Zip       string                    `json:"zip"`
+	City      string                    `json:"city"`
+	Country   string                    `json:"country"`
+	Type      string                    `json:"type"`
+	Notes     string                    `json:"notes"`
+	Active    bool                      `json:"active"`
+	Customers []CustomerContactResponse `json:"customers"`
+	CreatedAt time.Time                 `json:"created_at"`
 }
 
 // ToResponse converts Contact to ContactResponse
 func (c *Contact) ToResponse() ContactResponse {
 	return ContactResponse{
 		ID:        c.ID,
+		TenantID:  c.TenantID,
 		FirstName: c.FirstName,
 		LastName:  c.LastName,
 		Email:     c.Email,
@@ -65,6 +137,7 @@ func (c *Contact) ToResponse() ContactResponse {
 		Type:      c.Type,
 		Notes:     c.Notes,
 		Active:    c.Active,
+		Customers: customerContactResponses(c.Customers),
 		CreatedAt: c.CreatedAt,
 	}
 }
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
+	"gorm.io/gorm"
+)
+
+// CreditNote represents a credit note (Storno) issued against an invoice,
+// either cancelling what's left of it or crediting some of its lines. Amounts
+// are positive and reduce what the customer owes. Credit notes are final
+// once issued.
+type CreditNote struct {
+	ID           uint                 `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	TenantID     uint                 `gorm:"not null;index;uniqueIndex:idx_credit_notes_tenant_number" json:"tenant_id"`
+	InvoiceID    uint                 `gorm:"not null;index" json:"invoice_id"`
+	CustomerID   uint                 `gorm:"not null;index" json:"customer_id"`
+	Number       string               `gorm:"not null;uniqueIndex:idx_credit_notes_tenant_number" json:"number"`
+	Cancellation bool                 `gorm:"not null;default:false" json:"cancellation"` // cancels the invoice
+	Reason       string               `json:"reason"`
+	IssuedAt     time.Time            `gorm:"not null" json:"issued_at"`
+	Subtotal     money.Money          `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	TaxRate      float64              `gorm:"not null" json:"tax_rate"` // percent, as on the invoice
+	TaxAmount    money.Money          `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	Total        money.Money          `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+	PDFPath      string               `json:"-"` // rendered PDF, empty until rendered
+	Items        []CreditNoteLineItem `gorm:"foreignKey:CreditNoteID" json:"items,omitempty"`
+}
+
+// TableName specifies the table name for CreditNote
+func (CreditNote) TableName() string {
+	return "credit_notes"
+}
+
+// TenantScoped marks CreditNote as belonging to a tenant
+func (CreditNote) TenantScoped() {}
+
+// BeforeUpdate keeps the contents of issued credit notes from changing. Only
+// the rendered PDF can be updated.
+func (n *CreditNote) BeforeUpdate(tx *gorm.DB) error {
+	if !updatesOnly(tx, "pdf_path") {
+		return ErrDocumentFinalized
+	}
+	return nil
+}
+
+// BeforeDelete keeps issued credit notes from being deleted
+func (n *CreditNote) BeforeDelete(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// CreditNoteLineItem represents a credited quantity of an invoice line
+type CreditNoteLineItem struct {
+	ID                uint        `gorm:"primarykey" json:"id"`
+	TenantID          uint        `gorm:"not null;index" json:"tenant_id"`
+	CreditNoteID      uint        `gorm:"not null;index" json:"credit_note_id"`
+	InvoiceLineItemID uint        `gorm:"not null;index" json:"invoice_line_item_id"`
+	Position          int         `gorm:"not null" json:"position"` // position of the credited invoice line
+	Description       string      `gorm:"not null" json:"description"`
+	Quantity          float64     `gorm:"not null" json:"quantity"`
+	UnitPrice         money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Total             money.Money `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+}
+
+// TableName specifies the table name for CreditNoteLineItem
+func (CreditNoteLineItem) TableName() string {
+	return "credit_note_line_items"
+}
+
+// TenantScoped marks CreditNoteLineItem as belonging to a tenant
+func (CreditNoteLineItem) TenantScoped() {}
+
+// BeforeUpdate keeps the lines of issued credit notes from changing
+func (i *CreditNoteLineItem) BeforeUpdate(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// BeforeDelete keeps the lines of issued credit notes from being deleted
+func (i *CreditNoteLineItem) BeforeDelete(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// CreditNoteResponse represents the API response structure for CreditNote
+type CreditNoteResponse struct {
+	ID           uint                      `json:"id"`
+	InvoiceID    uint                      `json:"invoice_id"`
+	CustomerID   uint                      `json:"customer_id"`
+	Number       string                    `json:"number"`
+	Cancellation bool                      `json:"cancellation"`
+	Reason       string                    `json:"reason"`
+	IssuedAt     time.Time                 `json:"issued_at"`
+	Subtotal     money.Money               `json:"subtotal"`
+	TaxRate      float64                   `json:"tax_rate"`
+	TaxAmount    money.Money               `json:"tax_amount"`
+	Total        money.Money               `json:"total"`
+	Items        []InvoiceLineItemResponse `json:"items"`
+	CreatedAt    time.Time                 `json:"created_at"`
+}
+
+// ToResponse converts CreditNote to CreditNoteResponse
+func (n *CreditNote) ToResponse() CreditNoteResponse {
+	response := CreditNoteResponse{
+		ID:           n.ID,
+		InvoiceID:    n.InvoiceID,
+		CustomerID:   n.CustomerID,
+		Number:       n.Number,
+		Cancellation: n.Cancellation,
+		Reason:       n.Reason,
+		IssuedAt:     n.IssuedAt,
+		Subtotal:     n.Subtotal,
+		TaxRate:      n.TaxRate,
+		TaxAmount:    n.TaxAmount,
+		Total:        n.Total,
+		Items:        make([]InvoiceLineItemResponse, 0, len(n.Items)),
+		CreatedAt:    n.CreatedAt,
+	}
+	for _, item := range n.Items {
+		response.Items = append(response.Items, InvoiceLineItemResponse{
+			Position:    item.Position,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+	return response
+}
+
+// ToInvoiceData converts CreditNote to the data of the credit note PDF
+// template, referencing the credited invoice
+func (n *CreditNote) ToInvoiceData(company CompanyInfo, customer *Customer, invoice *Invoice) InvoiceData {
+	notes := fmt.Sprintf("Credit note for invoice %s of %s", invoice.Number, invoice.IssuedAt.Format("2006-01-02"))
+	if n.Cancellation {
+		notes = fmt.Sprintf("Cancellation of invoice %s of %s", invoice.Number, invoice.IssuedAt.Format("2006-01-02"))
+	}
+	if n.Reason != "" {
+		notes += ": " + n.Reason
+	}
+
+	data := InvoiceData{
+		InvoiceNumber: n.Number,
+		InvoiceDate:   n.IssuedAt,
+		Company:       company,
+		Customer:      customer.ToCustomerInfo(invoice.CustomerVATID),
+		Items:         make([]InvoiceItem, 0, len(n.Items)),
+		Subtotal:      n.Subtotal,
+		TaxRate:       n.TaxRate,
+		TaxAmount:     n.TaxAmount,
+		Total:         n.Total,
+		Notes:         notes,
+		ReverseCharge: invoice.ReverseCharge,
+		TaxNote:       invoice.TaxNote,
+	}
+	for _, item := range n.Items {
+		data.Items = append(data.Items, InvoiceItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+	return data
+}
+
+// CreditNoteCreateRequest represents the request structure for crediting lines of an invoice
+type CreditNoteCreateRequest struct {
+	Reason string                  `json:"reason" binding:"max=500"`
+	Items  []CreditNoteItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// CreditNoteItemRequest represents the credited quantity of an invoice line
+type CreditNoteItemRequest struct {
+	Position int     `json:"position" binding:"required,min=1"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// InvoiceCancelRequest represents the request structure for cancelling an invoice
+type InvoiceCancelRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// InvoicePaymentRequest represents the request structure for marking an invoice as paid
+type InvoicePaymentRequest struct {
+	PaidAt *time.Time `json:"paid_at"` // defaults to now
+}
+
+// CustomerBalance represents what a customer owes in a currency. Credit notes
+// reduce the invoiced amount; a negative outstanding amount is owed to the
+// customer.
+type CustomerBalance struct {
+	CustomerID  uint        `json:"customer_id"`
+	Invoiced    money.Money `json:"invoiced"`
+	Credited    money.Money `json:"credited"`
+	Paid        money.Money `json:"paid"`
+	Outstanding money.Money `json:"outstanding"`
+}
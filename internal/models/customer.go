@@ -28,6 +28,8 @@ type Customer struct {
 	Status        string `gorm:"default:'active'" json:"status"`
 	PaymentMethod string `json:"payment_method"`
 	Active        bool   `gorm:"default:true" json:"active"`
+	// Contacts linked to the customer with their roles
+	Contacts []CustomerContact `gorm:"foreignKey:CustomerID" json:"contacts,omitempty"`
 }
 
 // TableName specifies the table name for Customer
@@ -35,26 +37,46 @@ func (Customer) TableName() string {
 	return "customers"
 }
 
+// TenantScoped marks Customer as belonging to a tenant
+func (Customer) TenantScoped() {}
+
+// ToCustomerInfo converts Customer to the customer shown on invoices, with the
+// VAT ID the invoice was issued to
+func (c *Customer) ToCustomerInfo(vatID string) CustomerInfo {
+	return CustomerInfo{
+		ID:      c.ID,
+		Name:    c.Name,
+		Email:   c.Email,
+		Phone:   c.Phone,
+		Address: c.Street,
+		City:    c.City,
+		ZipCode: c.Zip,
+		Country: c.Country,
+		VATID:   vatID,
+	}
+}
+
 // CustomerResponse represents the API response structure for Customer
 type CustomerResponse struct {
-	ID            uint           `json:"id"`
-	Name          string         `json:"name"`
-	Email         string         `json:"email"`
-	Phone         string         `json:"phone"`
-	Street        string         `json:"street"`
-	Zip           string         `json:"zip"`
-	City          string         `json:"city"`
-	Country       string         `json:"country"`
-	TaxID         string         `json:"tax_id"`
-	VAT           string         `json:"vat"`
-	PlanID        uint           `json:"plan_id"`
-	Plan          PlanResponse   `json:"plan,omitempty"`
-	TenantID      uint           `json:"tenant_id"`
-	Tenant        TenantResponse `json:"tenant,omitempty"`
-	Status        string         `json:"status"`
-	PaymentMethod string         `json:"payment_method"`
-	Active        bool           `json:"active"`
-	CreatedAt     time.Time      `json:"created_at"`
+	ID            uint                      `json:"id"`
+	Name          string                    `json:"name"`
+	Email         string                    `json:"email"`
+	Phone         string                    `json:"phone"`
+	Street        string                    `json:"street"`
+	Zip           string                    `json:"zip"`
+	City          string                    `json:"city"`
+	Country       string                    `json:"country"`
+	TaxID         string                    `json:"tax_id"`
+	VAT           string                    `json:"vat"`
+	PlanID        uint                      `json:"plan_id"`
+	Plan          PlanResponse              `json:"plan,omitempty"`
+	TenantID      uint                      `json:"tenant_id"`
+	Tenant        TenantResponse            `json:"tenant,omitempty"`
+	Status        string                    `json:"status"`
+	PaymentMethod string                    `json:"payment_method"`
+	Active        bool                      `json:"active"`
+	Contacts      []CustomerContactResponse `json:"contacts"`
+	CreatedAt     time.Time                 `json:"created_at"`
 }
 
 // ToResponse converts Customer to CustomerResponse
@@ -75,6 +97,7 @@ func (c *Customer) ToResponse() CustomerResponse {
 		Status:        c.Status,
 		PaymentMethod: c.PaymentMethod,
 		Active:        c.Active,
+		Contacts:      customerContactResponses(c.Contacts),
 		CreatedAt:     c.CreatedAt,
 	}
 
@@ -102,7 +125,6 @@ type CustomerCreateRequest struct {
 	TaxID         string `json:"tax_id"`
 	VAT           string `json:"vat"`
 	PlanID        uint   `json:"plan_id" binding:"required"`
-	TenantID      uint   `json:"tenant_id" binding:"required"`
 	PaymentMethod string `json:"payment_method"`
 }
 
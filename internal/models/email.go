@@ -12,6 +12,7 @@ type Email struct {
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	TenantID     uint           `gorm:"not null;index" json:"tenant_id"`
 	To           string         `gorm:"column:to;not null" json:"to" binding:"required,email"`
 	From         string         `gorm:"column:from;not null" json:"from" binding:"required,email"`
 	Subject      string         `gorm:"not null" json:"subject" binding:"required"`
@@ -30,6 +31,9 @@ func (Email) TableName() string {
 	return "emails"
 }
 
+// TenantScoped marks Email as belonging to a tenant
+func (Email) TenantScoped() {}
+
 // EmailResponse represents the API response structure for Email
 type EmailResponse struct {
 	ID           uint       `json:"id"`
@@ -366,7 +366,7 @@ func (p Plan) GetSearchTitle() string {
 }
 
 func (p Plan) GetSearchDescription() string {
-	return fmt.Sprintf("Plan: %s %.2f %s/%s", p.Name, p.Price, p.Currency, p.InvoicePeriod)
+	return fmt.Sprintf("Plan: %s %s/%s", p.Name, p.Price, p.InvoicePeriod)
 }
 
 func (p Plan) GetSearchURL() string {
@@ -379,7 +379,6 @@ func (p Plan) GetSearchData() map[string]interface{} {
 		"name":           p.Name,
 		"description":    p.Description,
 		"price":          p.Price,
-		"currency":       p.Currency,
 		"invoice_period": p.InvoicePeriod,
 		"features":       p.Features,
 		"active":         p.Active,
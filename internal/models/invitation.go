@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation states
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+	InvitationStatusExpired  = "expired"
+)
+
+// Invitation represents an invitation for an email address to join a tenant.
+// Only the SHA-256 hash of the latest invitation link token is stored, so
+// resending or revoking invalidates earlier links.
+type Invitation struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	TenantID       uint           `gorm:"not null;index" json:"tenant_id"`
+	InvitedByID    uint           `gorm:"not null" json:"invited_by_id"`
+	Email          string         `gorm:"not null;index" json:"email"`
+	Role           string         `gorm:"not null;default:'user'" json:"role"`
+	TokenHash      string         `gorm:"not null" json:"-"`
+	ExpiresAt      time.Time      `gorm:"not null" json:"expires_at"`
+	SentAt         time.Time      `json:"sent_at"`
+	AcceptedAt     *time.Time     `json:"accepted_at"`
+	AcceptedUserID *uint          `json:"accepted_user_id"`
+	RevokedAt      *time.Time     `json:"revoked_at"`
+}
+
+// TableName specifies the table name for Invitation
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// Status returns the current state of the invitation
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !i.ExpiresAt.After(time.Now()):
+		return InvitationStatusExpired
+	}
+	return InvitationStatusPending
+}
+
+// InvitationResponse represents the API response structure for Invitation
+type InvitationResponse struct {
+	ID             uint       `json:"id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	Status         string     `json:"status"`
+	InvitedByID    uint       `json:"invited_by_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	SentAt         time.Time  `json:"sent_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uint      `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ToResponse converts Invitation to InvitationResponse
+func (i *Invitation) ToResponse() InvitationResponse {
+	return InvitationResponse{
+		ID:             i.ID,
+		Email:          i.Email,
+		Role:           i.Role,
+		Status:         i.Status(),
+		InvitedByID:    i.InvitedByID,
+		ExpiresAt:      i.ExpiresAt,
+		SentAt:         i.SentAt,
+		AcceptedAt:     i.AcceptedAt,
+		AcceptedUserID: i.AcceptedUserID,
+		RevokedAt:      i.RevokedAt,
+		CreatedAt:      i.CreatedAt,
+	}
+}
+
+// InvitationCreateRequest represents the request structure for inviting a user
+type InvitationCreateRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
+}
+
+// InvitationAcceptRequest represents the request structure for accepting an invitation
+type InvitationAcceptRequest struct {
+	Token     string `json:"token" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required,min=8"`
+	FirstName string `json:"first_name" binding:"required"`
+	LastName  string `json:"last_name" binding:"required"`
+}
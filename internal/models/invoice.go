@@ -0,0 +1,235 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
+	"gorm.io/gorm"
+)
+
+// Invoice states
+const (
+	InvoiceStatusOpen = "open" // issued and awaiting payment
+	InvoiceStatusPaid = "paid"
+	InvoiceStatusVoid = "void" // credited in full before it was paid
+)
+
+// ErrDocumentFinalized is returned when an issued invoice or credit note
+// would be changed or deleted. Corrections are made with credit notes.
+var ErrDocumentFinalized = errors.New("issued invoices and credit notes can't be changed")
+
+// Invoice represents an invoice issued to a customer for a billing period of
+// its plan. Invoices are final once issued: only their status, delivery and
+// rendered PDF change afterwards.
+type Invoice struct {
+	ID            uint              `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	TenantID      uint              `gorm:"not null;index;uniqueIndex:idx_invoices_tenant_number;uniqueIndex:idx_invoices_customer_period" json:"tenant_id"`
+	CustomerID    uint              `gorm:"not null;index;uniqueIndex:idx_invoices_customer_period" json:"customer_id"`
+	PlanID        uint              `gorm:"not null" json:"plan_id"`
+	Number        string            `gorm:"not null;uniqueIndex:idx_invoices_tenant_number" json:"number"`
+	Status        string            `gorm:"not null;default:'open';index" json:"status"`
+	IssuedAt      time.Time         `gorm:"not null" json:"issued_at"`
+	DueAt         time.Time         `gorm:"not null" json:"due_at"`
+	PeriodStart   time.Time         `gorm:"not null;uniqueIndex:idx_invoices_customer_period" json:"period_start"`
+	PeriodEnd     time.Time         `gorm:"not null" json:"period_end"`
+	Subtotal      money.Money       `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	TaxRate       float64           `gorm:"not null" json:"tax_rate"` // percent
+	TaxAmount     money.Money       `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	Total         money.Money       `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+	TaxCountry    string            `json:"tax_country"`                                  // country whose VAT is charged, empty if none is
+	ReverseCharge bool              `gorm:"not null;default:false" json:"reverse_charge"` // the customer accounts for the VAT
+	CustomerVATID string            `json:"customer_vat_id"`                              // the customer's validated VAT ID when issued
+	TaxNote       string            `json:"tax_note"`                                     // why no VAT is charged
+	PDFPath       string            `json:"-"`                                            // rendered PDF, empty until rendered
+	SentAt        *time.Time        `json:"sent_at"`                                      // when the invoice was emailed to the customer
+	PaidAt        *time.Time        `json:"paid_at"`                                      // when the payment was received
+	Items         []InvoiceLineItem `gorm:"foreignKey:InvoiceID" json:"items,omitempty"`
+}
+
+// TableName specifies the table name for Invoice
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+// TenantScoped marks Invoice as belonging to a tenant
+func (Invoice) TenantScoped() {}
+
+// BeforeUpdate keeps the contents of issued invoices from changing. Only the
+// status and delivery can be updated, one column at a time or with Select.
+func (i *Invoice) BeforeUpdate(tx *gorm.DB) error {
+	if !updatesOnly(tx, "status", "paid_at", "sent_at", "pdf_path") {
+		return ErrDocumentFinalized
+	}
+	return nil
+}
+
+// BeforeDelete keeps issued invoices from being deleted
+func (i *Invoice) BeforeDelete(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// InvoiceLineItem represents a line of an invoice
+type InvoiceLineItem struct {
+	ID          uint        `gorm:"primarykey" json:"id"`
+	TenantID    uint        `gorm:"not null;index" json:"tenant_id"`
+	InvoiceID   uint        `gorm:"not null;index" json:"invoice_id"`
+	Position    int         `gorm:"not null" json:"position"`
+	Description string      `gorm:"not null" json:"description"`
+	Quantity    float64     `gorm:"not null" json:"quantity"`
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Total       money.Money `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+}
+
+// TableName specifies the table name for InvoiceLineItem
+func (InvoiceLineItem) TableName() string {
+	return "invoice_line_items"
+}
+
+// TenantScoped marks InvoiceLineItem as belonging to a tenant
+func (InvoiceLineItem) TenantScoped() {}
+
+// BeforeUpdate keeps the lines of issued invoices from changing
+func (i *InvoiceLineItem) BeforeUpdate(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// BeforeDelete keeps the lines of issued invoices from being deleted
+func (i *InvoiceLineItem) BeforeDelete(tx *gorm.DB) error {
+	return ErrDocumentFinalized
+}
+
+// updatesOnly reports whether an update sets no other columns than the given
+// ones. Updates of whole structs, such as Save, may set any column unless
+// they Select the columns to update.
+func updatesOnly(tx *gorm.DB, columns ...string) bool {
+	allowed := func(name string) bool {
+		if tx.Statement.Schema != nil {
+			if field := tx.Statement.Schema.LookUpField(name); field != nil {
+				name = field.DBName
+			}
+		}
+		return name == "updated_at" || slices.Contains(columns, name)
+	}
+
+	var updated []string
+	if values, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		for name := range values {
+			updated = append(updated, name)
+		}
+	} else {
+		updated = tx.Statement.Selects
+	}
+	if len(updated) == 0 {
+		return false
+	}
+	for _, name := range updated {
+		if !allowed(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// InvoiceResponse represents the API response structure for Invoice
+type InvoiceResponse struct {
+	ID            uint                      `json:"id"`
+	CustomerID    uint                      `json:"customer_id"`
+	PlanID        uint                      `json:"plan_id"`
+	Number        string                    `json:"number"`
+	Status        string                    `json:"status"`
+	IssuedAt      time.Time                 `json:"issued_at"`
+	DueAt         time.Time                 `json:"due_at"`
+	PeriodStart   time.Time                 `json:"period_start"`
+	PeriodEnd     time.Time                 `json:"period_end"`
+	Subtotal      money.Money               `json:"subtotal"`
+	TaxRate       float64                   `json:"tax_rate"`
+	TaxAmount     money.Money               `json:"tax_amount"`
+	Total         money.Money               `json:"total"`
+	TaxCountry    string                    `json:"tax_country"`
+	ReverseCharge bool                      `json:"reverse_charge"`
+	CustomerVATID string                    `json:"customer_vat_id"`
+	TaxNote       string                    `json:"tax_note"`
+	SentAt        *time.Time                `json:"sent_at"`
+	PaidAt        *time.Time                `json:"paid_at"`
+	Items         []InvoiceLineItemResponse `json:"items"`
+	CreatedAt     time.Time                 `json:"created_at"`
+}
+
+// InvoiceLineItemResponse represents the API response structure for InvoiceLineItem
+type InvoiceLineItemResponse struct {
+	Position    int         `json:"position"`
+	Description string      `json:"description"`
+	Quantity    float64     `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Total       money.Money `json:"total"`
+}
+
+// ToResponse converts Invoice to InvoiceResponse
+func (i *Invoice) ToResponse() InvoiceResponse {
+	response := InvoiceResponse{
+		ID:            i.ID,
+		CustomerID:    i.CustomerID,
+		PlanID:        i.PlanID,
+		Number:        i.Number,
+		Status:        i.Status,
+		IssuedAt:      i.IssuedAt,
+		DueAt:         i.DueAt,
+		PeriodStart:   i.PeriodStart,
+		PeriodEnd:     i.PeriodEnd,
+		Subtotal:      i.Subtotal,
+		TaxRate:       i.TaxRate,
+		TaxAmount:     i.TaxAmount,
+		Total:         i.Total,
+		TaxCountry:    i.TaxCountry,
+		ReverseCharge: i.ReverseCharge,
+		CustomerVATID: i.CustomerVATID,
+		TaxNote:       i.TaxNote,
+		SentAt:        i.SentAt,
+		PaidAt:        i.PaidAt,
+		Items:         make([]InvoiceLineItemResponse, 0, len(i.Items)),
+		CreatedAt:     i.CreatedAt,
+	}
+	for _, item := range i.Items {
+		response.Items = append(response.Items, InvoiceLineItemResponse{
+			Position:    item.Position,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+	return response
+}
+
+// ToInvoiceData converts Invoice to the data of the invoice PDF template
+func (i *Invoice) ToInvoiceData(company CompanyInfo, customer *Customer) InvoiceData {
+	data := InvoiceData{
+		InvoiceNumber: i.Number,
+		InvoiceDate:   i.IssuedAt,
+		DueDate:       i.DueAt,
+		Company:       company,
+		Customer:      customer.ToCustomerInfo(i.CustomerVATID),
+		Items:         make([]InvoiceItem, 0, len(i.Items)),
+		Subtotal:      i.Subtotal,
+		TaxRate:       i.TaxRate,
+		TaxAmount:     i.TaxAmount,
+		Total:         i.Total,
+		Notes:         fmt.Sprintf("Billing period %s to %s", i.PeriodStart.Format("2006-01-02"), i.PeriodEnd.Format("2006-01-02")),
+		ReverseCharge: i.ReverseCharge,
+		TaxNote:       i.TaxNote,
+	}
+	for _, item := range i.Items {
+		data.Items = append(data.Items, InvoiceItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+	return data
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Login throttle scopes
+const (
+	ThrottleScopeAccount = "account"
+	ThrottleScopeIP      = "ip"
+)
+
+// LoginThrottle tracks failed login attempts for an account or a client IP
+type LoginThrottle struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	Scope         string         `gorm:"not null;uniqueIndex:idx_login_throttle_scope_identifier" json:"scope"`
+	Identifier    string         `gorm:"not null;uniqueIndex:idx_login_throttle_scope_identifier" json:"identifier"` // account key or IP address
+	Failures      int            `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time      `json:"last_failure_at"`
+	BlockedUntil  *time.Time     `json:"blocked_until"`
+}
+
+// TableName specifies the table name for LoginThrottle
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
@@ -6,11 +6,13 @@ import (
 	"gorm.io/gorm"
 )
 
-// Newsletter represents a newsletter subscription
+// Newsletter represents a subscription to a tenant's newsletter. The same
+// email can subscribe to the lists of several tenants.
 type Newsletter struct {
 	ID          uint           `json:"id" gorm:"primaryKey" example:"1"`
+	TenantID    uint           `json:"tenantId" gorm:"not null;index;index:idx_newsletters_tenant_email" example:"1"`
 	Name        string         `json:"name" gorm:"not null" example:"John Doe"`
-	Email       string         `json:"email" gorm:"not null;index" example:"john.doe@example.com"`
+	Email       string         `json:"email" gorm:"not null;index;index:idx_newsletters_tenant_email" example:"john.doe@example.com"`
 	Interest    string         `json:"interest" gorm:"default:'general'" example:"mental_health"`
 	Source      string         `json:"source" gorm:"not null" example:"website"`
 	LastContact time.Time      `json:"lastContact" gorm:"autoUpdateTime" example:"2025-08-03T10:00:00Z"`
@@ -24,6 +26,9 @@ func (Newsletter) TableName() string {
 	return "newsletters"
 }
 
+// TenantScoped marks Newsletter as belonging to a tenant
+func (Newsletter) TenantScoped() {}
+
 // NewsletterSubscribeRequest represents the request for newsletter subscription
 type NewsletterSubscribeRequest struct {
 	Email     string `json:"email" binding:"required,email"`
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of numbered documents, each numbered in its own sequence
+const (
+	NumberingKindInvoice    = "invoice"
+	NumberingKindCreditNote = "credit_note" // cancellations and credit notes
+)
+
+// NumberingKinds lists the kinds of numbered documents
+var NumberingKinds = []string{NumberingKindInvoice, NumberingKindCreditNote}
+
+// IsValidNumberingKind checks if documents of a kind are numbered
+func IsValidNumberingKind(kind string) bool {
+	for _, k := range NumberingKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NumberingScheme represents how a tenant's documents of a kind are
+// numbered. Tenants without a scheme of their own use the configured default.
+type NumberingScheme struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TenantID  uint      `gorm:"not null;uniqueIndex:idx_numbering_schemes_tenant_kind" json:"tenant_id"`
+	Kind      string    `gorm:"not null;uniqueIndex:idx_numbering_schemes_tenant_kind" json:"kind"`
+	Prefix    string    `gorm:"not null;default:''" json:"prefix"`
+	YearReset bool      `gorm:"not null;default:false" json:"year_reset"` // restart at 1 every year, with the year in the number
+	Padding   int       `gorm:"not null;default:6" json:"padding"`        // minimum number of digits, zero padded
+}
+
+// TableName specifies the table name for NumberingScheme
+func (NumberingScheme) TableName() string {
+	return "numbering_schemes"
+}
+
+// TenantScoped marks NumberingScheme as belonging to a tenant
+func (NumberingScheme) TenantScoped() {}
+
+// SequenceYear returns the year whose sequence numbers a document issued at
+// date, or 0 if the sequence doesn't restart every year
+func (s *NumberingScheme) SequenceYear(date time.Time) int {
+	if !s.YearReset {
+		return 0
+	}
+	return date.Year()
+}
+
+// Format returns the document number for a value of the year's sequence
+func (s *NumberingScheme) Format(year int, value int64) string {
+	if year != 0 {
+		return fmt.Sprintf("%s%d-%0*d", s.Prefix, year, s.Padding, value)
+	}
+	return fmt.Sprintf("%s%0*d", s.Prefix, s.Padding, value)
+}
+
+// NumberSequence represents the last number handed out in a tenant's
+// sequence of documents of a kind. Year is 0 for sequences that don't
+// restart every year.
+type NumberSequence struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TenantID  uint      `gorm:"not null;uniqueIndex:idx_number_sequences_tenant_kind_year" json:"tenant_id"`
+	Kind      string    `gorm:"not null;uniqueIndex:idx_number_sequences_tenant_kind_year" json:"kind"`
+	Year      int       `gorm:"not null;uniqueIndex:idx_number_sequences_tenant_kind_year" json:"year"`
+	LastValue int64     `gorm:"not null;default:0" json:"last_value"`
+}
+
+// TableName specifies the table name for NumberSequence
+func (NumberSequence) TableName() string {
+	return "number_sequences"
+}
+
+// TenantScoped marks NumberSequence as belonging to a tenant
+func (NumberSequence) TenantScoped() {}
+
+// NumberingSchemeResponse represents the API response structure for NumberingScheme
+type NumberingSchemeResponse struct {
+	Kind       string `json:"kind"`
+	Prefix     string `json:"prefix"`
+	YearReset  bool   `json:"year_reset"`
+	Padding    int    `json:"padding"`
+	NextNumber string `json:"next_number"` // number the next document would get now
+}
+
+// NumberingSchemeUpdateRequest represents the request structure for changing a numbering scheme
+type NumberingSchemeUpdateRequest struct {
+	Prefix    *string `json:"prefix" binding:"omitempty,max=20"`
+	YearReset *bool   `json:"year_reset"`
+	Padding   *int    `json:"padding" binding:"omitempty,min=1,max=12"`
+}
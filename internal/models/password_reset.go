@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken represents a single-use password reset token.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	TokenHash string         `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time      `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time     `json:"used_at"`
+	RequestIP string         `json:"request_ip"`
+}
+
+// TableName specifies the table name for PasswordResetToken
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// ForgotPasswordRequest represents the request structure for requesting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the request structure for resetting a password
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
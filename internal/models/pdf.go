@@ -3,6 +3,8 @@ package models
 import (
 	"fmt"
 	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 )
 
 // PDFTemplate represents a PDF template configuration
@@ -79,13 +81,19 @@ type InvoiceData struct {
 	// Invoice items
 	Items []InvoiceItem `json:"items" validate:"required,min=1"`
 
-	// Totals
-	Subtotal  float64 `json:"subtotal"`
-	TaxRate   float64 `json:"tax_rate"`
-	TaxAmount float64 `json:"tax_amount"`
-	Discount  float64 `json:"discount"`
-	Total     float64 `json:"total"`
-	Currency  string  `json:"currency" validate:"required"`
+	// Totals, in the currency of Total
+	Subtotal  money.Money `json:"subtotal"`
+	TaxRate   float64     `json:"tax_rate"`
+	TaxAmount money.Money `json:"tax_amount"`
+	Discount  money.Money `json:"discount"`
+	Total     money.Money `json:"total" validate:"required"`
+
+	// Locale amounts are formatted in by the template's FormatCurrency
+	Locale string `json:"locale,omitempty"`
+
+	// VAT
+	ReverseCharge bool   `json:"reverse_charge,omitempty"`
+	TaxNote       string `json:"tax_note,omitempty"` // why no VAT is charged
 
 	// Additional information
 	Notes       string `json:"notes,omitempty"`
@@ -93,6 +101,30 @@ type InvoiceData struct {
 	PaymentInfo string `json:"payment_info,omitempty"`
 }
 
+// TemplateData returns the invoice as data of a PDF template, keyed by field name
+func (d InvoiceData) TemplateData() map[string]interface{} {
+	return map[string]interface{}{
+		"InvoiceNumber": d.InvoiceNumber,
+		"InvoiceDate":   d.InvoiceDate,
+		"DueDate":       d.DueDate,
+		"Company":       d.Company,
+		"Customer":      d.Customer,
+		"Items":         d.Items,
+		"Subtotal":      d.Subtotal,
+		"TaxRate":       d.TaxRate,
+		"TaxAmount":     d.TaxAmount,
+		"Discount":      d.Discount,
+		"Total":         d.Total,
+		"Currency":      string(d.Total.Currency),
+		"Locale":        d.Locale,
+		"ReverseCharge": d.ReverseCharge,
+		"TaxNote":       d.TaxNote,
+		"Notes":         d.Notes,
+		"Terms":         d.Terms,
+		"PaymentInfo":   d.PaymentInfo,
+	}
+}
+
 // CompanyInfo represents company information
 type CompanyInfo struct {
 	Name    string `json:"name" validate:"required"`
@@ -119,15 +151,16 @@ type CustomerInfo struct {
 	State   string `json:"state"`
 	ZipCode string `json:"zip_code"`
 	Country string `json:"country"`
+	VATID   string `json:"vat_id,omitempty"`
 }
 
 // InvoiceItem represents an invoice line item
 type InvoiceItem struct {
-	Description string  `json:"description" validate:"required"`
-	Quantity    float64 `json:"quantity" validate:"required,gt=0"`
-	UnitPrice   float64 `json:"unit_price" validate:"required,gte=0"`
-	Total       float64 `json:"total"`
-	Category    string  `json:"category,omitempty"`
+	Description string      `json:"description" validate:"required"`
+	Quantity    float64     `json:"quantity" validate:"required,gt=0"`
+	UnitPrice   money.Money `json:"unit_price" validate:"required"`
+	Total       money.Money `json:"total"`
+	Category    string      `json:"category,omitempty"`
 }
 
 // ReportData represents data structure for report templates
@@ -295,7 +328,7 @@ func ValidateInvoiceData(data InvoiceData) []string {
 		if item.Quantity <= 0 {
 			errors = append(errors, fmt.Sprintf("item %d: quantity must be greater than 0", i+1))
 		}
-		if item.UnitPrice < 0 {
+		if item.UnitPrice.IsNegative() {
 			errors = append(errors, fmt.Sprintf("item %d: unit price cannot be negative", i+1))
 		}
 	}
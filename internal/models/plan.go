@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 	"gorm.io/gorm"
 )
 
@@ -15,12 +16,11 @@ type Plan struct {
 	Name          string         `gorm:"not null" json:"name" binding:"required"`
 	Slug          string         `gorm:"not null;uniqueIndex" json:"slug" binding:"required"`
 	Description   string         `json:"description"`
-	Price         float64        `gorm:"not null" json:"price" binding:"required"`
-	Currency      string         `gorm:"not null;default:'EUR'" json:"currency"`
+	Price         money.Money    `gorm:"embedded;embeddedPrefix:price_" json:"price"`
 	InvoicePeriod string         `gorm:"not null;default:'monthly'" json:"invoice_period"`
 	MaxUsers      int            `gorm:"default:10" json:"max_users"`
 	MaxClients    int            `gorm:"default:100" json:"max_clients"`
-	Features      string         `gorm:"type:text" json:"features"`
+	Features      PlanFeatures   `gorm:"type:text" json:"features"`
 	Active        bool           `gorm:"default:true" json:"active"`
 }
 
@@ -31,18 +31,17 @@ func (Plan) TableName() string {
 
 // PlanResponse represents the API response structure for Plan
 type PlanResponse struct {
-	ID            uint      `json:"id"`
-	Name          string    `json:"name"`
-	Slug          string    `json:"slug"`
-	Description   string    `json:"description"`
-	Price         float64   `json:"price"`
-	Currency      string    `json:"currency"`
-	InvoicePeriod string    `json:"invoice_period"`
-	MaxUsers      int       `json:"max_users"`
-	MaxClients    int       `json:"max_clients"`
-	Features      string    `json:"features"`
-	Active        bool      `json:"active"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint         `json:"id"`
+	Name          string       `json:"name"`
+	Slug          string       `json:"slug"`
+	Description   string       `json:"description"`
+	Price         money.Money  `json:"price"`
+	InvoicePeriod string       `json:"invoice_period"`
+	MaxUsers      int          `json:"max_users"`
+	MaxClients    int          `json:"max_clients"`
+	Features      PlanFeatures `json:"features" swaggertype:"object"`
+	Active        bool         `json:"active"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
 
 // ToResponse converts Plan to PlanResponse
@@ -53,7 +52,6 @@ func (p *Plan) ToResponse() PlanResponse {
 		Slug:          p.Slug,
 		Description:   p.Description,
 		Price:         p.Price,
-		Currency:      p.Currency,
 		InvoicePeriod: p.InvoicePeriod,
 		MaxUsers:      p.MaxUsers,
 		MaxClients:    p.MaxClients,
@@ -65,27 +63,25 @@ func (p *Plan) ToResponse() PlanResponse {
 
 // PlanCreateRequest represents the request structure for creating a plan
 type PlanCreateRequest struct {
-	Name          string  `json:"name" binding:"required"`
-	Slug          string  `json:"slug" binding:"required"`
-	Description   string  `json:"description"`
-	Price         float64 `json:"price" binding:"required"`
-	Currency      string  `json:"currency"`
-	InvoicePeriod string  `json:"invoice_period"`
-	MaxUsers      int     `json:"max_users"`
-	MaxClients    int     `json:"max_clients"`
-	Features      string  `json:"features"`
-	Active        *bool   `json:"active"`
+	Name          string       `json:"name" binding:"required"`
+	Slug          string       `json:"slug" binding:"required"`
+	Description   string       `json:"description"`
+	Price         money.Money  `json:"price"` // currency defaults to EUR
+	InvoicePeriod string       `json:"invoice_period"`
+	MaxUsers      int          `json:"max_users"`
+	MaxClients    int          `json:"max_clients"`
+	Features      PlanFeatures `json:"features" swaggertype:"object"`
+	Active        *bool        `json:"active"`
 }
 
 // PlanUpdateRequest represents the request structure for updating a plan
 type PlanUpdateRequest struct {
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Price         *float64 `json:"price"`
-	Currency      string   `json:"currency"`
-	InvoicePeriod string   `json:"invoice_period"`
-	MaxUsers      *int     `json:"max_users"`
-	MaxClients    *int     `json:"max_clients"`
-	Features      string   `json:"features"`
-	Active        *bool    `json:"active"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	Price         *money.Money  `json:"price"` // currency defaults to the plan's
+	InvoicePeriod string        `json:"invoice_period"`
+	MaxUsers      *int          `json:"max_users"`
+	MaxClients    *int          `json:"max_clients"`
+	Features      *PlanFeatures `json:"features" swaggertype:"object"`
+	Active        *bool         `json:"active"`
 }
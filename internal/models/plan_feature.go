@@ -0,0 +1,239 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of plan features
+const (
+	FeatureKindFlag  = "flag"  // boolean switch, e.g. PDF generation
+	FeatureKindQuota = "quota" // non-negative number, e.g. the contacts a tenant may keep
+	FeatureKindEnum  = "enum"  // one of a fixed set of values, e.g. the support level
+)
+
+// FeatureDefinition describes a feature that plans can carry
+type FeatureDefinition struct {
+	Key         string   `json:"key"`
+	Kind        string   `json:"kind"`
+	Values      []string `json:"values,omitempty"` // allowed values of enum features
+	Description string   `json:"description"`
+}
+
+var (
+	featureMu          sync.RWMutex
+	featureDefinitions = map[string]FeatureDefinition{
+		"pdf":      {Key: "pdf", Kind: FeatureKindFlag, Description: "PDF generation"},
+		"contacts": {Key: "contacts", Kind: FeatureKindQuota, Description: "Maximum number of contacts"},
+		"support":  {Key: "support", Kind: FeatureKindEnum, Values: []string{"email", "priority"}, Description: "Support level"},
+	}
+)
+
+// RegisterFeature adds or replaces a feature that plans can carry, so host
+// applications can gate their own functionality by plan
+func RegisterFeature(definition FeatureDefinition) {
+	featureMu.Lock()
+	defer featureMu.Unlock()
+	featureDefinitions[definition.Key] = definition
+}
+
+// LookupFeature returns the definition of a feature
+func LookupFeature(key string) (FeatureDefinition, bool) {
+	featureMu.RLock()
+	defer featureMu.RUnlock()
+	definition, ok := featureDefinitions[key]
+	return definition, ok
+}
+
+// FeatureDefinitions returns all known features ordered by key
+func FeatureDefinitions() []FeatureDefinition {
+	featureMu.RLock()
+	defer featureMu.RUnlock()
+	definitions := make([]FeatureDefinition, 0, len(featureDefinitions))
+	for _, definition := range featureDefinitions {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Key < definitions[j].Key
+	})
+	return definitions
+}
+
+// PlanFeatures holds the features of a plan by kind. In JSON and in the
+// database it is a flat object such as {"pdf": true, "contacts": 500, "support": "email"}.
+type PlanFeatures struct {
+	Flags  map[string]bool
+	Quotas map[string]int
+	Values map[string]string
+}
+
+// Enabled checks if the plan includes the feature: a true flag, a positive
+// quota or a set enum value
+func (f PlanFeatures) Enabled(key string) bool {
+	if enabled, ok := f.Flags[key]; ok {
+		return enabled
+	}
+	if quota, ok := f.Quotas[key]; ok {
+		return quota > 0
+	}
+	return f.Values[key] != ""
+}
+
+// Quota returns the quota of the feature and whether the plan sets it
+func (f PlanFeatures) Quota(key string) (int, bool) {
+	quota, ok := f.Quotas[key]
+	return quota, ok
+}
+
+// EnumValue returns the value of an enum feature, or "" if the plan doesn't set it
+func (f PlanFeatures) EnumValue(key string) string {
+	return f.Values[key]
+}
+
+// Validate checks the features against their definitions
+func (f PlanFeatures) Validate() error {
+	check := func(key, kind string) (FeatureDefinition, error) {
+		definition, ok := LookupFeature(key)
+		if !ok {
+			return definition, fmt.Errorf("unknown feature %q", key)
+		}
+		if definition.Kind != kind {
+			return definition, fmt.Errorf("feature %q must be a %s", key, definition.Kind)
+		}
+		return definition, nil
+	}
+
+	for key := range f.Flags {
+		if _, err := check(key, FeatureKindFlag); err != nil {
+			return err
+		}
+	}
+	for key, quota := range f.Quotas {
+		if _, err := check(key, FeatureKindQuota); err != nil {
+			return err
+		}
+		if quota < 0 {
+			return fmt.Errorf("feature %q must not be negative", key)
+		}
+	}
+	for key, value := range f.Values {
+		definition, err := check(key, FeatureKindEnum)
+		if err != nil {
+			return err
+		}
+		if !containsString(definition.Values, value) {
+			return fmt.Errorf("feature %q must be one of: %s", key, strings.Join(definition.Values, ", "))
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the features as a flat object
+func (f PlanFeatures) MarshalJSON() ([]byte, error) {
+	features := make(map[string]interface{}, len(f.Flags)+len(f.Quotas)+len(f.Values))
+	for key, enabled := range f.Flags {
+		features[key] = enabled
+	}
+	for key, quota := range f.Quotas {
+		features[key] = quota
+	}
+	for key, value := range f.Values {
+		features[key] = value
+	}
+	return json.Marshal(features)
+}
+
+// UnmarshalJSON decodes a flat object, sorting values by their JSON type
+func (f *PlanFeatures) UnmarshalJSON(data []byte) error {
+	var features map[string]interface{}
+	if err := json.Unmarshal(data, &features); err != nil {
+		return err
+	}
+
+	*f = PlanFeatures{}
+	for key, value := range features {
+		switch v := value.(type) {
+		case bool:
+			f.setFlag(key, v)
+		case float64:
+			if v != math.Trunc(v) {
+				return fmt.Errorf("feature %q must be a whole number", key)
+			}
+			if f.Quotas == nil {
+				f.Quotas = make(map[string]int)
+			}
+			f.Quotas[key] = int(v)
+		case string:
+			if f.Values == nil {
+				f.Values = make(map[string]string)
+			}
+			f.Values[key] = v
+		default:
+			return fmt.Errorf("feature %q must be a boolean, number or string", key)
+		}
+	}
+	return nil
+}
+
+// Value stores the features as JSON text
+func (f PlanFeatures) Value() (driver.Value, error) {
+	data, err := f.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads features stored as JSON text. Plans saved before features were
+// typed may hold a comma-separated list of names, which become flags.
+func (f *PlanFeatures) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported plan features type %T", value)
+	}
+
+	*f = PlanFeatures{}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	if strings.HasPrefix(raw, "{") {
+		return f.UnmarshalJSON([]byte(raw))
+	}
+
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			f.setFlag(key, true)
+		}
+	}
+	return nil
+}
+
+// setFlag sets a flag, creating the map on first use
+func (f *PlanFeatures) setFlag(key string, enabled bool) {
+	if f.Flags == nil {
+		f.Flags = make(map[string]bool)
+	}
+	f.Flags[key] = enabled
+}
+
+// containsString checks if the slice holds the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package models
+
+// Principal types
+const (
+	PrincipalTypeUser   = "user"
+	PrincipalTypeAPIKey = "api_key"
+)
+
+// Principal represents the authenticated caller of a request, either a user
+// logged in with a JWT or an integration using an API key
+type Principal struct {
+	Type     string
+	TenantID uint
+	UserID   uint   // zero for API keys
+	APIKeyID uint   // zero for users
+	Role     string // empty for API keys
+	Scopes   []string
+}
+
+// IsAPIKey checks if the principal authenticated with an API key
+func (p *Principal) IsAPIKey() bool {
+	return p.Type == PrincipalTypeAPIKey
+}
+
+// HasScope checks if the principal may use the given scope. Users are not
+// restricted by scopes; their access is governed by their role.
+func (p *Principal) HasScope(scope string) bool {
+	if !p.IsAPIKey() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
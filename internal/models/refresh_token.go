@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken represents an opaque refresh token issued to a device.
+// Tokens issued from the same login share a FamilyID; every refresh rotates
+// the token and replaying an already rotated token revokes the whole family.
+type RefreshToken struct {
+	ID              uint           `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	UserID          uint           `gorm:"not null;index" json:"user_id"`
+	TenantID        uint           `gorm:"not null" json:"tenant_id"`
+	FamilyID        string         `gorm:"not null;index" json:"family_id"`
+	TokenHash       string         `gorm:"not null;uniqueIndex" json:"-"`
+	AccessTokenID   string         `gorm:"index" json:"-"`
+	AccessExpiresAt time.Time      `json:"-"`
+	DeviceName      string         `json:"device_name"`
+	IPAddress       string         `json:"ip_address"`
+	UserAgent       string         `json:"user_agent"`
+	ExpiresAt       time.Time      `gorm:"not null" json:"expires_at"`
+	UsedAt          *time.Time     `json:"used_at"`
+	RevokedAt       *time.Time     `json:"revoked_at"`
+	RevokedReason   string         `json:"revoked_reason"`
+}
+
+// TableName specifies the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive checks if the refresh token can still be exchanged
+func (rt *RefreshToken) IsActive() bool {
+	return rt.UsedAt == nil && rt.RevokedAt == nil && time.Now().Before(rt.ExpiresAt)
+}
+
+// RefreshTokenRequest represents the request structure for refreshing tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
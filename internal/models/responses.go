@@ -54,3 +54,14 @@ func ErrorResponseFunc(message string, err string) APIResponse {
 		Error:   err,
 	}
 }
+
+// ErrorResponseWithDetails creates an error API response carrying structured
+// details in its data, such as the plan limit a request was refused for
+func ErrorResponseWithDetails(message string, err string, details interface{}) APIResponse {
+	return APIResponse{
+		Success: false,
+		Message: message,
+		Error:   err,
+		Data:    details,
+	}
+}
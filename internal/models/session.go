@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents a login on a device. It owns the refresh token family
+// issued at login and is referenced by the sid claim of its access tokens.
+type Session struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	UserID        uint           `gorm:"not null;index" json:"user_id"`
+	TenantID      uint           `gorm:"not null" json:"tenant_id"`
+	FamilyID      string         `gorm:"not null;uniqueIndex" json:"-"`
+	DeviceName    string         `json:"device_name"`
+	IPAddress     string         `json:"ip_address"`
+	UserAgent     string         `json:"user_agent"`
+	LastSeenAt    time.Time      `json:"last_seen_at"`
+	ExpiresAt     time.Time      `gorm:"not null" json:"expires_at"`
+	RevokedAt     *time.Time     `json:"revoked_at"`
+	RevokedReason string         `json:"revoked_reason"`
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive checks if the session is neither revoked nor expired
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// SessionResponse represents the API response structure for Session
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// ToResponse converts Session to SessionResponse
+func (s *Session) ToResponse() SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		IPAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
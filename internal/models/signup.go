@@ -0,0 +1,28 @@
+package models
+
+// SignupRequest represents the request structure for signing up a new organisation
+type SignupRequest struct {
+	OrganizationName string `json:"organization_name" binding:"required"`
+	Slug             string `json:"slug"` // generated from the organisation name if empty
+	PlanID           uint   `json:"plan_id" binding:"required"`
+	Username         string `json:"username" binding:"required"`
+	Email            string `json:"email" binding:"required,email"`
+	Password         string `json:"password" binding:"required,min=8"`
+	FirstName        string `json:"first_name" binding:"required"`
+	LastName         string `json:"last_name" binding:"required"`
+	BillingEmail     string `json:"billing_email" binding:"omitempty,email"` // defaults to the owner's email
+	Street           string `json:"street"`
+	Zip              string `json:"zip"`
+	City             string `json:"city"`
+	Country          string `json:"country"`
+	TaxID            string `json:"tax_id"`
+	VAT              string `json:"vat"`
+}
+
+// SignupResponse represents the records provisioned by a signup
+type SignupResponse struct {
+	Tenant       TenantResponse       `json:"tenant"`
+	User         UserResponse         `json:"user"`
+	Customer     CustomerResponse     `json:"customer"`
+	Subscription SubscriptionResponse `json:"subscription"`
+}
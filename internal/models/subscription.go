@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Subscription states
+const (
+	SubscriptionStatusTrialing = "trialing" // free trial until TrialEndsAt
+	SubscriptionStatusActive   = "active"   // paid and renewing every billing period
+	SubscriptionStatusPastDue  = "past_due" // payment overdue, expires after the grace period
+	SubscriptionStatusCanceled = "canceled" // ended by the tenant
+	SubscriptionStatusExpired  = "expired"  // ended because payment never arrived
+)
+
+// Subscription represents a tenant's subscription to a plan. A tenant has at
+// most one current subscription; ended ones are kept as history. The plan of
+// the current subscription is mirrored to Tenant.PlanID for entitlements.
+type Subscription struct {
+	ID                 uint       `gorm:"primarykey" json:"id"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	TenantID           uint       `gorm:"not null;index" json:"tenant_id"`
+	PlanID             uint       `gorm:"not null" json:"plan_id"`
+	Status             string     `gorm:"not null;index" json:"status"`
+	StartedAt          time.Time  `gorm:"not null" json:"started_at"`
+	TrialEndsAt        *time.Time `json:"trial_ends_at"`
+	CurrentPeriodStart time.Time  `gorm:"not null" json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `gorm:"not null;index" json:"current_period_end"`
+	PendingPlanID      *uint      `json:"pending_plan_id"`                                    // plan that takes over at the end of the period
+	CancelAtPeriodEnd  bool       `gorm:"not null;default:false" json:"cancel_at_period_end"` // cancellation scheduled for the end of the period
+	CanceledAt         *time.Time `json:"canceled_at"`
+	PastDueSince       *time.Time `json:"past_due_since"`
+	EndedAt            *time.Time `json:"ended_at"`
+}
+
+// TableName specifies the table name for Subscription
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// TenantScoped marks Subscription as belonging to a tenant
+func (Subscription) TenantScoped() {}
+
+// IsEnded checks if the subscription was canceled or has expired
+func (s *Subscription) IsEnded() bool {
+	return s.Status == SubscriptionStatusCanceled || s.Status == SubscriptionStatusExpired
+}
+
+// SubscriptionResponse represents the API response structure for Subscription
+type SubscriptionResponse struct {
+	ID                 uint       `json:"id"`
+	PlanID             uint       `json:"plan_id"`
+	Status             string     `json:"status"`
+	StartedAt          time.Time  `json:"started_at"`
+	TrialEndsAt        *time.Time `json:"trial_ends_at"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"`
+	PendingPlanID      *uint      `json:"pending_plan_id"`
+	CancelAtPeriodEnd  bool       `json:"cancel_at_period_end"`
+	CanceledAt         *time.Time `json:"canceled_at"`
+	PastDueSince       *time.Time `json:"past_due_since"`
+	EndedAt            *time.Time `json:"ended_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// ToResponse converts Subscription to SubscriptionResponse
+func (s *Subscription) ToResponse() SubscriptionResponse {
+	return SubscriptionResponse{
+		ID:                 s.ID,
+		PlanID:             s.PlanID,
+		Status:             s.Status,
+		StartedAt:          s.StartedAt,
+		TrialEndsAt:        s.TrialEndsAt,
+		CurrentPeriodStart: s.CurrentPeriodStart,
+		CurrentPeriodEnd:   s.CurrentPeriodEnd,
+		PendingPlanID:      s.PendingPlanID,
+		CancelAtPeriodEnd:  s.CancelAtPeriodEnd,
+		CanceledAt:         s.CanceledAt,
+		PastDueSince:       s.PastDueSince,
+		EndedAt:            s.EndedAt,
+		CreatedAt:          s.CreatedAt,
+	}
+}
+
+// SubscriptionCreateRequest represents the request structure for subscribing to a plan
+type SubscriptionCreateRequest struct {
+	PlanID uint `json:"plan_id" binding:"required"`
+}
+
+// SubscriptionChangeRequest represents the request structure for upgrading or downgrading
+type SubscriptionChangeRequest struct {
+	PlanID      uint `json:"plan_id" binding:"required"`
+	AtPeriodEnd bool `json:"at_period_end"` // switch when the current period ends instead of now
+}
+
+// SubscriptionCancelRequest represents the request structure for canceling a subscription
+type SubscriptionCancelRequest struct {
+	AtPeriodEnd bool `json:"at_period_end"` // keep the subscription until the current period ends
+}
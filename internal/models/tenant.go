@@ -6,14 +6,32 @@ import (
 	"gorm.io/gorm"
 )
 
+// TenantScoped is implemented by models whose rows belong to a single tenant
+// through a tenant_id column. Queries on these models are restricted to the
+// request's tenant by the tenancy package.
+type TenantScoped interface {
+	TenantScoped()
+}
+
+// Tenant states
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+)
+
 // Tenant represents a tenant in the multi-tenant system
 type Tenant struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	Name      string         `gorm:"not null;unique" json:"name" binding:"required"`
-	Slug      string         `gorm:"not null;unique" json:"slug" binding:"required"`
+	ID                uint           `gorm:"primarykey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	Name              string         `gorm:"not null;unique" json:"name" binding:"required"`
+	Slug              string         `gorm:"not null;unique" json:"slug" binding:"required"`
+	RequireMFA        bool           `gorm:"default:false" json:"require_mfa"`        // every user must enrol in two-factor authentication
+	PlanID            *uint          `json:"plan_id"`                                 // plan whose limits apply to the tenant (nullable)
+	BillingCustomerID *uint          `gorm:"index" json:"billing_customer_id"`        // customer of the platform tenant whose invoices pay the subscription (nullable)
+	Status            string         `gorm:"not null;default:'active'" json:"status"` // suspended tenants can't log in
+	SuspendedAt       *time.Time     `json:"suspended_at"`
 }
 
 // TableName specifies the table name for Tenant
@@ -21,32 +39,68 @@ func (Tenant) TableName() string {
 	return "tenants"
 }
 
+// IsSuspended checks if the tenant's users are locked out
+func (t *Tenant) IsSuspended() bool {
+	return t.Status == TenantStatusSuspended
+}
+
 // TenantResponse represents the API response structure for Tenant
 type TenantResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Slug      string    `json:"slug"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                uint       `json:"id"`
+	Name              string     `json:"name"`
+	Slug              string     `json:"slug"`
+	RequireMFA        bool       `json:"require_mfa"`
+	PlanID            *uint      `json:"plan_id"`
+	BillingCustomerID *uint      `json:"billing_customer_id"`
+	Status            string     `json:"status"`
+	SuspendedAt       *time.Time `json:"suspended_at"`
+	CreatedAt         time.Time  `json:"created_at"`
 }
 
 // ToResponse converts Tenant to TenantResponse
 func (t *Tenant) ToResponse() TenantResponse {
 	return TenantResponse{
-		ID:        t.ID,
-		Name:      t.Name,
-		Slug:      t.Slug,
-		CreatedAt: t.CreatedAt,
+		ID:                t.ID,
+		Name:              t.Name,
+		Slug:              t.Slug,
+		RequireMFA:        t.RequireMFA,
+		PlanID:            t.PlanID,
+		BillingCustomerID: t.BillingCustomerID,
+		Status:            t.Status,
+		SuspendedAt:       t.SuspendedAt,
+		CreatedAt:         t.CreatedAt,
 	}
 }
 
 // TenantCreateRequest represents the request structure for creating a tenant
 type TenantCreateRequest struct {
-	Name string `json:"name" binding:"required"`
-	Slug string `json:"slug" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+	Slug   string `json:"slug" binding:"required"`
+	PlanID *uint  `json:"plan_id"`
 }
 
 // TenantUpdateRequest represents the request structure for updating a tenant
 type TenantUpdateRequest struct {
-	Name string `json:"name"`
-	Slug string `json:"slug"`
+	Name              string `json:"name"`
+	Slug              string `json:"slug"`
+	PlanID            *uint  `json:"plan_id"`
+	BillingCustomerID *uint  `json:"billing_customer_id"` // customer of the super-admin's tenant; 0 unlinks
+}
+
+// TenantUsage represents the usage counters of a tenant
+type TenantUsage struct {
+	Users     int64 `json:"users"`
+	Customers int64 `json:"customers"`
+	Contacts  int64 `json:"contacts"`
+}
+
+// TenantDetailResponse represents a tenant with its usage for super-admins
+type TenantDetailResponse struct {
+	TenantResponse
+	Usage TenantUsage `json:"usage"`
+}
+
+// TenantSecurityUpdateRequest represents the request structure for updating tenant security settings
+type TenantSecurityUpdateRequest struct {
+	RequireMFA *bool `json:"require_mfa" binding:"required"`
 }
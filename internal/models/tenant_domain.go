@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// TenantDomainVerificationPrefix is prepended to a custom domain to get the
+// name of its verification TXT record
+const TenantDomainVerificationPrefix = "_ae-saas-verification."
+
+// TenantDomain represents a custom domain under which a tenant is served.
+// A domain only resolves to its tenant once ownership has been verified
+// through a DNS TXT record containing the verification token.
+type TenantDomain struct {
+	ID                uint       `gorm:"primarykey" json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	TenantID          uint       `gorm:"not null;index" json:"tenant_id"`
+	Domain            string     `gorm:"not null;uniqueIndex" json:"domain"`
+	VerificationToken string     `gorm:"not null" json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+}
+
+// TableName specifies the table name for TenantDomain
+func (TenantDomain) TableName() string {
+	return "tenant_domains"
+}
+
+// TenantScoped marks TenantDomain as belonging to a tenant
+func (TenantDomain) TenantScoped() {}
+
+// IsVerified checks if ownership of the domain has been verified
+func (d *TenantDomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// TenantDomainResponse represents the API response structure for TenantDomain
+type TenantDomainResponse struct {
+	ID                uint       `json:"id"`
+	Domain            string     `json:"domain"`
+	Verified          bool       `json:"verified"`
+	VerificationName  string     `json:"verification_name"`  // DNS name of the TXT record
+	VerificationToken string     `json:"verification_token"` // value of the TXT record
+	VerifiedAt        *time.Time `json:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// ToResponse converts TenantDomain to TenantDomainResponse
+func (d *TenantDomain) ToResponse() TenantDomainResponse {
+	return TenantDomainResponse{
+		ID:                d.ID,
+		Domain:            d.Domain,
+		Verified:          d.IsVerified(),
+		VerificationName:  TenantDomainVerificationPrefix + d.Domain,
+		VerificationToken: d.VerificationToken,
+		VerifiedAt:        d.VerifiedAt,
+		CreatedAt:         d.CreatedAt,
+	}
+}
+
+// TenantDomainCreateRequest represents the request structure for adding a custom domain
+type TenantDomainCreateRequest struct {
+	Domain string `json:"domain" binding:"required"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode represents a one-time recovery code for two-factor authentication.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	CodeHash  string         `gorm:"not null" json:"-"`
+	UsedAt    *time.Time     `json:"used_at"`
+}
+
+// TableName specifies the table name for RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// TwoFactorSetupResponse represents the response structure for starting TOTP enrolment
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorDisableRequest represents the request structure for disabling two-factor authentication
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse represents newly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAChallengeResponse represents the login response for users with two-factor authentication
+type MFAChallengeResponse struct {
+	MFARequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// MFAVerifyRequest represents the request structure for completing a login with a second factor.
+// Either a TOTP code or a recovery code must be provided.
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	DeviceName     string `json:"device_name"`
+}
@@ -6,6 +6,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super-admin"
+)
+
+// IsValidRole checks if the role is one of the known user roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin, RoleSuperAdmin:
+		return true
+	}
+	return false
+}
+
 // User represents a user in the system with multi-tenant support
 type User struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
@@ -20,7 +36,12 @@ type User struct {
 	Role         string         `gorm:"not null;default:'user'" json:"role"`
 	TenantID     uint           `gorm:"not null" json:"tenant_id"`
 	// Tenant       Tenant         `gorm:"foreignKey:TenantID" json:"tenant,omitempty"` // Disabled for migration
-	Active bool `gorm:"default:true" json:"active"`
+	Active             bool       `gorm:"default:true" json:"active"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	VerificationSentAt *time.Time `json:"-"`
+	TOTPSecret         string     `json:"-"`
+	TOTPEnabledAt      *time.Time `json:"-"`
+	TOTPLastUsedStep   int64      `json:"-"`
 }
 
 // TableName specifies the table name for User
@@ -28,32 +49,46 @@ func (User) TableName() string {
 	return "users"
 }
 
+// IsEmailVerified checks if the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsTwoFactorEnabled checks if the user has completed TOTP enrolment
+func (u *User) IsTwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
 // UserResponse represents the API response structure for User
 type UserResponse struct {
-	ID        uint           `json:"id"`
-	Username  string         `json:"username"`
-	Email     string         `json:"email"`
-	FirstName string         `json:"first_name"`
-	LastName  string         `json:"last_name"`
-	Role      string         `json:"role"`
-	TenantID  uint           `json:"tenant_id"`
-	Tenant    TenantResponse `json:"tenant,omitempty"`
-	Active    bool           `json:"active"`
-	CreatedAt time.Time      `json:"created_at"`
+	ID               uint           `json:"id"`
+	Username         string         `json:"username"`
+	Email            string         `json:"email"`
+	FirstName        string         `json:"first_name"`
+	LastName         string         `json:"last_name"`
+	Role             string         `json:"role"`
+	TenantID         uint           `json:"tenant_id"`
+	Tenant           TenantResponse `json:"tenant,omitempty"`
+	Active           bool           `json:"active"`
+	EmailVerified    bool           `json:"email_verified"`
+	TwoFactorEnabled bool           `json:"two_factor_enabled"`
+	CreatedAt        time.Time      `json:"created_at"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	response := UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		TenantID:  u.TenantID,
-		Active:    u.Active,
-		CreatedAt: u.CreatedAt,
+		ID:               u.ID,
+		Username:         u.Username,
+		Email:            u.Email,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+		Role:             u.Role,
+		TenantID:         u.TenantID,
+		Active:           u.Active,
+		EmailVerified:    u.IsEmailVerified(),
+		TwoFactorEnabled: u.IsTwoFactorEnabled(),
+		CreatedAt:        u.CreatedAt,
 	}
 
 	// Temporarily commented out due to migration issues
@@ -75,6 +110,16 @@ type UserCreateRequest struct {
 	TenantID  uint   `json:"tenant_id" binding:"required"`
 }
 
+// TenantUserCreateRequest represents the request structure for an admin creating a user in their tenant
+type TenantUserCreateRequest struct {
+	Username  string `json:"username" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required,min=8"`
+	FirstName string `json:"first_name" binding:"required"`
+	LastName  string `json:"last_name" binding:"required"`
+	Role      string `json:"role"`
+}
+
 // UserUpdateRequest represents the request structure for updating a user
 type UserUpdateRequest struct {
 	Username  string `json:"username"`
@@ -87,12 +132,16 @@ type UserUpdateRequest struct {
 
 // LoginRequest represents the login request structure
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name"`
 }
 
 // LoginResponse represents the login response structure
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token                 string       `json:"token"`
+	ExpiresAt             time.Time    `json:"expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  UserResponse `json:"user"`
 }
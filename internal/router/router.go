@@ -1,10 +1,15 @@
 package router
 
 import (
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/bootstrap"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/handlers"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/middleware"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/services"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	mailer "github.com/ae-saas-basic/ae-saas-basic/services"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -15,8 +20,14 @@ import (
 func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 	router := gin.Default()
 
+	// Restrict tenant-scoped models to the request's tenant
+	if err := tenancy.Register(db); err != nil {
+		panic("failed to register tenancy callbacks: " + err.Error())
+	}
+
 	// Add middleware
 	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.ResolveTenant(db, cfg.Tenant))
 
 	// Swagger documentation endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -25,36 +36,55 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 	router.Static("/static", "./statics")
 	router.StaticFile("/favicon.ico", "./statics/images/favicon.ico")
 
+	// Initialize token service for access/refresh token pairs
+	tokenService := services.NewTokenService(db, time.Duration(cfg.JWT.RefreshExpiryHour)*time.Hour)
+
+	// Initialize login throttle for brute-force protection
+	throttleService := services.NewLoginThrottleService(db, services.LoginThrottleConfig{
+		FreeAttempts:  cfg.Auth.LoginFreeAttempts,
+		BackoffBase:   time.Duration(cfg.Auth.LoginBackoffSecond) * time.Second,
+		MaxAttempts:   cfg.Auth.LoginMaxAttempts,
+		IPMaxAttempts: cfg.Auth.LoginIPMaxAttempts,
+		Lockout:       time.Duration(cfg.Auth.LoginLockoutMinute) * time.Minute,
+	})
+
+	// Initialize entitlements for plan limits and features
+	entitlementService := services.NewEntitlementService(db)
+
+	// Initialize subscription lifecycle of tenants
+	subscriptionService := services.NewSubscriptionService(db, bootstrap.SubscriptionConfig(cfg.Billing))
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, cfg.Auth, tokenService, throttleService, entitlementService, subscriptionService)
 	healthHandler := handlers.NewHealthHandler(db)
 	planHandler := handlers.NewPlanHandler(db)
-	customerHandler := handlers.NewCustomerHandler(db)
-	contactHandler := handlers.NewContactHandler(db)
+	customerHandler := handlers.NewCustomerHandler(db, entitlementService)
+	contactHandler := handlers.NewContactHandler(db, entitlementService)
 	emailHandler := handlers.NewEmailHandler(db)
 	userSettingsHandler := handlers.NewUserSettingsHandler(db)
+	tenantHandler := handlers.NewTenantHandler(db, cfg.Tenant)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	userHandler := handlers.NewUserHandler(db, tokenService, entitlementService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
 	staticHandler := handlers.NewStaticHandler("./statics")
 
 	// Initialize PDF service and handler
-	pdfServiceConfig := &services.PDFConfig{
-		PageSize:     cfg.PDF.PageSize,
-		Orientation:  cfg.PDF.Orientation,
-		MarginTop:    cfg.PDF.MarginTop,
-		MarginRight:  cfg.PDF.MarginRight,
-		MarginBottom: cfg.PDF.MarginBottom,
-		MarginLeft:   cfg.PDF.MarginLeft,
-		Quality:      cfg.PDF.Quality,
-		EnableJS:     cfg.PDF.EnableJS,
-		LoadTimeout:  cfg.PDF.LoadTimeout,
-		Headers:      make(map[string]string),
-	}
-	pdfService := services.NewPDFService(cfg.PDF.TemplateDir, cfg.PDF.OutputDir, pdfServiceConfig)
+	pdfService := bootstrap.NewPDFService(cfg.PDF)
 	pdfHandler := handlers.NewPDFHandler(pdfService)
 
+	// Initialize invoicing of customers
+	numberingService := services.NewNumberingService(db, bootstrap.NumberingConfig(cfg.Billing))
+	numberingHandler := handlers.NewNumberingHandler(numberingService)
+	invoiceService := services.NewInvoiceService(db, numberingService, pdfService, mailer.NewEmailService(), bootstrap.InvoiceConfig(cfg))
+	invoiceHandler := handlers.NewInvoiceHandler(db, invoiceService, subscriptionService)
+
 	// Initialize fuzzy search service and handler
 	fuzzySearchService := services.NewFuzzySearchService(db, nil)
 	fuzzySearchHandler := handlers.NewFuzzySearchHandler(fuzzySearchService)
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// Public routes (no authentication required)
 	public := router.Group("/api/v1")
 	{
@@ -62,12 +92,22 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 		public.GET("/health", healthHandler.Health)
 		public.GET("/ping", healthHandler.Ping)
 
+		// Self-service signup of new organisations
+		public.POST("/signup", authHandler.Signup)
+
 		// Authentication
 		public.POST("/auth/login", authHandler.Login)
 		public.POST("/auth/register", authHandler.Register)
+		public.POST("/auth/refresh", authHandler.Refresh)
+		public.POST("/auth/forgot-password", authHandler.ForgotPassword)
+		public.POST("/auth/reset-password", authHandler.ResetPassword)
+		public.GET("/auth/verify-email", authHandler.VerifyEmail)
+		public.POST("/auth/2fa/verify", authHandler.VerifyTwoFactor)
+		public.POST("/auth/invitations/accept", authHandler.AcceptInvitation)
 
 		// Public plans (for signup pages)
 		public.GET("/plans", planHandler.GetPlans)
+		public.GET("/plans/features", planHandler.GetPlanFeatures)
 		public.GET("/plans/:id", planHandler.GetPlan)
 
 		// Static asset serving
@@ -93,30 +133,50 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 		}
 	}
 
-	// Protected routes (authentication required)
+	// Protected routes (authentication required). API keys are only accepted
+	// on groups guarded by RequireScope; user-only groups use RequireUser.
 	protected := router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(db))
+	protected.Use(middleware.AuthMiddleware(db, cfg.Auth))
 	{
 		// Auth routes for authenticated users
 		auth := protected.Group("/auth")
+		auth.Use(middleware.RequireUser())
 		{
 			auth.POST("/logout", authHandler.Logout)
 			auth.POST("/change-password", authHandler.ChangePassword)
 			auth.GET("/me", authHandler.Me)
+			auth.POST("/resend-verification", authHandler.ResendVerification)
+
+			// Session management
+			auth.GET("/sessions", authHandler.GetSessions)
+			auth.DELETE("/sessions", authHandler.RevokeOtherSessions)
+			auth.DELETE("/sessions/:id", authHandler.RevokeSession)
+
+			// Two-factor authentication
+			auth.POST("/2fa/setup", authHandler.SetupTwoFactor)
+			auth.POST("/2fa/confirm", authHandler.ConfirmTwoFactor)
+			auth.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			auth.POST("/2fa/disable", authHandler.DisableTwoFactor)
 		}
 
 		// Customer routes
 		customers := protected.Group("/customers")
+		customers.Use(middleware.RequireScope("customers"))
 		{
 			customers.GET("", customerHandler.GetCustomers)
 			customers.GET("/:id", customerHandler.GetCustomer)
 			customers.POST("", customerHandler.CreateCustomer)
 			customers.PUT("/:id", customerHandler.UpdateCustomer)
 			customers.DELETE("/:id", customerHandler.DeleteCustomer)
+			customers.GET("/:id/contacts", customerHandler.GetCustomerContacts)
+			customers.POST("/:id/contacts", customerHandler.LinkContact)
+			customers.DELETE("/:id/contacts/:contact_id", customerHandler.UnlinkContact)
+			customers.GET("/:id/balance", invoiceHandler.GetCustomerBalance)
 		}
 
 		// Contact routes
 		contacts := protected.Group("/contacts")
+		contacts.Use(middleware.RequireScope("contacts"))
 		{
 			contacts.GET("", contactHandler.GetContacts)
 			contacts.GET("/:id", contactHandler.GetContact)
@@ -127,13 +187,33 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 		// Newsletter management routes (protected)
 		newsletter := protected.Group("/contact")
+		newsletter.Use(middleware.RequireScope("contacts"))
 		{
 			newsletter.GET("/newsletter", contactHandler.GetNewsletterSubscriptions)
 			newsletter.DELETE("/newsletter/unsubscribe", contactHandler.UnsubscribeFromNewsletter)
 		}
 
+		// Invoice routes
+		invoices := protected.Group("/invoices")
+		invoices.Use(middleware.RequireScope("invoices"))
+		{
+			invoices.GET("", invoiceHandler.GetInvoices)
+			invoices.GET("/:id", invoiceHandler.GetInvoice)
+			invoices.GET("/:id/download", invoiceHandler.DownloadInvoice)
+		}
+
+		// Credit note routes
+		creditNotes := protected.Group("/credit-notes")
+		creditNotes.Use(middleware.RequireScope("invoices"))
+		{
+			creditNotes.GET("", invoiceHandler.GetCreditNotes)
+			creditNotes.GET("/:id", invoiceHandler.GetCreditNote)
+			creditNotes.GET("/:id/download", invoiceHandler.DownloadCreditNote)
+		}
+
 		// Email routes
 		emails := protected.Group("/emails")
+		emails.Use(middleware.RequireScope("emails"))
 		{
 			emails.GET("", emailHandler.GetEmails)
 			emails.GET("/:id", emailHandler.GetEmail)
@@ -143,6 +223,7 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 		// User settings routes
 		userSettings := protected.Group("/user-settings")
+		userSettings.Use(middleware.RequireUser())
 		{
 			userSettings.GET("", userSettingsHandler.GetUserSettings)
 			userSettings.PUT("", userSettingsHandler.UpdateUserSettings)
@@ -151,6 +232,7 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 		// Static file management (authenticated)
 		statics := protected.Group("/static")
+		statics.Use(middleware.RequireUser())
 		{
 			statics.GET("/assets", staticHandler.ListAssets)
 			statics.GET("/templates/:type/:template", staticHandler.ServeTemplate)
@@ -158,6 +240,7 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 		// PDF generation routes (authenticated)
 		pdf := protected.Group("/pdf")
+		pdf.Use(middleware.RequireUser(), middleware.RequireFeature(entitlementService, "pdf"))
 		{
 			// Template management
 			pdf.GET("/templates", pdfHandler.ListTemplates)
@@ -175,6 +258,7 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 		// Fuzzy search routes (authenticated)
 		search := protected.Group("/search")
+		search.Use(middleware.RequireUser())
 		{
 			// Advanced search
 			search.POST("", fuzzySearchHandler.Search)
@@ -192,17 +276,100 @@ func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 
 	// Admin routes (admin authentication required)
 	admin := router.Group("/api/v1/admin")
-	admin.Use(middleware.AuthMiddleware(db))
+	admin.Use(middleware.AuthMiddleware(db, cfg.Auth))
 	admin.Use(middleware.RequireAdmin())
 	{
-		// Admin plan management
+		// Super-admin plan management; plans are shared by all tenants
 		adminPlans := admin.Group("/plans")
+		adminPlans.Use(middleware.RequireRole("super-admin"))
 		{
 			adminPlans.POST("", planHandler.CreatePlan)
 			adminPlans.PUT("/:id", planHandler.UpdatePlan)
 			adminPlans.DELETE("/:id", planHandler.DeletePlan)
 		}
 
+		// Admin tenant settings and custom domains
+		adminTenant := admin.Group("/tenant")
+		{
+			adminTenant.PUT("/security", tenantHandler.UpdateTenantSecurity)
+			adminTenant.GET("/domains", tenantHandler.GetDomains)
+			adminTenant.POST("/domains", tenantHandler.AddDomain)
+			adminTenant.POST("/domains/:id/verify", tenantHandler.VerifyDomain)
+			adminTenant.DELETE("/domains/:id", tenantHandler.RemoveDomain)
+		}
+
+		// Admin subscription of the tenant to a plan
+		adminSubscription := admin.Group("/subscription")
+		{
+			adminSubscription.GET("", subscriptionHandler.GetSubscription)
+			adminSubscription.POST("", subscriptionHandler.StartSubscription)
+			adminSubscription.POST("/change", subscriptionHandler.ChangePlan)
+			adminSubscription.POST("/cancel", subscriptionHandler.CancelSubscription)
+			adminSubscription.POST("/resume", subscriptionHandler.ResumeSubscription)
+		}
+
+		// Admin payment, cancellation and crediting of the tenant's invoices
+		adminInvoices := admin.Group("/invoices")
+		{
+			adminInvoices.POST("/:id/pay", invoiceHandler.MarkInvoicePaid)
+			adminInvoices.POST("/:id/cancel", invoiceHandler.CancelInvoice)
+			adminInvoices.POST("/:id/credit-notes", invoiceHandler.CreateCreditNote)
+		}
+
+		// Admin numbering of the tenant's invoices and credit notes
+		adminNumbering := admin.Group("/numbering")
+		{
+			adminNumbering.GET("", numberingHandler.GetNumberingSchemes)
+			adminNumbering.PUT("/:kind", numberingHandler.UpdateNumberingScheme)
+		}
+
+		// Super-admin tenant administration
+		adminTenants := admin.Group("/tenants")
+		adminTenants.Use(middleware.RequireRole("super-admin"))
+		{
+			adminTenants.GET("", tenantHandler.GetTenants)
+			adminTenants.GET("/:id", tenantHandler.GetTenant)
+			adminTenants.POST("", tenantHandler.CreateTenant)
+			adminTenants.PUT("/:id", tenantHandler.UpdateTenant)
+			adminTenants.DELETE("/:id", tenantHandler.DeleteTenant)
+			adminTenants.POST("/:id/suspend", tenantHandler.SuspendTenant)
+			adminTenants.POST("/:id/reactivate", tenantHandler.ReactivateTenant)
+		}
+
+		// Admin user management, sessions and account unlock
+		adminUsers := admin.Group("/users")
+		{
+			adminUsers.GET("", userHandler.GetUsers)
+			adminUsers.GET("/:id", userHandler.GetUser)
+			adminUsers.POST("", userHandler.CreateUser)
+			adminUsers.PUT("/:id", userHandler.UpdateUser)
+			adminUsers.DELETE("/:id", userHandler.DeleteUser)
+			adminUsers.POST("/:id/restore", userHandler.RestoreUser)
+			adminUsers.POST("/:id/activate", userHandler.ActivateUser)
+			adminUsers.POST("/:id/deactivate", userHandler.DeactivateUser)
+			adminUsers.POST("/:id/unlock", authHandler.UnlockUser)
+			adminUsers.GET("/:id/sessions", authHandler.GetUserSessions)
+			adminUsers.DELETE("/:id/sessions", authHandler.RevokeUserSessions)
+			adminUsers.DELETE("/:id/sessions/:session_id", authHandler.RevokeUserSession)
+		}
+
+		// Admin invitations
+		adminInvitations := admin.Group("/invitations")
+		{
+			adminInvitations.GET("", authHandler.GetInvitations)
+			adminInvitations.POST("", authHandler.CreateInvitation)
+			adminInvitations.POST("/:id/resend", authHandler.ResendInvitation)
+			adminInvitations.DELETE("/:id", authHandler.RevokeInvitation)
+		}
+
+		// Admin API key management
+		adminAPIKeys := admin.Group("/api-keys")
+		{
+			adminAPIKeys.GET("", apiKeyHandler.GetAPIKeys)
+			adminAPIKeys.POST("", apiKeyHandler.CreateAPIKey)
+			adminAPIKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+
 		// Admin search management
 		adminSearch := admin.Group("/search")
 		{
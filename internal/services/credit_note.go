@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
+	"gorm.io/gorm"
+)
+
+// creditNoteTemplate is the PDF template credit notes are rendered with
+const creditNoteTemplate = "credit_note"
+
+// quantityTolerance absorbs floating point noise when comparing quantities
+const quantityTolerance = 1e-9
+
+var (
+	// ErrNothingToCredit is returned when cancelling an invoice that has been credited in full
+	ErrNothingToCredit = errors.New("invoice has already been credited in full")
+	// ErrUnknownInvoiceLine is returned when crediting a line the invoice doesn't have
+	ErrUnknownInvoiceLine = errors.New("invoice has no line at this position")
+	// ErrCreditExceedsInvoice is returned when crediting more of a line than is left of it
+	ErrCreditExceedsInvoice = errors.New("credited quantity exceeds what is left of the invoice line")
+)
+
+// Cancel issues a credit note for everything of the invoice that hasn't been
+// credited yet. An unpaid invoice becomes void.
+func (s *InvoiceService) Cancel(invoice *models.Invoice, reason string) (*models.CreditNote, error) {
+	return s.issueCreditNote(invoice, reason, nil, true)
+}
+
+// Credit issues a credit note for quantities of lines of the invoice. An
+// unpaid invoice that is credited in full becomes void.
+func (s *InvoiceService) Credit(invoice *models.Invoice, reason string, lines []models.CreditNoteItemRequest) (*models.CreditNote, error) {
+	return s.issueCreditNote(invoice, reason, lines, false)
+}
+
+// issueCreditNote credits lines of the invoice, or all that is left of it for
+// a cancellation
+func (s *InvoiceService) issueCreditNote(invoice *models.Invoice, reason string, lines []models.CreditNoteItemRequest, cancellation bool) (*models.CreditNote, error) {
+	var note models.CreditNote
+	err := tenancy.WithTenant(s.db, invoice.TenantID).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		number, err := s.numbering.Next(tx, models.NumberingKindCreditNote, now)
+		if err != nil {
+			return err
+		}
+
+		var current models.Invoice
+		if err := tx.Preload("Items").First(&current, invoice.ID).Error; err != nil {
+			return err
+		}
+		remaining, credited, err := remainingQuantities(tx, &current)
+		if err != nil {
+			return err
+		}
+
+		if cancellation {
+			lines = nil
+			for _, item := range current.Items {
+				if remaining[item.Position] > quantityTolerance {
+					lines = append(lines, models.CreditNoteItemRequest{Position: item.Position, Quantity: remaining[item.Position]})
+				}
+			}
+			if len(lines) == 0 {
+				return ErrNothingToCredit
+			}
+		}
+
+		currency := current.Total.Currency
+		note = models.CreditNote{
+			InvoiceID:    current.ID,
+			CustomerID:   current.CustomerID,
+			Number:       number,
+			Cancellation: cancellation,
+			Reason:       reason,
+			IssuedAt:     now,
+			Subtotal:     money.New(0, currency),
+			TaxRate:      current.TaxRate,
+		}
+		for _, line := range lines {
+			item := findLineItem(&current, line.Position)
+			if item == nil {
+				return ErrUnknownInvoiceLine
+			}
+			if line.Quantity > remaining[line.Position]+quantityTolerance {
+				return ErrCreditExceedsInvoice
+			}
+			remaining[line.Position] -= line.Quantity
+
+			total := item.UnitPrice.Mul(line.Quantity)
+			if remaining[line.Position] <= quantityTolerance {
+				// The last credit of a line takes what's left of its total
+				total = item.Total.Sub(credited[line.Position])
+			}
+			credited[line.Position] = credited[line.Position].Add(total)
+			note.Items = append(note.Items, models.CreditNoteLineItem{
+				InvoiceLineItemID: item.ID,
+				Position:          item.Position,
+				Description:       item.Description,
+				Quantity:          line.Quantity,
+				UnitPrice:         item.UnitPrice,
+				Total:             total,
+			})
+			note.Subtotal = note.Subtotal.Add(total)
+		}
+
+		fullyCredited := true
+		for _, quantity := range remaining {
+			if quantity > quantityTolerance {
+				fullyCredited = false
+			}
+		}
+		if fullyCredited {
+			// Credit exactly what was invoiced, whatever the rounding of earlier credit notes
+			var previous struct{ Subtotal, TaxAmount int64 }
+			if err := tx.Model(&models.CreditNote{}).Select("COALESCE(SUM(subtotal_amount), 0) AS subtotal, COALESCE(SUM(tax_amount_amount), 0) AS tax_amount").
+				Where("invoice_id = ?", current.ID).Scan(&previous).Error; err != nil {
+				return err
+			}
+			note.Subtotal = current.Subtotal.Sub(money.New(previous.Subtotal, currency))
+			note.TaxAmount = current.TaxAmount.Sub(money.New(previous.TaxAmount, currency))
+		} else {
+			note.TaxAmount = note.Subtotal.Percent(note.TaxRate)
+		}
+		note.Total = note.Subtotal.Add(note.TaxAmount)
+
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if fullyCredited && current.Status == models.InvoiceStatusOpen {
+			if err := tx.Model(&current).Update("status", models.InvoiceStatusVoid).Error; err != nil {
+				return err
+			}
+			invoice.Status = models.InvoiceStatusVoid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// CreditNotePDF returns the rendered credit note, rendering and storing it on
+// first use
+func (s *InvoiceService) CreditNotePDF(note *models.CreditNote) ([]byte, error) {
+	if pdf, ok, err := storedPDF(note.PDFPath); ok || err != nil {
+		return pdf, err
+	}
+
+	db := tenancy.WithTenant(s.db, note.TenantID)
+	if note.Items == nil {
+		if err := db.Where("credit_note_id = ?", note.ID).Order("position").Find(&note.Items).Error; err != nil {
+			return nil, err
+		}
+	}
+	var invoice models.Invoice
+	if err := db.First(&invoice, note.InvoiceID).Error; err != nil {
+		return nil, err
+	}
+	var customer models.Customer
+	if err := db.Unscoped().First(&customer, note.CustomerID).Error; err != nil {
+		return nil, err
+	}
+
+	data := note.ToInvoiceData(s.config.Company, &customer, &invoice)
+	pdf, path, err := s.render(creditNoteTemplate, fmt.Sprintf("credit_note_%d_%s.pdf", note.TenantID, note.Number), data)
+	if err != nil {
+		return nil, err
+	}
+	note.PDFPath = path
+	if err := db.Model(note).Update("pdf_path", path).Error; err != nil {
+		return nil, err
+	}
+	return pdf, nil
+}
+
+// Balances returns what the customer owes per currency, netting credit notes
+// against invoices
+func (s *InvoiceService) Balances(tenantID, customerID uint) ([]models.CustomerBalance, error) {
+	db := tenancy.WithTenant(s.db, tenantID)
+
+	type sum struct {
+		Currency money.Currency
+		Amount   int64
+	}
+	var invoiced, paid, credited []sum
+	if err := db.Model(&models.Invoice{}).Select("total_currency AS currency, SUM(total_amount) AS amount").
+		Where("customer_id = ?", customerID).Group("total_currency").Scan(&invoiced).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Invoice{}).Select("total_currency AS currency, SUM(total_amount) AS amount").
+		Where("customer_id = ? AND status = ?", customerID, models.InvoiceStatusPaid).Group("total_currency").Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.CreditNote{}).Select("total_currency AS currency, SUM(total_amount) AS amount").
+		Where("customer_id = ?", customerID).Group("total_currency").Scan(&credited).Error; err != nil {
+		return nil, err
+	}
+
+	balances := []models.CustomerBalance{}
+	balance := func(currency money.Currency) *models.CustomerBalance {
+		for i := range balances {
+			if balances[i].Invoiced.Currency == currency {
+				return &balances[i]
+			}
+		}
+		zero := money.New(0, currency)
+		balances = append(balances, models.CustomerBalance{CustomerID: customerID, Invoiced: zero, Credited: zero, Paid: zero})
+		return &balances[len(balances)-1]
+	}
+	for _, row := range invoiced {
+		balance(row.Currency).Invoiced = money.New(row.Amount, row.Currency)
+	}
+	for _, row := range paid {
+		balance(row.Currency).Paid = money.New(row.Amount, row.Currency)
+	}
+	for _, row := range credited {
+		balance(row.Currency).Credited = money.New(row.Amount, row.Currency)
+	}
+	for i := range balances {
+		balances[i].Outstanding = balances[i].Invoiced.Sub(balances[i].Credited).Sub(balances[i].Paid)
+	}
+	return balances, nil
+}
+
+// remainingQuantities returns per invoice line position the quantity that
+// hasn't been credited yet and the amount that has
+func remainingQuantities(tx *gorm.DB, invoice *models.Invoice) (map[int]float64, map[int]money.Money, error) {
+	remaining := make(map[int]float64, len(invoice.Items))
+	credited := make(map[int]money.Money, len(invoice.Items))
+	ids := make([]uint, 0, len(invoice.Items))
+	for _, item := range invoice.Items {
+		remaining[item.Position] = item.Quantity
+		ids = append(ids, item.ID)
+	}
+	if len(ids) == 0 {
+		return remaining, credited, nil
+	}
+
+	var items []models.CreditNoteLineItem
+	if err := tx.Where("invoice_line_item_id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, item := range items {
+		remaining[item.Position] -= item.Quantity
+		credited[item.Position] = credited[item.Position].Add(item.Total)
+	}
+	return remaining, credited, nil
+}
+
+// findLineItem returns the line of the invoice at a position
+func findLineItem(invoice *models.Invoice, position int) *models.InvoiceLineItem {
+	for i := range invoice.Items {
+		if invoice.Items[i].Position == position {
+			return &invoice.Items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"gorm.io/gorm"
+)
+
+// Resources limited by a plan
+const (
+	LimitUsers     = "users"     // active users, limited by Plan.MaxUsers
+	LimitCustomers = "customers" // customers, limited by Plan.MaxClients
+	LimitContacts  = "contacts"  // contacts, limited by the "contacts" quota in Plan.Features
+)
+
+var (
+	// ErrLimitReached is returned when a tenant has as many of a resource as its plan allows
+	ErrLimitReached = errors.New("plan limit reached")
+	// ErrFeatureUnavailable is returned when a tenant's plan doesn't include a feature
+	ErrFeatureUnavailable = errors.New("feature not included in plan")
+	// ErrSubscriptionEnded is returned when a tenant's subscription was canceled or has expired
+	ErrSubscriptionEnded = errors.New("subscription has ended")
+)
+
+// EntitlementError describes the plan limit or feature a request was refused
+// for, or the ended subscription that no longer entitles the tenant to its plan
+type EntitlementError struct {
+	Limit        string `json:"limit,omitempty"`
+	Feature      string `json:"feature,omitempty"`
+	Subscription string `json:"subscription,omitempty"` // state of the ended subscription
+	PlanID       uint   `json:"plan_id"`
+	Plan         string `json:"plan"`
+	Allowed      int    `json:"allowed,omitempty"`
+	Current      int64  `json:"current,omitempty"`
+}
+
+// Error implements the error interface
+func (e *EntitlementError) Error() string {
+	if e.Subscription != "" {
+		return fmt.Sprintf("subscription to plan %s is %s", e.Plan, e.Subscription)
+	}
+	if e.Feature != "" {
+		return fmt.Sprintf("plan %s doesn't include the %s feature", e.Plan, e.Feature)
+	}
+	return fmt.Sprintf("plan %s allows %d %s", e.Plan, e.Allowed, e.Limit)
+}
+
+// Unwrap lets errors.Is match ErrLimitReached, ErrFeatureUnavailable and
+// ErrSubscriptionEnded
+func (e *EntitlementError) Unwrap() error {
+	if e.Subscription != "" {
+		return ErrSubscriptionEnded
+	}
+	if e.Feature != "" {
+		return ErrFeatureUnavailable
+	}
+	return ErrLimitReached
+}
+
+// HTTPStatus returns 402 for reached limits and ended subscriptions, which an
+// upgrade or new subscription lifts, and 403 for features the plan doesn't
+// include
+func (e *EntitlementError) HTTPStatus() int {
+	if e.Subscription != "" {
+		return http.StatusPaymentRequired
+	}
+	if e.Feature != "" {
+		return http.StatusForbidden
+	}
+	return http.StatusPaymentRequired
+}
+
+// EntitlementService checks tenants against the limits and features of their
+// plan. Tenants without a plan, and limits <= 0, are not restricted.
+type EntitlementService struct {
+	db *gorm.DB
+}
+
+// NewEntitlementService creates a new entitlement service
+func NewEntitlementService(db *gorm.DB) *EntitlementService {
+	return &EntitlementService{db: db}
+}
+
+// Plan returns the tenant's current plan, or nil if it has none. Tenants
+// whose last subscription was canceled or has expired keep their plan_id,
+// but get an *EntitlementError instead of the plan.
+func (s *EntitlementService) Plan(tenantID uint) (*models.Plan, error) {
+	var tenant models.Tenant
+	if err := s.db.Select("id", "plan_id").First(&tenant, tenantID).Error; err != nil {
+		return nil, err
+	}
+
+	var plan *models.Plan
+	if tenant.PlanID != nil {
+		plan = &models.Plan{}
+		if err := s.db.First(plan, *tenant.PlanID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			plan = nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	var subscription models.Subscription
+	err := tenancy.WithTenant(s.db, tenantID).Select("id", "status", "plan_id").Order("id DESC").First(&subscription).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && subscription.IsEnded() {
+		entitlementErr := &EntitlementError{Subscription: subscription.Status, PlanID: subscription.PlanID}
+		if plan != nil {
+			entitlementErr.Plan = plan.Name
+		}
+		return nil, entitlementErr
+	}
+	return plan, nil
+}
+
+// CheckLimit returns an *EntitlementError if the tenant can't have another
+// resource of the given kind
+func (s *EntitlementService) CheckLimit(tenantID uint, limit string) error {
+	plan, err := s.Plan(tenantID)
+	if err != nil || plan == nil {
+		return err
+	}
+
+	allowed, err := planLimit(plan, limit)
+	if err != nil || allowed <= 0 {
+		return err
+	}
+
+	var count int64
+	switch limit {
+	case LimitUsers:
+		err = s.db.Model(&models.User{}).Where("tenant_id = ? AND active = ?", tenantID, true).Count(&count).Error
+	case LimitCustomers:
+		err = tenancy.WithTenant(s.db, tenantID).Model(&models.Customer{}).Count(&count).Error
+	case LimitContacts:
+		err = tenancy.WithTenant(s.db, tenantID).Model(&models.Contact{}).Count(&count).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(allowed) {
+		return &EntitlementError{
+			Limit:   limit,
+			PlanID:  plan.ID,
+			Plan:    plan.Name,
+			Allowed: allowed,
+			Current: count,
+		}
+	}
+	return nil
+}
+
+// CheckFeature returns an *EntitlementError if the tenant's plan doesn't
+// include the feature
+func (s *EntitlementService) CheckFeature(tenantID uint, feature string) error {
+	plan, err := s.Plan(tenantID)
+	if err != nil || plan == nil {
+		return err
+	}
+
+	if !plan.Features.Enabled(feature) {
+		return &EntitlementError{
+			Feature: feature,
+			PlanID:  plan.ID,
+			Plan:    plan.Name,
+		}
+	}
+	return nil
+}
+
+// planLimit returns how many resources of the kind the plan allows
+func planLimit(plan *models.Plan, limit string) (int, error) {
+	switch limit {
+	case LimitUsers:
+		return plan.MaxUsers, nil
+	case LimitCustomers:
+		return plan.MaxClients, nil
+	case LimitContacts:
+		quota, _ := plan.Features.Quota(LimitContacts)
+		return quota, nil
+	}
+	return 0, fmt.Errorf("unknown plan limit %q", limit)
+}
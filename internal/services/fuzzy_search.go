@@ -2,9 +2,12 @@ package services
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 	"gorm.io/gorm"
 )
 
@@ -167,7 +170,7 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 		OrderBy:      "first_name, last_name",
 		Permissions: PermissionConfig{
 			RequireAuth: true,
-			TenantField: "organization_id",
+			TenantField: "tenant_id",
 		},
 	})
 
@@ -185,7 +188,7 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 		OrderBy:      "name",
 		Permissions: PermissionConfig{
 			RequireAuth: true,
-			TenantField: "organization_id",
+			TenantField: "tenant_id",
 		},
 	})
 
@@ -203,7 +206,7 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 		OrderBy:      "created_at DESC",
 		Permissions: PermissionConfig{
 			RequireAuth: true,
-			TenantField: "organization_id",
+			TenantField: "tenant_id",
 		},
 	})
 
@@ -216,9 +219,9 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 			{Name: "description", Weight: 0.6, SearchType: "fulltext"},
 			{Name: "features", Weight: 0.4, SearchType: "contains"},
 		},
-		SelectFields: []string{"id", "name", "description", "price", "currency", "billing_cycle"},
+		SelectFields: []string{"id", "name", "description", "price_amount", "price_currency", "billing_cycle"},
 		WhereClause:  "active = true",
-		OrderBy:      "price ASC",
+		OrderBy:      "price_amount ASC",
 		Permissions: PermissionConfig{
 			RequireAuth: false, // Plans can be publicly searchable
 		},
@@ -238,7 +241,7 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 		OrderBy:      "sent_at DESC",
 		Permissions: PermissionConfig{
 			RequireAuth: true,
-			TenantField: "organization_id",
+			TenantField: "tenant_id",
 		},
 	})
 }
@@ -247,8 +250,8 @@ func (s *FuzzySearchService) RegisterDefaultEntities() {
 func (s *FuzzySearchService) Search(options SearchOptions) (*SearchResponse, error) {
 	startTime := time.Now()
 
-	// Validate search query
-	if len(strings.TrimSpace(options.Query)) < s.config.MinSearchLength {
+	// Validate search query; without a tenant there is nothing to search
+	if options.TenantID == nil || len(strings.TrimSpace(options.Query)) < s.config.MinSearchLength {
 		return &SearchResponse{
 			Query:         options.Query,
 			Total:         0,
@@ -276,6 +279,11 @@ func (s *FuzzySearchService) Search(options SearchOptions) (*SearchResponse, err
 	// Search each entity type
 	for _, entityType := range entityTypes {
 		if config, exists := s.entities[entityType]; exists {
+			// Anonymous searches only see public entities
+			if config.Permissions.RequireAuth && options.UserID == nil {
+				continue
+			}
+
 			results, err := s.searchEntity(entityType, config, options)
 			if err != nil {
 				continue // Skip entities with errors, don't fail entire search
@@ -319,7 +327,12 @@ func (s *FuzzySearchService) Search(options SearchOptions) (*SearchResponse, err
 
 // searchEntity searches within a specific entity type
 func (s *FuzzySearchService) searchEntity(entityType string, config EntityConfig, options SearchOptions) ([]SearchResult, error) {
-	query := s.db.Table(config.TableName)
+	// Searches never span tenants. Table queries bypass the tenancy
+	// callbacks, but row-level security still needs to know the tenant
+	if options.TenantID == nil {
+		return nil, tenancy.ErrMissingTenant
+	}
+	query := tenancy.WithTenant(s.db, *options.TenantID).Table(config.TableName)
 
 	// Apply joins
 	for _, join := range config.JoinTables {
@@ -338,8 +351,8 @@ func (s *FuzzySearchService) searchEntity(entityType string, config EntityConfig
 		query = query.Where(config.WhereClause)
 	}
 
-	// Apply organization filtering
-	if options.TenantID != nil && config.Permissions.TenantField != "" {
+	// Apply tenant filtering
+	if config.Permissions.TenantField != "" {
 		query = query.Where(config.Permissions.TenantField+" = ?", *options.TenantID)
 	}
 
@@ -550,9 +563,9 @@ func (s *FuzzySearchService) buildTitleDescription(entityType string, row map[st
 
 	case "plans":
 		title := fmt.Sprintf("%v", getField(row, "name"))
-		price := getField(row, "price")
-		currency := getField(row, "currency")
-		description := fmt.Sprintf("Price: %v %v", price, currency)
+		amount, _ := strconv.ParseInt(getField(row, "price_amount"), 10, 64)
+		price := money.New(amount, money.Currency(getField(row, "price_currency")))
+		description := fmt.Sprintf("Price: %s", price)
 		return title, description
 
 	case "emails":
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/vat"
+	"gorm.io/gorm"
+)
+
+// invoiceTemplate is the PDF template invoices are rendered with
+const invoiceTemplate = "invoice"
+
+var (
+	// ErrInvoiceNotOpen is returned when paying an invoice that is paid or void
+	ErrInvoiceNotOpen = errors.New("only open invoices can be marked as paid")
+	// ErrPeriodInvoiced is returned when issuing an invoice for a billing
+	// period the customer has already been invoiced for
+	ErrPeriodInvoiced = errors.New("the billing period has already been invoiced")
+)
+
+// InvoiceMailer emails invoices to customers. The EmailService of the public
+// services package implements it.
+type InvoiceMailer interface {
+	SendInvoiceEmail(to, recipientName, invoiceNumber, amount, dueDate string, pdf []byte) error
+}
+
+// InvoiceConfig holds the invoicing settings
+type InvoiceConfig struct {
+	VAT          vat.Engine         // decides the VAT charged to each customer
+	Locale       string             // locale amounts are formatted in on invoices and emails
+	PaymentTerm  time.Duration      // time between issuing an invoice and its due date
+	BillingStart time.Time          // periods ending before it aren't invoiced; zero starts with the current period
+	Company      models.CompanyInfo // issuer shown on invoices
+}
+
+// InvoiceService issues invoices to customers for every billing period of
+// their plan, renders them with the PDF service and emails them
+type InvoiceService struct {
+	db         *gorm.DB
+	numbering  *NumberingService
+	pdfService *PDFService
+	mailer     InvoiceMailer
+	config     InvoiceConfig
+}
+
+// NewInvoiceService creates a new invoice service
+func NewInvoiceService(db *gorm.DB, numbering *NumberingService, pdfService *PDFService, mailer InvoiceMailer, config InvoiceConfig) *InvoiceService {
+	if config.PaymentTerm <= 0 {
+		config.PaymentTerm = 14 * 24 * time.Hour
+	}
+	return &InvoiceService{
+		db:         db,
+		numbering:  numbering,
+		pdfService: pdfService,
+		mailer:     mailer,
+		config:     config,
+	}
+}
+
+// GenerateDue issues an invoice for every billing period that started by now
+// and hasn't been invoiced, and returns how many were issued. Periods are
+// billed in advance, following on from the customer's last invoice; the
+// first invoice is for the period containing the billing start, or now, with
+// periods counted from when the customer was created. Customers that are
+// inactive or on a free plan aren't invoiced. Customers that fail are logged
+// and retried on the next run, as are undelivered invoices.
+func (s *InvoiceService) GenerateDue(now time.Time) (int, error) {
+	db := tenancy.AllTenants(s.db)
+
+	var customers []models.Customer
+	if err := db.Where("active = ? AND status = ?", true, "active").Order("id").Find(&customers).Error; err != nil {
+		return 0, err
+	}
+
+	issued := 0
+	for i := range customers {
+		n, err := s.generateForCustomer(db, &customers[i], now)
+		issued += n
+		if err != nil {
+			log.Printf("Failed to invoice customer %d of tenant %d: %v", customers[i].ID, customers[i].TenantID, err)
+		}
+	}
+
+	if err := s.DeliverPending(); err != nil {
+		return issued, err
+	}
+	return issued, nil
+}
+
+// generateForCustomer issues the customer's invoices for the billing periods
+// that started by now and returns how many were issued
+func (s *InvoiceService) generateForCustomer(db *gorm.DB, customer *models.Customer, now time.Time) (int, error) {
+	var plan models.Plan
+	if err := db.Unscoped().First(&plan, customer.PlanID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if plan.Price.Amount <= 0 {
+		return 0, nil
+	}
+
+	start, err := s.nextPeriodStart(db, customer, plan.InvoicePeriod, now)
+	if err != nil {
+		return 0, err
+	}
+	issued := 0
+	for !start.After(now) {
+		invoice, err := s.Issue(customer, &plan, start, now)
+		if errors.Is(err, ErrPeriodInvoiced) {
+			// A parallel run got there first and carries on from here
+			return issued, nil
+		}
+		if err != nil {
+			return issued, err
+		}
+		issued++
+		start = invoice.PeriodEnd
+	}
+	return issued, nil
+}
+
+// Issue creates the invoice of a customer for the billing period of the plan
+// starting at periodStart, or returns ErrPeriodInvoiced if there already is
+// one. The VAT is decided by the customer's country and VAT ID at the time
+// of issue.
+func (s *InvoiceService) Issue(customer *models.Customer, plan *models.Plan, periodStart, issuedAt time.Time) (*models.Invoice, error) {
+	periodEnd := periodEnd(periodStart, plan.InvoicePeriod)
+	tax := s.config.VAT.Decide(vat.Buyer{
+		Country:  customer.Country,
+		VATID:    customer.VAT,
+		Business: customer.VAT != "" || customer.TaxID != "",
+	})
+
+	item := models.InvoiceLineItem{
+		TenantID:    customer.TenantID,
+		Position:    1,
+		Description: fmt.Sprintf("%s plan, %s to %s", plan.Name, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")),
+		Quantity:    1,
+		UnitPrice:   plan.Price,
+		Total:       plan.Price,
+	}
+	invoice := models.Invoice{
+		TenantID:      customer.TenantID,
+		CustomerID:    customer.ID,
+		PlanID:        plan.ID,
+		Status:        models.InvoiceStatusOpen,
+		IssuedAt:      issuedAt,
+		DueAt:         issuedAt.Add(s.config.PaymentTerm),
+		PeriodStart:   periodStart,
+		PeriodEnd:     periodEnd,
+		Subtotal:      item.Total,
+		TaxRate:       tax.Rate,
+		TaxAmount:     item.Total.Percent(tax.Rate),
+		TaxCountry:    tax.Country,
+		ReverseCharge: tax.ReverseCharge,
+		CustomerVATID: tax.VATID,
+		TaxNote:       tax.Note,
+		Items:         []models.InvoiceLineItem{item},
+	}
+	invoice.Total = invoice.Subtotal.Add(invoice.TaxAmount)
+
+	err := tenancy.WithTenant(s.db, customer.TenantID).Transaction(func(tx *gorm.DB) error {
+		number, err := s.numbering.Next(tx, models.NumberingKindInvoice, issuedAt)
+		if err != nil {
+			return err
+		}
+
+		// The locked number sequence serializes issuing within the tenant,
+		// so parallel runs see each other's invoices here
+		var existing int64
+		if err := tx.Model(&models.Invoice{}).
+			Where("customer_id = ? AND period_start = ?", customer.ID, periodStart).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrPeriodInvoiced
+		}
+
+		invoice.Number = number
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// DeliverPending renders and emails open invoices that haven't been sent.
+// Failures are logged and retried on the next call.
+func (s *InvoiceService) DeliverPending() error {
+	var invoices []models.Invoice
+	err := tenancy.AllTenants(s.db).Preload("Items").
+		Where("status = ? AND sent_at IS NULL", models.InvoiceStatusOpen).
+		Order("id").Find(&invoices).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range invoices {
+		if err := s.Deliver(&invoices[i]); err != nil {
+			log.Printf("Failed to deliver invoice %s of tenant %d: %v", invoices[i].Number, invoices[i].TenantID, err)
+		}
+	}
+	return nil
+}
+
+// Deliver emails the invoice PDF to the customer and records when it was sent
+func (s *InvoiceService) Deliver(invoice *models.Invoice) error {
+	db := tenancy.WithTenant(s.db, invoice.TenantID)
+
+	var customer models.Customer
+	if err := db.Unscoped().First(&customer, invoice.CustomerID).Error; err != nil {
+		return err
+	}
+
+	pdf, err := s.PDF(invoice)
+	if err != nil {
+		return err
+	}
+
+	amount := invoice.Total.Format(s.config.Locale)
+	if err := s.mailer.SendInvoiceEmail(customer.Email, customer.Name, invoice.Number, amount, invoice.DueAt.Format("2006-01-02"), pdf); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	invoice.SentAt = &now
+	return db.Model(invoice).Update("sent_at", now).Error
+}
+
+// MarkPaid records that an open invoice has been paid, which settles it in
+// the customer's balance
+func (s *InvoiceService) MarkPaid(invoice *models.Invoice, paidAt time.Time) error {
+	result := tenancy.WithTenant(s.db, invoice.TenantID).Model(invoice).
+		Where("status = ?", models.InvoiceStatusOpen).
+		Updates(map[string]interface{}{"status": models.InvoiceStatusPaid, "paid_at": paidAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvoiceNotOpen
+	}
+	invoice.Status = models.InvoiceStatusPaid
+	invoice.PaidAt = &paidAt
+	return nil
+}
+
+// PDF returns the rendered invoice, rendering and storing it on first use
+func (s *InvoiceService) PDF(invoice *models.Invoice) ([]byte, error) {
+	if pdf, ok, err := storedPDF(invoice.PDFPath); ok || err != nil {
+		return pdf, err
+	}
+
+	db := tenancy.WithTenant(s.db, invoice.TenantID)
+	if invoice.Items == nil {
+		if err := db.Where("invoice_id = ?", invoice.ID).Order("position").Find(&invoice.Items).Error; err != nil {
+			return nil, err
+		}
+	}
+	var customer models.Customer
+	if err := db.Unscoped().First(&customer, invoice.CustomerID).Error; err != nil {
+		return nil, err
+	}
+
+	data := invoice.ToInvoiceData(s.config.Company, &customer)
+	pdf, path, err := s.render(invoiceTemplate, fmt.Sprintf("invoice_%d_%s.pdf", invoice.TenantID, invoice.Number), data)
+	if err != nil {
+		return nil, err
+	}
+	invoice.PDFPath = path
+	if err := db.Model(invoice).Update("pdf_path", path).Error; err != nil {
+		return nil, err
+	}
+	return pdf, nil
+}
+
+// Run calls GenerateDue every interval until ctx is done
+func (s *InvoiceService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if issued, err := s.GenerateDue(time.Now()); err != nil {
+			log.Printf("Failed to generate due invoices: %v", err)
+		} else if issued > 0 {
+			log.Printf("Issued %d invoices", issued)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextPeriodStart returns the start of the customer's first billing period
+// that hasn't been invoiced. Customers without invoices start with the period
+// containing the billing start, so earlier periods are never invoiced.
+func (s *InvoiceService) nextPeriodStart(db *gorm.DB, customer *models.Customer, invoicePeriod string, now time.Time) (time.Time, error) {
+	var last models.Invoice
+	err := db.Where("customer_id = ?", customer.ID).Order("period_end DESC").First(&last).Error
+	if err == nil {
+		return last.PeriodEnd, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, err
+	}
+
+	billingStart := s.config.BillingStart
+	if billingStart.IsZero() {
+		billingStart = now
+	}
+	start := customer.CreatedAt
+	for {
+		end := periodEnd(start, invoicePeriod)
+		if end.After(billingStart) {
+			return start, nil
+		}
+		start = end
+	}
+}
+
+// render renders document data with a PDF template and stores the PDF under
+// filename, returning it and its path
+func (s *InvoiceService) render(template, filename string, data models.InvoiceData) ([]byte, string, error) {
+	if problems := models.ValidateInvoiceData(data); len(problems) > 0 {
+		return nil, "", fmt.Errorf("invalid invoice data: %v", problems)
+	}
+	data.Locale = s.config.Locale
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pdf, err := s.pdfService.GeneratePDF(ctx, PDFTemplateData{
+		Template: template,
+		Data:     data.TemplateData(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	path, err := s.pdfService.SavePDF(pdf, filename)
+	if err != nil {
+		return nil, "", err
+	}
+	return pdf, path, nil
+}
+
+// storedPDF reads a rendered PDF. It reports false if there is none, so it
+// needs to be rendered.
+func storedPDF(path string) ([]byte, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	pdf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return pdf, true, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownNumberingKind is returned for document kinds that aren't numbered
+	ErrUnknownNumberingKind = errors.New("unknown numbering kind")
+
+	// ErrNumberingStarted is returned when the prefix or yearly reset of a
+	// scheme would change after numbers have been handed out
+	ErrNumberingStarted = errors.New("prefix and yearly reset can't be changed once numbers have been handed out")
+)
+
+// NumberingConfig holds the numbering schemes tenants start out with
+type NumberingConfig struct {
+	Invoice    models.NumberingScheme
+	CreditNote models.NumberingScheme
+}
+
+// NumberingService hands out consecutive document numbers from per-tenant
+// sequences, one per kind of document
+type NumberingService struct {
+	db     *gorm.DB
+	config NumberingConfig
+}
+
+// NewNumberingService creates a new numbering service
+func NewNumberingService(db *gorm.DB, config NumberingConfig) *NumberingService {
+	return &NumberingService{
+		db:     db,
+		config: config,
+	}
+}
+
+// Next returns the next number of the sequence of documents of a kind, for a
+// document issued at date. tx must be the transaction that stores the
+// document and carry its tenant: the sequence row stays locked until the
+// transaction ends, and a rollback returns the number, so numbers are
+// consecutive without gaps even when documents are issued in parallel.
+//
+// Every statement here writes before it reads, so on SQLite the transaction
+// takes the write lock first and parallel transactions wait for each other
+// (given a busy timeout) instead of failing with a lock upgrade deadlock.
+func (s *NumberingService) Next(tx *gorm.DB, kind string, date time.Time) (string, error) {
+	scheme, err := s.lockScheme(tx, kind)
+	if err != nil {
+		return "", err
+	}
+	year := scheme.SequenceYear(date)
+
+	sequence := models.NumberSequence{Kind: kind, Year: year}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+		return "", err
+	}
+	result := tx.Model(&models.NumberSequence{}).
+		Where("kind = ? AND year = ?", kind, year).
+		UpdateColumn("last_value", gorm.Expr("last_value + 1"))
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected != 1 {
+		return "", fmt.Errorf("number sequence %s/%d not found", kind, year)
+	}
+	if err := tx.Where("kind = ? AND year = ?", kind, year).First(&sequence).Error; err != nil {
+		return "", err
+	}
+
+	return scheme.Format(year, sequence.LastValue), nil
+}
+
+// Schemes returns the tenant's numbering schemes with the number the next
+// document of each kind would get at date
+func (s *NumberingService) Schemes(tenantID uint, date time.Time) ([]models.NumberingSchemeResponse, error) {
+	db := tenancy.WithTenant(s.db, tenantID)
+
+	responses := make([]models.NumberingSchemeResponse, 0, len(models.NumberingKinds))
+	for _, kind := range models.NumberingKinds {
+		response, err := s.schemeResponse(db, kind, date)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
+	}
+	return responses, nil
+}
+
+// UpdateScheme changes how the tenant's documents of a kind are numbered.
+// The padding can always be changed. The prefix and yearly reset can only be
+// changed until the first number is handed out, as the new format could
+// repeat a number already handed out, e.g. "2026-0001" from prefix "" with
+// yearly reset and from prefix "2026-" without.
+func (s *NumberingService) UpdateScheme(tenantID uint, kind string, req models.NumberingSchemeUpdateRequest) (*models.NumberingSchemeResponse, error) {
+	var response *models.NumberingSchemeResponse
+	err := tenancy.WithTenant(s.db, tenantID).Transaction(func(tx *gorm.DB) error {
+		scheme, err := s.lockScheme(tx, kind)
+		if err != nil {
+			return err
+		}
+
+		if (req.Prefix != nil && *req.Prefix != scheme.Prefix) || (req.YearReset != nil && *req.YearReset != scheme.YearReset) {
+			var started int64
+			if err := tx.Model(&models.NumberSequence{}).Where("kind = ? AND last_value > 0", kind).Count(&started).Error; err != nil {
+				return err
+			}
+			if started > 0 {
+				return ErrNumberingStarted
+			}
+		}
+
+		updates := map[string]interface{}{}
+		if req.Prefix != nil {
+			updates["prefix"] = *req.Prefix
+		}
+		if req.YearReset != nil {
+			updates["year_reset"] = *req.YearReset
+		}
+		if req.Padding != nil {
+			updates["padding"] = *req.Padding
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(scheme).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		response, err = s.schemeResponse(tx, kind, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// lockScheme returns the scheme of the tenant's documents of a kind, storing
+// the default scheme on first use. Inserting first takes the write lock on
+// SQLite, so the scheme can't change while the transaction hands out numbers.
+func (s *NumberingService) lockScheme(tx *gorm.DB, kind string) (*models.NumberingScheme, error) {
+	defaults, err := s.defaultScheme(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaults).Error; err != nil {
+		return nil, err
+	}
+	var scheme models.NumberingScheme
+	if err := tx.Where("kind = ?", kind).First(&scheme).Error; err != nil {
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// schemeResponse returns the current scheme of documents of a kind without
+// storing the default
+func (s *NumberingService) schemeResponse(db *gorm.DB, kind string, date time.Time) (*models.NumberingSchemeResponse, error) {
+	var scheme models.NumberingScheme
+	err := db.Where("kind = ?", kind).First(&scheme).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		scheme, err = s.defaultScheme(kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	year := scheme.SequenceYear(date)
+	var sequence models.NumberSequence
+	if err := db.Where("kind = ? AND year = ?", kind, year).Limit(1).Find(&sequence).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.NumberingSchemeResponse{
+		Kind:       kind,
+		Prefix:     scheme.Prefix,
+		YearReset:  scheme.YearReset,
+		Padding:    scheme.Padding,
+		NextNumber: scheme.Format(year, sequence.LastValue+1),
+	}, nil
+}
+
+// defaultScheme returns the configured scheme of documents of a kind
+func (s *NumberingService) defaultScheme(kind string) (models.NumberingScheme, error) {
+	var scheme models.NumberingScheme
+	switch kind {
+	case models.NumberingKindInvoice:
+		scheme = s.config.Invoice
+	case models.NumberingKindCreditNote:
+		scheme = s.config.CreditNote
+	default:
+		return scheme, ErrUnknownNumberingKind
+	}
+	scheme.Kind = kind
+	if scheme.Padding <= 0 {
+		scheme.Padding = 1
+	}
+	return scheme, nil
+}
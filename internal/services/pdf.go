@@ -12,6 +12,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/money"
 )
 
 // PDFConfig holds configuration for PDF generation
@@ -316,8 +318,35 @@ func (s *PDFService) enrichTemplateData(data map[string]interface{}) map[string]
 	data["FormatDate"] = func(t time.Time, layout string) string {
 		return t.Format(layout)
 	}
-	data["FormatCurrency"] = func(amount float64, currency string) string {
-		return fmt.Sprintf("%.2f %s", amount, currency)
+	// FormatCurrency formats Money in the document's locale; plain numbers
+	// are taken as major units of the given currency
+	locale, _ := data["Locale"].(string)
+	if locale == "" {
+		locale = money.DefaultLocale
+	}
+	data["FormatCurrency"] = func(amount interface{}, currency ...string) string {
+		switch value := amount.(type) {
+		case money.Money:
+			return value.Format(locale)
+		case *money.Money:
+			return value.Format(locale)
+		}
+		code := money.DefaultCurrency
+		if len(currency) > 0 && currency[0] != "" {
+			code = money.Currency(strings.ToUpper(currency[0]))
+		}
+		var major float64
+		switch value := amount.(type) {
+		case float64:
+			major = value
+		case float32:
+			major = float64(value)
+		case int:
+			major = float64(value)
+		case int64:
+			major = float64(value)
+		}
+		return money.FromFloat(major, code).Format(locale)
 	}
 	data["ToUpper"] = strings.ToUpper
 	data["ToLower"] = strings.ToLower
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/internal/tenancy"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoSubscription is returned when a tenant has no current subscription
+	ErrNoSubscription = errors.New("tenant has no current subscription")
+	// ErrSubscriptionExists is returned when subscribing a tenant that already has a current subscription
+	ErrSubscriptionExists = errors.New("tenant already has a current subscription")
+	// ErrSubscriptionState is returned when the subscription's state doesn't allow the change
+	ErrSubscriptionState = errors.New("subscription state doesn't allow this change")
+	// ErrPlanUnavailable is returned when the plan doesn't exist or is inactive
+	ErrPlanUnavailable = errors.New("plan not found or inactive")
+)
+
+// SubscriptionConfig holds the subscription lifecycle settings
+type SubscriptionConfig struct {
+	TrialPeriod time.Duration // trial length of new subscriptions; zero starts them active
+	GracePeriod time.Duration // how long a past due subscription is kept before it expires
+}
+
+// SubscriptionService manages the subscription of tenants to plans and moves
+// subscriptions through trialing, active, past_due, canceled and expired.
+// The plan of a tenant's current subscription is mirrored to Tenant.PlanID,
+// which the entitlement service reads.
+type SubscriptionService struct {
+	db     *gorm.DB
+	config SubscriptionConfig
+}
+
+// NewSubscriptionService creates a new subscription service
+func NewSubscriptionService(db *gorm.DB, config SubscriptionConfig) *SubscriptionService {
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = 7 * 24 * time.Hour
+	}
+	return &SubscriptionService{
+		db:     db,
+		config: config,
+	}
+}
+
+// Current returns the tenant's current subscription
+func (s *SubscriptionService) Current(tenantID uint) (*models.Subscription, error) {
+	return s.current(s.db, tenantID)
+}
+
+// Start subscribes a tenant without a current subscription to a plan
+func (s *SubscriptionService) Start(tenantID, planID uint) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.current(tx, tenantID); err == nil {
+			return ErrSubscriptionExists
+		} else if !errors.Is(err, ErrNoSubscription) {
+			return err
+		}
+
+		plan, err := activePlan(tx, planID)
+		if err != nil {
+			return err
+		}
+		subscription, err = s.Subscribe(tx, tenantID, plan)
+		return err
+	})
+	return subscription, err
+}
+
+// Subscribe creates a subscription to the plan, trialing if a trial period is
+// configured, and makes the plan the tenant's plan. It runs on tx so signup
+// can provision the tenant and its subscription in one transaction.
+func (s *SubscriptionService) Subscribe(tx *gorm.DB, tenantID uint, plan *models.Plan) (*models.Subscription, error) {
+	now := time.Now()
+	subscription := models.Subscription{
+		TenantID:           tenantID,
+		PlanID:             plan.ID,
+		Status:             models.SubscriptionStatusActive,
+		StartedAt:          now,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   periodEnd(now, plan.InvoicePeriod),
+	}
+	if s.config.TrialPeriod > 0 {
+		trialEnd := now.Add(s.config.TrialPeriod)
+		subscription.Status = models.SubscriptionStatusTrialing
+		subscription.TrialEndsAt = &trialEnd
+		subscription.CurrentPeriodEnd = trialEnd
+	}
+
+	if err := tenancy.WithTenant(tx, tenantID).Create(&subscription).Error; err != nil {
+		return nil, err
+	}
+	if err := setTenantPlan(tx, tenantID, plan.ID); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ChangePlan upgrades or downgrades the tenant's subscription, either now or
+// when the current period ends. Changing back to the current plan drops a
+// scheduled change.
+func (s *SubscriptionService) ChangePlan(tenantID, planID uint, atPeriodEnd bool) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		subscription, err = s.current(tx, tenantID)
+		if err != nil {
+			return err
+		}
+		if subscription.Status == models.SubscriptionStatusPastDue {
+			return ErrSubscriptionState
+		}
+		if _, err := activePlan(tx, planID); err != nil {
+			return err
+		}
+
+		switch {
+		case planID == subscription.PlanID:
+			subscription.PendingPlanID = nil
+		case atPeriodEnd:
+			subscription.PendingPlanID = &planID
+		default:
+			subscription.PlanID = planID
+			subscription.PendingPlanID = nil
+			if err := setTenantPlan(tx, tenantID, planID); err != nil {
+				return err
+			}
+		}
+		return tenancy.WithTenant(tx, tenantID).Save(subscription).Error
+	})
+	return subscription, err
+}
+
+// Cancel ends the tenant's subscription now, or schedules it to end with the
+// current period
+func (s *SubscriptionService) Cancel(tenantID uint, atPeriodEnd bool) (*models.Subscription, error) {
+	subscription, err := s.current(s.db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	subscription.CanceledAt = &now
+	if atPeriodEnd {
+		subscription.CancelAtPeriodEnd = true
+	} else {
+		endSubscription(subscription, models.SubscriptionStatusCanceled, now)
+	}
+
+	if err := tenancy.WithTenant(s.db, tenantID).Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Resume keeps a subscription whose cancellation at period end is scheduled
+func (s *SubscriptionService) Resume(tenantID uint) (*models.Subscription, error) {
+	subscription, err := s.current(s.db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.CancelAtPeriodEnd {
+		return nil, ErrSubscriptionState
+	}
+
+	subscription.CancelAtPeriodEnd = false
+	subscription.CanceledAt = nil
+	if err := tenancy.WithTenant(s.db, tenantID).Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// MarkPastDue flags an active subscription whose payment is overdue. It
+// expires unless MarkPaid is called within the grace period. For tenants
+// invoiced through a billing customer, SyncPayments does both.
+func (s *SubscriptionService) MarkPastDue(tenantID uint) (*models.Subscription, error) {
+	return s.markPastDue(tenantID, time.Now())
+}
+
+// markPastDue flags an active subscription as past due since the given time
+func (s *SubscriptionService) markPastDue(tenantID uint, since time.Time) (*models.Subscription, error) {
+	subscription, err := s.current(s.db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != models.SubscriptionStatusActive {
+		return nil, ErrSubscriptionState
+	}
+
+	subscription.Status = models.SubscriptionStatusPastDue
+	subscription.PastDueSince = &since
+	if err := tenancy.WithTenant(s.db, tenantID).Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// MarkPaid reactivates a past due subscription once its payment arrived
+func (s *SubscriptionService) MarkPaid(tenantID uint) (*models.Subscription, error) {
+	subscription, err := s.current(s.db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != models.SubscriptionStatusPastDue {
+		return nil, ErrSubscriptionState
+	}
+
+	subscription.Status = models.SubscriptionStatusActive
+	subscription.PastDueSince = nil
+	if err := tenancy.WithTenant(s.db, tenantID).Save(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// SyncPayments moves the subscriptions of tenants that are invoiced through a
+// billing customer along with that customer's invoices: active subscriptions
+// become past due while an open invoice is overdue, and past due ones become
+// active again once none is. With customerIDs, only the tenants billed
+// through them are synced. It returns how many subscriptions changed.
+func (s *SubscriptionService) SyncPayments(now time.Time, customerIDs ...uint) (int, error) {
+	query := s.db.Select("id", "billing_customer_id").Where("billing_customer_id IS NOT NULL")
+	if len(customerIDs) > 0 {
+		query = query.Where("billing_customer_id IN ?", customerIDs)
+	}
+	var tenants []models.Tenant
+	if err := query.Order("id").Find(&tenants).Error; err != nil {
+		return 0, err
+	}
+	if len(tenants) == 0 {
+		return 0, nil
+	}
+
+	billed := make([]uint, 0, len(tenants))
+	for _, tenant := range tenants {
+		billed = append(billed, *tenant.BillingCustomerID)
+	}
+	var overdue []uint
+	err := tenancy.AllTenants(s.db).Model(&models.Invoice{}).
+		Where("customer_id IN ? AND status = ? AND due_at <= ?", billed, models.InvoiceStatusOpen, now).
+		Distinct("customer_id").Pluck("customer_id", &overdue).Error
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, tenant := range tenants {
+		subscription, err := s.current(s.db, tenant.ID)
+		if errors.Is(err, ErrNoSubscription) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+
+		isOverdue := slices.Contains(overdue, *tenant.BillingCustomerID)
+		switch {
+		case isOverdue && subscription.Status == models.SubscriptionStatusActive:
+			_, err = s.markPastDue(tenant.ID, now)
+		case !isOverdue && subscription.Status == models.SubscriptionStatusPastDue:
+			_, err = s.MarkPaid(tenant.ID)
+		default:
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// ProcessDue moves every subscription whose trial, period or grace period
+// ended by now to its next state and returns how many were changed:
+// subscriptions follow the payment of their invoices (see SyncPayments),
+// trials become active, periods renew or end if canceled at period end, and
+// past due subscriptions expire after the grace period.
+func (s *SubscriptionService) ProcessDue(now time.Time) (int, error) {
+	synced, err := s.SyncPayments(now)
+	if err != nil {
+		return 0, err
+	}
+
+	db := tenancy.AllTenants(s.db)
+
+	var due []models.Subscription
+	err = db.Where("(status IN ? AND current_period_end <= ?) OR (status = ? AND past_due_since <= ?)",
+		[]string{models.SubscriptionStatusTrialing, models.SubscriptionStatusActive}, now,
+		models.SubscriptionStatusPastDue, now.Add(-s.config.GracePeriod)).
+		Order("id").Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	processed := synced
+	for i := range due {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return s.advance(tx, &due[i], now)
+		}); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// Run calls ProcessDue every interval until ctx is done
+func (s *SubscriptionService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if processed, err := s.ProcessDue(time.Now()); err != nil {
+			log.Printf("Failed to process due subscriptions: %v", err)
+		} else if processed > 0 {
+			log.Printf("Processed %d due subscriptions", processed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// advance moves a due subscription to its next state
+func (s *SubscriptionService) advance(tx *gorm.DB, subscription *models.Subscription, now time.Time) error {
+	switch {
+	case subscription.Status == models.SubscriptionStatusPastDue:
+		endSubscription(subscription, models.SubscriptionStatusExpired, now)
+	case subscription.CancelAtPeriodEnd:
+		endSubscription(subscription, models.SubscriptionStatusCanceled, subscription.CurrentPeriodEnd)
+	default:
+		if subscription.PendingPlanID != nil {
+			if err := setTenantPlan(tx, subscription.TenantID, *subscription.PendingPlanID); err != nil {
+				return err
+			}
+			subscription.PlanID = *subscription.PendingPlanID
+			subscription.PendingPlanID = nil
+		}
+
+		var plan models.Plan
+		if err := tx.Unscoped().First(&plan, subscription.PlanID).Error; err != nil {
+			return err
+		}
+
+		// Catch up on periods missed while the scheduler wasn't running
+		subscription.Status = models.SubscriptionStatusActive
+		for !subscription.CurrentPeriodEnd.After(now) {
+			subscription.CurrentPeriodStart = subscription.CurrentPeriodEnd
+			subscription.CurrentPeriodEnd = periodEnd(subscription.CurrentPeriodStart, plan.InvoicePeriod)
+		}
+	}
+	return tx.Save(subscription).Error
+}
+
+// current loads the tenant's subscription that hasn't ended yet
+func (s *SubscriptionService) current(db *gorm.DB, tenantID uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := tenancy.WithTenant(db, tenantID).
+		Where("status NOT IN ?", []string{models.SubscriptionStatusCanceled, models.SubscriptionStatusExpired}).
+		Order("id DESC").First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoSubscription
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// endSubscription marks the subscription as ended with the given state
+func endSubscription(subscription *models.Subscription, status string, at time.Time) {
+	subscription.Status = status
+	subscription.EndedAt = &at
+	subscription.CancelAtPeriodEnd = false
+	subscription.PendingPlanID = nil
+}
+
+// activePlan loads a plan that tenants can subscribe to
+func activePlan(db *gorm.DB, planID uint) (*models.Plan, error) {
+	var plan models.Plan
+	err := db.Where("id = ? AND active = ?", planID, true).First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPlanUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// setTenantPlan makes the plan the one whose limits apply to the tenant
+func setTenantPlan(db *gorm.DB, tenantID, planID uint) error {
+	return db.Model(&models.Tenant{}).Where("id = ?", tenantID).Update("plan_id", planID).Error
+}
+
+// periodEnd returns the end of a billing period of the plan's invoice period
+// starting at start. Unknown invoice periods are billed monthly.
+func periodEnd(start time.Time, invoicePeriod string) time.Time {
+	switch invoicePeriod {
+	case "yearly", "annually":
+		return start.AddDate(1, 0, 0)
+	case "quarterly":
+		return start.AddDate(0, 3, 0)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
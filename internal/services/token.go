@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/ae-saas-basic/ae-saas-basic/pkg/auth"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// ClientInfo describes the device a token pair is issued to
+type ClientInfo struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+}
+
+// TokenPair represents an access token together with its refresh token
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// TokenService issues access/refresh token pairs and rotates refresh tokens
+type TokenService struct {
+	db         *gorm.DB
+	refreshTTL time.Duration
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(db *gorm.DB, refreshTTL time.Duration) *TokenService {
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+	return &TokenService{
+		db:         db,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssueTokenPair starts a new login session and issues its first token pair
+func (s *TokenService) IssueTokenPair(user *models.User, client ClientInfo) (*TokenPair, error) {
+	familyID, err := auth.GenerateOpaqueToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := models.Session{
+			UserID:     user.ID,
+			TenantID:   user.TenantID,
+			FamilyID:   familyID,
+			DeviceName: client.DeviceName,
+			IPAddress:  client.IPAddress,
+			UserAgent:  client.UserAgent,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(s.refreshTTL),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return fmt.Errorf("failed to store session: %w", err)
+		}
+
+		var err error
+		pair, err = s.issue(tx, user, &session, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Rotate exchanges a refresh token for a new token pair. The presented token is
+// marked as used; presenting it again revokes every token of its family.
+func (s *TokenService) Rotate(refreshToken string, client ClientInfo) (*TokenPair, *models.User, error) {
+	var current models.RefreshToken
+	if err := s.db.Where("token_hash = ?", auth.HashToken(refreshToken)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if current.UsedAt != nil {
+		if err := s.RevokeFamily(current.FamilyID, "Refresh token reuse detected"); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	if !current.IsActive() {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := s.db.First(&user, current.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if !user.Active || user.TenantID != current.TenantID {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if client.DeviceName == "" {
+		client.DeviceName = current.DeviceName
+	}
+
+	var pair *TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Guard against concurrent rotation of the same token
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		session, err := s.sessionForToken(tx, &current, client)
+		if err != nil {
+			return err
+		}
+
+		// The session stays alive as long as its tokens keep being refreshed
+		now := time.Now()
+		if err := tx.Model(session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   client.IPAddress,
+			"expires_at":   now.Add(s.refreshTTL),
+		}).Error; err != nil {
+			return err
+		}
+
+		pair, err = s.issue(tx, &user, session, client)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			if revokeErr := s.RevokeFamily(current.FamilyID, "Refresh token reuse detected"); revokeErr != nil {
+				return nil, nil, revokeErr
+			}
+		}
+		return nil, nil, err
+	}
+
+	return pair, &user, nil
+}
+
+// RevokeFamily revokes all refresh tokens of a family and blacklists their access tokens
+func (s *TokenService) RevokeFamily(familyID, reason string) error {
+	return s.revoke(s.db.Where("family_id = ?", familyID), reason)
+}
+
+// RevokeByAccessTokenID revokes the token family the given access token was issued with
+func (s *TokenService) RevokeByAccessTokenID(tokenID, reason string) error {
+	var token models.RefreshToken
+	if err := s.db.Where("access_token_id = ?", tokenID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.RevokeFamily(token.FamilyID, reason)
+}
+
+// RevokeAllForUser revokes every refresh token family of a user
+func (s *TokenService) RevokeAllForUser(userID uint, reason string) error {
+	return s.revoke(s.db.Where("user_id = ?", userID), reason)
+}
+
+// RevokeSession revokes a login session together with its tokens
+func (s *TokenService) RevokeSession(session *models.Session, reason string) error {
+	return s.RevokeFamily(session.FamilyID, reason)
+}
+
+// RevokeOtherSessions revokes every session of a user except the given one
+func (s *TokenService) RevokeOtherSessions(userID, keepSessionID uint, reason string) error {
+	var keep models.Session
+	if err := s.db.Where("id = ? AND user_id = ?", keepSessionID, userID).First(&keep).Error; err != nil {
+		return err
+	}
+
+	return s.revoke(s.db.Where("user_id = ? AND family_id <> ?", userID, keep.FamilyID), reason)
+}
+
+// sessionForToken returns the session owning a refresh token. Tokens issued
+// before sessions existed get a session created on their first rotation.
+func (s *TokenService) sessionForToken(db *gorm.DB, token *models.RefreshToken, client ClientInfo) (*models.Session, error) {
+	var session models.Session
+	err := db.Where("family_id = ?", token.FamilyID).First(&session).Error
+	if err == nil {
+		if session.RevokedAt != nil {
+			return nil, ErrInvalidRefreshToken
+		}
+		return &session, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	session = models.Session{
+		UserID:     token.UserID,
+		TenantID:   token.TenantID,
+		FamilyID:   token.FamilyID,
+		DeviceName: client.DeviceName,
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  token.ExpiresAt,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	return &session, nil
+}
+
+// issue creates a signed access token and a refresh token within the session's family
+func (s *TokenService) issue(db *gorm.DB, user *models.User, session *models.Session, client ClientInfo) (*TokenPair, error) {
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.TenantID, user.Role, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:          user.ID,
+		TenantID:        user.TenantID,
+		FamilyID:        session.FamilyID,
+		TokenHash:       auth.HashToken(refreshToken),
+		AccessTokenID:   accessToken.ID,
+		AccessExpiresAt: accessToken.ExpiresAt,
+		DeviceName:      client.DeviceName,
+		IPAddress:       client.IPAddress,
+		UserAgent:       client.UserAgent,
+		ExpiresAt:       time.Now().Add(s.refreshTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken.Token,
+		AccessTokenExpiresAt:  accessToken.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// revoke marks the refresh tokens matched by query and their sessions as revoked
+// and blacklists the access tokens that were issued alongside them and have not yet expired
+func (s *TokenService) revoke(query *gorm.DB, reason string) error {
+	var tokens []models.RefreshToken
+	if err := query.Find(&tokens).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	familySet := make(map[string]bool)
+	var families []string
+	for _, token := range tokens {
+		if !familySet[token.FamilyID] {
+			familySet[token.FamilyID] = true
+			families = append(families, token.FamilyID)
+		}
+	}
+
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("family_id IN ? AND revoked_at IS NULL", families).
+			Updates(map[string]interface{}{
+				"revoked_at":     now,
+				"revoked_reason": reason,
+			}).Error; err != nil {
+			return err
+		}
+
+		for _, token := range tokens {
+			if token.RevokedAt == nil {
+				if err := tx.Model(&models.RefreshToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+					"revoked_at":     now,
+					"revoked_reason": reason,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			if token.AccessTokenID == "" || !token.AccessExpiresAt.After(now) {
+				continue
+			}
+
+			blacklistEntry := models.TokenBlacklist{
+				TokenID:   token.AccessTokenID,
+				UserID:    token.UserID,
+				ExpiresAt: token.AccessExpiresAt,
+				Reason:    reason,
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blacklistEntry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,168 @@
+package tenancy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// rowLevelSecurityName identifies the row-level security plugin on a database
+const rowLevelSecurityName = "tenancy:row_level_security"
+
+// rowLevelSecurityPolicy admits rows of the tenant in app.tenant_id, or all
+// rows when app.all_tenants is on. Without either setting no rows are visible.
+const rowLevelSecurityPolicy = `CREATE POLICY tenant_isolation ON %s
+	USING (current_setting('app.all_tenants', true) = 'on'
+		OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)`
+
+// rowLevelSecurity sets app.tenant_id for every transaction run with a tenant
+// context, so PostgreSQL policies can enforce isolation as a second line of
+// defence behind the query callbacks
+type rowLevelSecurity struct{}
+
+// Name implements gorm.Plugin
+func (rowLevelSecurity) Name() string {
+	return rowLevelSecurityName
+}
+
+// Initialize implements gorm.Plugin
+func (rowLevelSecurity) Initialize(db *gorm.DB) error {
+	// Writes already run in GORM's default transaction
+	if err := db.Callback().Create().After("gorm:begin_transaction").Register("tenancy:set_tenant", setTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:begin_transaction").Register("tenancy:set_tenant", setTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:begin_transaction").Register("tenancy:set_tenant", setTenant); err != nil {
+		return err
+	}
+
+	// Queries get a transaction of their own unless they run in one already
+	if err := db.Callback().Query().Before("gorm:query").Register("tenancy:begin_transaction", beginTenantTransaction); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:after_query").Register("tenancy:commit_or_rollback_transaction", callbacks.CommitOrRollbackTransaction); err != nil {
+		return err
+	}
+
+	// Row and Rows hand out results after the callbacks finish, so they can
+	// only be scoped inside an explicit transaction. Outside of one they see
+	// no tenant rows at all.
+	if err := db.Callback().Row().Before("gorm:row").Register("tenancy:set_tenant", setTenant); err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("tenancy:set_tenant", setTenant)
+}
+
+// EnableRowLevelSecurity makes db set the app.tenant_id setting checked by the
+// policies installed by MigratePolicies. It requires PostgreSQL and a database
+// role that is neither superuser nor BYPASSRLS, as those skip all policies.
+func EnableRowLevelSecurity(db *gorm.DB) error {
+	if name := db.Dialector.Name(); name != "postgres" {
+		return fmt.Errorf("tenancy: row-level security requires PostgreSQL, not %s", name)
+	}
+	if RowLevelSecurityEnabled(db) {
+		return nil
+	}
+	return db.Use(rowLevelSecurity{})
+}
+
+// RowLevelSecurityEnabled checks if EnableRowLevelSecurity was called on db
+func RowLevelSecurityEnabled(db *gorm.DB) bool {
+	_, ok := db.Config.Plugins[rowLevelSecurityName]
+	return ok
+}
+
+// MigratePolicies installs the tenant isolation policy on the tables of the
+// tenant-scoped models if row-level security is enabled, and turns it off
+// otherwise so the tables stay readable. It does nothing on other databases
+// than PostgreSQL.
+func MigratePolicies(db *gorm.DB, dst ...interface{}) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for _, model := range dst {
+		if _, ok := model.(models.TenantScoped); !ok {
+			continue
+		}
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		table := db.Statement.Quote(stmt.Schema.Table)
+
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s NO FORCE ROW LEVEL SECURITY", table),
+			fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY", table),
+		}
+		if RowLevelSecurityEnabled(db) {
+			statements = []string{
+				fmt.Sprintf("DROP POLICY IF EXISTS tenant_isolation ON %s", table),
+				fmt.Sprintf(rowLevelSecurityPolicy, table),
+				fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", table),
+				// Apply the policy to the table owner as well
+				fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY", table),
+			}
+		}
+
+		for _, sql := range statements {
+			if err := db.Exec(sql).Error; err != nil {
+				return fmt.Errorf("failed to update row-level security of %s: %w", stmt.Schema.Table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// beginTenantTransaction starts a transaction for a query with a tenant context
+// and sets the tenant for it
+func beginTenantTransaction(db *gorm.DB) {
+	if _, _, ok := contextTenant(db); !ok {
+		return
+	}
+	callbacks.BeginTransaction(db)
+	setTenant(db)
+}
+
+// setTenant sets app.tenant_id and app.all_tenants for the current transaction
+func setTenant(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	tenantID, allTenants, ok := contextTenant(db)
+	if !ok {
+		return
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); !inTransaction {
+		return
+	}
+
+	tenant, all := "", "off"
+	if allTenants {
+		all = "on"
+	} else {
+		tenant = strconv.FormatUint(uint64(tenantID), 10)
+	}
+
+	_, err := db.Statement.ConnPool.ExecContext(db.Statement.Context,
+		"SELECT set_config('app.tenant_id', $1, true), set_config('app.all_tenants', $2, true)", tenant, all)
+	db.AddError(err)
+}
+
+// contextTenant returns the tenant of the statement's context, or whether it
+// may access all tenants
+func contextTenant(db *gorm.DB) (uint, bool, bool) {
+	ctx := db.Statement.Context
+	if allTenants, _ := ctx.Value(allTenantsKey).(bool); allTenants {
+		return 0, true, true
+	}
+	tenantID, ok := FromContext(ctx)
+	return tenantID, false, ok
+}
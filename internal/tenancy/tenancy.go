@@ -0,0 +1,175 @@
+// Package tenancy scopes database access to a single tenant.
+//
+// Models opt in by implementing models.TenantScoped. Once Register has been
+// called, every query, update and delete of such a model run through a
+// *gorm.DB derived from a tenant context is restricted to that tenant's rows,
+// and creates get the tenant's ID. Accessing a tenant-scoped model without a
+// tenant in the context fails with ErrMissingTenant instead of silently
+// reading or writing across tenants.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrMissingTenant is returned when a tenant-scoped model is accessed without a tenant
+	ErrMissingTenant = errors.New("tenancy: tenant-scoped model accessed without a tenant")
+	// ErrTenantMismatch is returned when a record is created for a different tenant than the context's
+	ErrTenantMismatch = errors.New("tenancy: record belongs to a different tenant")
+)
+
+// tenantColumn is the column holding the owning tenant of scoped models
+const tenantColumn = "tenant_id"
+
+type contextKey int
+
+const (
+	tenantIDKey contextKey = iota
+	allTenantsKey
+)
+
+// NewContext returns a context carrying the tenant whose data may be accessed
+func NewContext(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// FromContext returns the tenant carried by the context
+func FromContext(ctx context.Context) (uint, bool) {
+	tenantID, ok := ctx.Value(tenantIDKey).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// WithTenant returns a session restricted to the given tenant
+func WithTenant(db *gorm.DB, tenantID uint) *gorm.DB {
+	return db.WithContext(NewContext(db.Statement.Context, tenantID))
+}
+
+// AllTenants returns a session that may access the data of every tenant.
+// It is meant for platform-level operations such as super-admin reports and
+// provisioning, which set tenant IDs explicitly.
+func AllTenants(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, allTenantsKey, true))
+}
+
+// Register installs the tenant callbacks on db. Calling it again is a no-op.
+func Register(db *gorm.DB) error {
+	if db.Callback().Query().Get("tenancy:query") != nil {
+		return nil
+	}
+
+	if err := db.Callback().Create().Before("gorm:create").Register("tenancy:create", assignTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("tenancy:query", filterTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tenancy:row", filterTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenancy:update", filterTenantWrite); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("tenancy:delete", filterTenantWrite)
+}
+
+// scopedTenant reports whether the statement touches a tenant-scoped model
+// and which tenant it is restricted to. It records ErrMissingTenant on the
+// statement if no tenant is available.
+func scopedTenant(db *gorm.DB) (uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return 0, false
+	}
+	if _, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(models.TenantScoped); !ok {
+		return 0, false
+	}
+
+	tenantID, allTenants, ok := contextTenant(db)
+	if allTenants {
+		return 0, false
+	}
+	if !ok {
+		db.AddError(ErrMissingTenant)
+		return 0, false
+	}
+	return tenantID, true
+}
+
+// filterTenant restricts queries to the context's tenant
+func filterTenant(db *gorm.DB) {
+	if tenantID, ok := scopedTenant(db); ok {
+		addTenantCondition(db, tenantID)
+	}
+}
+
+// filterTenantWrite restricts updates and deletes to the context's tenant
+func filterTenantWrite(db *gorm.DB) {
+	tenantID, ok := scopedTenant(db)
+	if !ok {
+		return
+	}
+
+	// Leave statements without any condition alone so GORM still rejects
+	// global updates and deletes instead of running them across the tenant
+	if _, hasWhere := db.Statement.Clauses["WHERE"]; !hasWhere && !db.AllowGlobalUpdate && !hasPrimaryKey(db) {
+		return
+	}
+
+	addTenantCondition(db, tenantID)
+}
+
+func addTenantCondition(db *gorm.DB, tenantID uint) {
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: tenantColumn}, Value: tenantID},
+	}})
+}
+
+// hasPrimaryKey reports whether GORM can derive conditions from the primary
+// key of the statement's record
+func hasPrimaryKey(db *gorm.DB) bool {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || db.Statement.ReflectValue.Kind() != reflect.Struct {
+		return false
+	}
+	_, isZero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue)
+	return !isZero
+}
+
+// assignTenant sets the context's tenant on created records
+func assignTenant(db *gorm.DB) {
+	tenantID, ok := scopedTenant(db)
+	if !ok {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField(tenantColumn)
+	if field == nil {
+		return
+	}
+
+	assign := func(record reflect.Value) {
+		value, isZero := field.ValueOf(db.Statement.Context, record)
+		if isZero {
+			db.AddError(field.Set(db.Statement.Context, record, tenantID))
+			return
+		}
+		if id, ok := value.(uint); !ok || id != tenantID {
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			assign(reflect.Indirect(db.Statement.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		assign(db.Statement.ReflectValue)
+	}
+}
@@ -0,0 +1,70 @@
+package tenancy
+
+import (
+	"testing"
+
+	"github.com/ae-saas-basic/ae-saas-basic/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Customer{}, &models.Plan{}))
+	require.NoError(t, Register(db))
+	require.NoError(t, Register(db))
+	return db
+}
+
+func TestScopedQueries(t *testing.T) {
+	db := setupDB(t)
+	acme, globex := WithTenant(db, 1), WithTenant(db, 2)
+
+	require.NoError(t, acme.Create(&models.Customer{Name: "A", Email: "a@example.com"}).Error)
+	globexCustomer := models.Customer{Name: "G", Email: "g@example.com"}
+	require.NoError(t, globex.Create(&globexCustomer).Error)
+	assert.Equal(t, uint(2), globexCustomer.TenantID)
+
+	var customers []models.Customer
+	require.NoError(t, acme.Find(&customers).Error)
+	if assert.Len(t, customers, 1) {
+		assert.Equal(t, uint(1), customers[0].TenantID)
+	}
+
+	var count int64
+	require.NoError(t, acme.Model(&models.Customer{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Rows of other tenants can't be read, updated or deleted
+	var customer models.Customer
+	assert.ErrorIs(t, acme.First(&customer, globexCustomer.ID).Error, gorm.ErrRecordNotFound)
+	assert.Equal(t, int64(0), acme.Model(&globexCustomer).Update("name", "Hijacked").RowsAffected)
+	assert.Equal(t, int64(0), acme.Delete(&globexCustomer).RowsAffected)
+	require.NoError(t, globex.First(&customer, globexCustomer.ID).Error)
+	assert.Equal(t, "G", customer.Name)
+
+	// Global updates are still rejected
+	assert.ErrorIs(t, acme.Model(&models.Customer{}).Update("name", "All").Error, gorm.ErrMissingWhereClause)
+
+	// Creating a record for another tenant fails
+	assert.ErrorIs(t, acme.Create(&models.Customer{Name: "X", Email: "x@example.com", TenantID: 2}).Error, ErrTenantMismatch)
+
+	// Platform-level access sees every tenant
+	require.NoError(t, AllTenants(db).Model(&models.Customer{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestMissingTenant(t *testing.T) {
+	db := setupDB(t)
+
+	var customers []models.Customer
+	assert.ErrorIs(t, db.Find(&customers).Error, ErrMissingTenant)
+	assert.ErrorIs(t, db.Create(&models.Customer{Name: "A", Email: "a@example.com", TenantID: 1}).Error, ErrMissingTenant)
+
+	// Models that are not tenant-scoped are unaffected
+	var plans []models.Plan
+	assert.NoError(t, db.Find(&plans).Error)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
 	_ "github.com/ae-saas-basic/ae-saas-basic/docs" // swagger docs
+	"github.com/ae-saas-basic/ae-saas-basic/internal/bootstrap"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/config"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/database"
 	"github.com/ae-saas-basic/ae-saas-basic/internal/router"
@@ -32,6 +35,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Tenant API key for machine-to-machine access.
+
 // @tag.name authentication
 // @tag.description Authentication and user management endpoints
 
@@ -78,8 +86,14 @@ func main() {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
-	// Set JWT secret
+	// Set JWT secret, signing keys and access token lifetime
 	auth.SetJWTSecret(cfg.JWT.Secret)
+	auth.SetJWTExpiry(time.Duration(cfg.JWT.ExpiryHour) * time.Hour)
+	keySet, err := auth.LoadKeySet(cfg.JWT.SigningKeys, cfg.JWT.Secret, cfg.JWT.AcceptHS256)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	auth.SetKeySet(keySet)
 
 	// Connect to database (create database if it doesn't exist)
 	db, err := database.ConnectWithAutoCreate(cfg.Database)
@@ -100,6 +114,9 @@ func main() {
 	// Setup router
 	r := router.SetupRouter(db, cfg)
 
+	// Run subscription and invoice schedulers
+	bootstrap.StartBackgroundJobs(context.Background(), db, cfg)
+
 	// Start server
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
 	log.Printf("Starting AE SaaS Basic server on %s", addr)
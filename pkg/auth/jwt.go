@@ -9,39 +9,94 @@ import (
 
 // JWTClaims represents the JWT claims
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	TenantID uint   `json:"tenant_id"`
-	Role     string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	TenantID  uint   `json:"tenant_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// AccessToken represents a signed access token together with its metadata
+type AccessToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
 var jwtSecret = []byte("your-secret-key") // TODO: Move to config
 
+// keySet holds the access token signing keys; nil means HS256 with jwtSecret
+var keySet *KeySet
+
+// jwtExpiry is the lifetime of issued access tokens
+var jwtExpiry = 24 * time.Hour
+
 // GenerateJWT generates a JWT token for the user
 func GenerateJWT(userID, tenantID uint, role string) (string, error) {
+	accessToken, err := GenerateAccessToken(userID, tenantID, role, 0)
+	if err != nil {
+		return "", err
+	}
+	return accessToken.Token, nil
+}
+
+// GenerateAccessToken generates a JWT access token for a login session and
+// returns it with its ID and expiration. A zero sessionID omits the sid claim.
+func GenerateAccessToken(userID, tenantID uint, role string, sessionID uint) (*AccessToken, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(jwtExpiry)
 	claims := JWTClaims{
-		UserID:   userID,
-		TenantID: tenantID,
-		Role:     role,
+		UserID:    userID,
+		TenantID:  tenantID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID:        fmt.Sprintf("%d_%d", userID, time.Now().Unix()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	key, err := currentKeySet().SigningKey(now)
+	if err != nil {
+		return nil, err
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessToken{
+		Token:     signed,
+		ID:        tokenID,
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
 // ValidateJWT validates a JWT token and returns the claims
 func ValidateJWT(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Tokens without kid were signed with the shared secret
+		kid, _ := token.Header["kid"].(string)
+		key, ok := currentKeySet().Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
@@ -69,3 +124,29 @@ func ParseTokenClaims(tokenString string) (string, time.Time, error) {
 func SetJWTSecret(secret string) {
 	jwtSecret = []byte(secret)
 }
+
+// SetKeySet sets the keys used to sign and verify access tokens (for configuration).
+// The JWT secret keeps signing email links and MFA challenges.
+func SetKeySet(ks *KeySet) {
+	keySet = ks
+}
+
+// PublicJWKS returns the public access token verification keys
+func PublicJWKS() JWKS {
+	return currentKeySet().JWKS()
+}
+
+// currentKeySet returns the configured key set or the HS256 fallback
+func currentKeySet() *KeySet {
+	if keySet != nil {
+		return keySet
+	}
+	return NewKeySet(NewHMACSigningKey("", jwtSecret))
+}
+
+// SetJWTExpiry sets the lifetime of issued access tokens (for configuration)
+func SetJWTExpiry(expiry time.Duration) {
+	if expiry > 0 {
+		jwtExpiry = expiry
+	}
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is a key used to sign and verify access tokens. Keys loaded from a
+// public key only (or retired HMAC secrets) can verify tokens but never sign them.
+type SigningKey struct {
+	ID         string    // kid header value
+	Algorithm  string    // HS256, RS256 or EdDSA
+	ActiveFrom time.Time // the key signs new tokens from this time on
+	signKey    interface{}
+	verifyKey  interface{}
+}
+
+// CanSign checks if the key holds private material
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// method returns the jwt signing method for the key's algorithm
+func (k *SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// NewHMACSigningKey creates an HS256 key from a shared secret
+func NewHMACSigningKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Algorithm: AlgorithmHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// ParseSigningKeyPEM parses an RSA or Ed25519 key from PEM data. Private keys
+// (PKCS#1 or PKCS#8) can sign and verify, public keys (PKIX) only verify.
+func ParseSigningKeyPEM(id string, data []byte, activeFrom time.Time) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found", id)
+	}
+
+	key := &SigningKey{ID: id, ActiveFrom: activeFrom}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM block type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.signKey, key.verifyKey = AlgorithmRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Algorithm, key.verifyKey = AlgorithmRS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.signKey, key.verifyKey = AlgorithmEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Algorithm, key.verifyKey = AlgorithmEdDSA, k
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, parsed)
+	}
+
+	return key, nil
+}
+
+// LoadSigningKeyFile reads an RSA or Ed25519 key from a PEM file
+func LoadSigningKeyFile(id, path string, activeFrom time.Time) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+	return ParseSigningKeyPEM(id, data, activeFrom)
+}
+
+// KeySet holds the keys used to sign and verify access tokens
+type KeySet struct {
+	keys []*SigningKey
+}
+
+// NewKeySet creates a key set from the given keys
+func NewKeySet(keys ...*SigningKey) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// LoadKeySet builds a key set from a key specification and the shared secret.
+//
+// The specification is a comma-separated list of kid=path[@activeFrom] entries,
+// e.g. "2026-01=/keys/2026-01.pem,2026-07=/keys/2026-07.pem@2026-07-01T00:00:00Z".
+// Without entries, HS256 with the secret is used for signing. With entries,
+// HS256 tokens keep verifying only if acceptHS256 is set.
+func LoadKeySet(spec, secret string, acceptHS256 bool) (*KeySet, error) {
+	var keys []*SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, path, found := strings.Cut(entry, "=")
+		if !found || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid key entry %q, expected kid=path[@activeFrom]", entry)
+		}
+
+		var activeFrom time.Time
+		if at := strings.LastIndex(path, "@"); at >= 0 {
+			var err error
+			activeFrom, err = time.Parse(time.RFC3339, path[at+1:])
+			if err != nil {
+				return nil, fmt.Errorf("key %s: invalid activation time: %w", id, err)
+			}
+			path = path[:at]
+		}
+
+		key, err := LoadSigningKeyFile(id, path, activeFrom)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	hmacKey := NewHMACSigningKey("", []byte(secret))
+	if len(keys) == 0 {
+		return NewKeySet(hmacKey), nil
+	}
+	if acceptHS256 {
+		// Verify-only, so tokens issued before the switch stay valid until they expire
+		hmacKey.signKey = nil
+		keys = append(keys, hmacKey)
+	}
+
+	return NewKeySet(keys...), nil
+}
+
+// SigningKey returns the key that signs new tokens at the given time: the
+// signing-capable key with the latest activation time that is not in the future
+func (ks *KeySet) SigningKey(at time.Time) (*SigningKey, error) {
+	var current *SigningKey
+	for _, key := range ks.keys {
+		if !key.CanSign() || key.ActiveFrom.After(at) {
+			continue
+		}
+		if current == nil || key.ActiveFrom.After(current.ActiveFrom) {
+			current = key
+		}
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf("no active signing key")
+	}
+	return current, nil
+}
+
+// Key returns the key with the given kid
+func (ks *KeySet) Key(id string) (*SigningKey, bool) {
+	for _, key := range ks.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWK represents a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. Keys scheduled for future activation
+// are included so verifiers can pick them up before they are used; HMAC secrets never are.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return jwks
+}
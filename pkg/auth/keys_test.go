@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyFile writes a private key as PKCS#8 PEM and returns its path
+func writeKeyFile(t *testing.T, dir, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// useKeySet installs a key set for the duration of the test
+func useKeySet(t *testing.T, ks *KeySet) {
+	SetKeySet(ks)
+	t.Cleanup(func() { SetKeySet(nil) })
+}
+
+// TestKeyRotation tests that the newest active key signs and older keys keep verifying
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaPath := writeKeyFile(t, dir, "old", rsaKey)
+	edPath := writeKeyFile(t, dir, "new", edKey)
+
+	// Only the RSA key is active yet
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	ks, err := LoadKeySet("old="+rsaPath+",new="+edPath+"@"+future, "secret", false)
+	require.NoError(t, err)
+	useKeySet(t, ks)
+
+	oldToken, err := GenerateAccessToken(1, 2, "user", 0)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(oldToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	// Both keys are published, the HMAC secret is not
+	jwks := PublicJWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	assert.Equal(t, "old", jwks.Keys[0].KeyID)
+	assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+
+	// Once the Ed25519 key is active it signs, and the RSA token still verifies
+	key, err := ks.SigningKey(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "new", key.ID)
+	assert.Equal(t, AlgorithmEdDSA, key.Algorithm)
+
+	now := time.Now()
+	ks.keys[1].ActiveFrom = now
+	newToken, err := GenerateAccessToken(1, 2, "user", 0)
+	require.NoError(t, err)
+
+	_, err = ValidateJWT(newToken.Token)
+	assert.NoError(t, err)
+	_, err = ValidateJWT(oldToken.Token)
+	assert.NoError(t, err)
+}
+
+// TestHS256Compatibility tests that HS256 tokens without kid keep verifying only when accepted
+func TestHS256Compatibility(t *testing.T) {
+	SetJWTSecret("secret")
+	t.Cleanup(func() { SetJWTSecret("your-secret-key") })
+
+	legacy, err := GenerateAccessToken(1, 2, "user", 0)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	path := writeKeyFile(t, t.TempDir(), "ed", edKey)
+
+	ks, err := LoadKeySet("ed="+path, "secret", true)
+	require.NoError(t, err)
+	useKeySet(t, ks)
+
+	_, err = ValidateJWT(legacy.Token)
+	assert.NoError(t, err)
+
+	ks, err = LoadKeySet("ed="+path, "secret", false)
+	require.NoError(t, err)
+	useKeySet(t, ks)
+
+	_, err = ValidateJWT(legacy.Token)
+	assert.Error(t, err)
+}
+
+// TestLoadKeySetErrors tests rejection of malformed key specifications
+func TestLoadKeySetErrors(t *testing.T) {
+	_, err := LoadKeySet("missing-path", "secret", true)
+	assert.Error(t, err)
+
+	_, err = LoadKeySet("kid=/does/not/exist.pem", "secret", true)
+	assert.Error(t, err)
+
+	_, err = ParseSigningKeyPEM("kid", []byte("not a pem"), time.Time{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// signedPayload is the content of a signed token
+type signedPayload struct {
+	Purpose   string `json:"p"`
+	Subject   string `json:"s"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// GenerateSignedToken creates a compact HMAC-signed token for links sent by email.
+// The purpose binds the token to a single use case, e.g. "email_verification".
+func GenerateSignedToken(purpose, subject string, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(signedPayload{
+		Purpose:   purpose,
+		Subject:   subject,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(encoded), nil
+}
+
+// ParseSignedToken verifies a signed token for the given purpose and returns its subject
+func ParseSignedToken(purpose, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed token")
+	}
+
+	if !hmac.Equal([]byte(sign(parts[0])), []byte(parts[1])) {
+		return "", fmt.Errorf("invalid token signature")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("malformed token")
+	}
+
+	var payload signedPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", fmt.Errorf("malformed token")
+	}
+
+	if payload.Purpose != purpose {
+		return "", fmt.Errorf("token is not valid for %s", purpose)
+	}
+	if time.Now().Unix() > payload.ExpiresAt {
+		return "", fmt.Errorf("token has expired")
+	}
+
+	return payload.Subject, nil
+}
+
+// sign returns the base64 encoded HMAC-SHA256 signature of value
+func sign(value string) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
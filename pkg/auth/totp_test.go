@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 test key from RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// TestGenerateTOTPCode tests code generation against the RFC 6238 test vectors
+func TestGenerateTOTPCode(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := GenerateTOTPCode(rfcSecret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+// TestVerifyTOTPCode tests verification with clock skew
+func TestVerifyTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, now)
+	assert.NoError(t, err)
+
+	step, ok := VerifyTOTPCode(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/totpPeriod, step)
+
+	_, ok = VerifyTOTPCode(secret, code, now.Add(totpPeriod*time.Second))
+	assert.True(t, ok, "previous step is accepted")
+
+	_, ok = VerifyTOTPCode(secret, code, now.Add(5*totpPeriod*time.Second))
+	assert.False(t, ok, "old codes are rejected")
+
+	_, ok = VerifyTOTPCode(secret, "12345", now)
+	assert.False(t, ok)
+}
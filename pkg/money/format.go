@@ -0,0 +1,106 @@
+package money
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultLocale is the locale amounts are formatted in when none matches
+const DefaultLocale = "en"
+
+// numberFormat describes how a locale writes amounts of money
+type numberFormat struct {
+	decimal     string // decimal separator
+	group       string // thousands separator
+	symbolFirst bool   // symbol before the number
+	spaced      bool   // non-breaking space between symbol and number
+}
+
+// formats holds the number formats of the supported locales, by BCP 47 tag
+// or language
+var formats = map[string]numberFormat{
+	"en":    {decimal: ".", group: ",", symbolFirst: true},
+	"de":    {decimal: ",", group: ".", spaced: true},
+	"de-AT": {decimal: ",", group: ".", symbolFirst: true, spaced: true},
+	"de-CH": {decimal: ".", group: "’", symbolFirst: true, spaced: true},
+	"es":    {decimal: ",", group: ".", spaced: true},
+	"fr":    {decimal: ",", group: "\u202f", spaced: true},
+	"it":    {decimal: ",", group: ".", spaced: true},
+	"nl":    {decimal: ",", group: ".", symbolFirst: true, spaced: true},
+	"pl":    {decimal: ",", group: "\u00a0", spaced: true},
+	"pt":    {decimal: ",", group: "\u00a0", spaced: true},
+	"sv":    {decimal: ",", group: "\u00a0", spaced: true},
+}
+
+// Format returns the amount as written in a locale such as "de-DE" or "en",
+// e.g. "1.234,56 €" or "€1,234.56". Locales are matched by tag, then by
+// language, falling back to DefaultLocale.
+func (m Money) Format(locale string) string {
+	format := lookupFormat(locale)
+
+	integer, fraction := m.parts()
+	number := groupDigits(integer, format.group)
+	if fraction != "" {
+		number += format.decimal + fraction
+	}
+
+	symbol := m.Currency.Symbol()
+	separator := ""
+	if format.spaced || isCode(symbol) {
+		separator = "\u00a0"
+	}
+
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	if format.symbolFirst {
+		return sign + symbol + separator + number
+	}
+	return sign + number + separator + symbol
+}
+
+// lookupFormat returns the number format of a locale
+func lookupFormat(locale string) numberFormat {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	language, region, _ := strings.Cut(locale, "-")
+	language = strings.ToLower(language)
+
+	if format, ok := formats[language+"-"+strings.ToUpper(region)]; ok {
+		return format
+	}
+	if format, ok := formats[language]; ok {
+		return format
+	}
+	return formats[DefaultLocale]
+}
+
+// groupDigits inserts a separator between every three digits from the right
+func groupDigits(digits, separator string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(separator)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// isCode reports whether a symbol is a currency code, which is always set
+// apart from the number
+func isCode(symbol string) bool {
+	for _, r := range symbol {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return len(symbol) > 1
+}
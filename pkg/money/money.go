@@ -0,0 +1,240 @@
+// Package money represents amounts of money as integer minor units of an
+// ISO 4217 currency, so that sums and totals don't suffer from floating point
+// rounding.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCurrency is returned when decoding a currency that isn't an ISO 4217 code
+var ErrInvalidCurrency = errors.New("currency must be a three letter ISO 4217 code")
+
+// Currency is an ISO 4217 currency code such as EUR
+type Currency string
+
+// DefaultCurrency is the currency of amounts given without one
+const DefaultCurrency Currency = "EUR"
+
+// minorDigits lists the currencies whose minor unit isn't a hundredth
+var minorDigits = map[Currency]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// symbols holds the symbols of common currencies; others are shown by code
+var symbols = map[Currency]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+	"JPY": "¥",
+	"INR": "₹",
+	"PLN": "zł",
+	"SEK": "kr",
+	"DKK": "kr",
+	"NOK": "kr",
+	"CZK": "Kč",
+}
+
+// Digits returns the number of decimal places of the currency's minor unit
+func (c Currency) Digits() int {
+	if digits, ok := minorDigits[c]; ok {
+		return digits
+	}
+	return 2
+}
+
+// Symbol returns the currency's symbol, or its code if it has none
+func (c Currency) Symbol() string {
+	if symbol, ok := symbols[c]; ok {
+		return symbol
+	}
+	return string(c)
+}
+
+// Valid reports whether the currency looks like an ISO 4217 code
+func (c Currency) Valid() bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an amount in minor units of a currency, e.g. 1189 EUR for 11.89 €.
+// Stored with GORM it is embedded as two columns, usually with an
+// embeddedPrefix such as total_.
+type Money struct {
+	Amount   int64    `gorm:"not null;default:0" json:"amount"`           // minor units
+	Currency Currency `gorm:"size:3;not null;default:''" json:"currency"` // ISO 4217 code
+}
+
+// New returns an amount of minor units of a currency
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromFloat converts an amount in major units, e.g. 11.89, to Money. The
+// decimal value of amount is rounded to the minor unit half away from zero.
+func FromFloat(amount float64, currency Currency) Money {
+	value := decimal(amount)
+	return Money{Amount: round(value.Mul(value, scale(currency))), Currency: currency}
+}
+
+// Float64 returns the amount in major units. The result is for display and
+// interfaces expecting floats; calculate with Money instead.
+func (m Money) Float64() float64 {
+	value, _ := new(big.Rat).SetFrac(big.NewInt(m.Amount), scale(m.Currency).Num()).Float64()
+	return value
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns the sum of two amounts. The zero Money takes the currency of
+// the other amount. Add panics if the currencies differ, as amounts of
+// different currencies can't be added without an exchange rate.
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.sameCurrency(other)}
+}
+
+// Sub returns the difference of two amounts, with the currency rules of Add
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.sameCurrency(other)}
+}
+
+// Neg returns the amount with its sign reversed
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns the amount multiplied by a quantity, rounded to the minor unit
+// half away from zero
+func (m Money) Mul(quantity float64) Money {
+	factor := decimal(quantity)
+	return Money{Amount: round(factor.Mul(factor, new(big.Rat).SetInt64(m.Amount))), Currency: m.Currency}
+}
+
+// Percent returns rate percent of the amount, such as the tax on it, rounded
+// to the minor unit half away from zero
+func (m Money) Percent(rate float64) Money {
+	factor := decimal(rate)
+	return Money{Amount: round(factor.Mul(factor, big.NewRat(m.Amount, 100))), Currency: m.Currency}
+}
+
+// Decimal returns the amount in major units with the currency's decimal
+// places, e.g. "11.89"
+func (m Money) Decimal() string {
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	integer, fraction := m.parts()
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction
+}
+
+// String returns the amount with the currency code, e.g. "11.89 EUR"
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// UnmarshalJSON decodes Money from its amount in minor units and currency,
+// upper-casing the currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	type plain Money
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	value.Currency = Currency(strings.ToUpper(string(value.Currency)))
+	if value.Currency != "" && !value.Currency.Valid() {
+		return ErrInvalidCurrency
+	}
+	*m = Money(value)
+	return nil
+}
+
+// sameCurrency returns the currency shared by two amounts
+func (m Money) sameCurrency(other Money) Currency {
+	switch {
+	case m.Currency == other.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return other.Currency
+	case other.Currency == "" && other.Amount == 0:
+		return m.Currency
+	default:
+		panic(fmt.Sprintf("money: mismatched currencies %s and %s", m.Currency, other.Currency))
+	}
+}
+
+// parts returns the digits of the absolute amount before and after the
+// decimal point
+func (m Money) parts() (string, string) {
+	digits := m.Currency.Digits()
+	value := strconv.FormatUint(absolute(m.Amount), 10)
+	if digits == 0 {
+		return value, ""
+	}
+	if len(value) <= digits {
+		value = strings.Repeat("0", digits-len(value)+1) + value
+	}
+	return value[:len(value)-digits], value[len(value)-digits:]
+}
+
+// absolute returns the magnitude of an amount, also of the smallest int64
+func absolute(amount int64) uint64 {
+	if amount < 0 {
+		return uint64(-(amount + 1)) + 1
+	}
+	return uint64(amount)
+}
+
+// decimal returns the shortest decimal representation of a float exactly,
+// so 0.1 is one tenth rather than the binary float closest to it. NaN and
+// infinities are treated as zero.
+func decimal(value float64) *big.Rat {
+	if r, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64)); ok {
+		return r
+	}
+	return new(big.Rat)
+}
+
+// scale returns the number of minor units in a major unit of a currency
+func scale(currency Currency) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.Digits())), nil))
+}
+
+// round rounds a number to an integer, halves away from zero
+func round(value *big.Rat) int64 {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(value.Denom()) >= 0 {
+		if value.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient.Int64()
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFromFloat tests conversion to minor units with the currency's digits
+func TestFromFloat(t *testing.T) {
+	assert.Equal(t, New(1189, "EUR"), FromFloat(11.89, "EUR"))
+	assert.Equal(t, New(101, "EUR"), FromFloat(1.005, "EUR"), "decimal halves round up")
+	assert.Equal(t, New(-101, "EUR"), FromFloat(-1.005, "EUR"), "and away from zero")
+	assert.Equal(t, New(1235, "JPY"), FromFloat(1234.5, "JPY"))
+	assert.Equal(t, New(1235, "KWD"), FromFloat(1.2345, "KWD"))
+	assert.Equal(t, New(0, "EUR"), FromFloat(math.NaN(), "EUR"))
+
+	assert.Equal(t, 11.89, New(1189, "EUR").Float64())
+	assert.Equal(t, 1234.0, New(1234, "JPY").Float64())
+}
+
+// TestArithmetic tests sums, products and rounding
+func TestArithmetic(t *testing.T) {
+	price := New(999, "EUR")
+	assert.Equal(t, New(1998, "EUR"), price.Add(price))
+	assert.Equal(t, New(0, "EUR"), price.Sub(price))
+	assert.Equal(t, New(-999, "EUR"), price.Neg())
+	assert.Equal(t, price, Money{}.Add(price), "the zero Money takes the currency")
+
+	// Ten times a tenth is exact, unlike ten float64 additions of 0.1
+	var sum Money
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(FromFloat(0.1, "EUR"))
+	}
+	assert.Equal(t, New(100, "EUR"), sum)
+
+	assert.Equal(t, New(2997, "EUR"), price.Mul(3))
+	assert.Equal(t, New(500, "EUR"), price.Mul(0.5005), "499.9995 rounds up")
+	assert.Equal(t, New(190, "EUR"), price.Percent(19))
+	assert.Equal(t, New(4, "EUR"), New(50, "EUR").Percent(7.7), "3.85 rounds up")
+	assert.Equal(t, New(-4, "EUR"), New(-50, "EUR").Percent(7.7))
+	assert.Equal(t, New(255, "EUR"), New(1000, "EUR").Percent(25.5))
+
+	assert.Panics(t, func() { price.Add(New(1, "USD")) })
+}
+
+// TestFormat tests plain and locale formatting
+func TestFormat(t *testing.T) {
+	amount := New(123456789, "EUR")
+	assert.Equal(t, "1234567.89 EUR", amount.String())
+	assert.Equal(t, "-0.05 EUR", New(-5, "EUR").String())
+	assert.Equal(t, "1234 JPY", New(1234, "JPY").String())
+
+	assert.Equal(t, "€1,234,567.89", amount.Format("en"))
+	assert.Equal(t, "€1,234,567.89", amount.Format(""))
+	assert.Equal(t, "1.234.567,89\u00a0€", amount.Format("de_DE"))
+	assert.Equal(t, "€\u00a01.234.567,89", amount.Format("de-AT"))
+	assert.Equal(t, "1\u202f234\u202f567,89\u00a0€", amount.Format("fr-FR"))
+	assert.Equal(t, "CHF\u00a01’234.50", New(123450, "CHF").Format("de-CH"))
+	assert.Equal(t, "CHF\u00a01,234.50", New(123450, "CHF").Format("en-US"))
+	assert.Equal(t, "-$0.99", New(-99, "USD").Format("en"))
+	assert.Equal(t, "¥1,234", New(1234, "JPY").Format("en"))
+}
+
+// TestJSON tests that Money is encoded as minor units with its currency
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(1189, "EUR"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":1189,"currency":"EUR"}`, string(data))
+
+	var decoded Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":999,"currency":"usd"}`), &decoded))
+	assert.Equal(t, New(999, "USD"), decoded)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":999,"currency":"EURO"}`), &decoded), ErrInvalidCurrency)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -67,3 +68,25 @@ func GetEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// slugReplacer transliterates characters that would otherwise be dropped from slugs
+var slugReplacer = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss", "&", "-and-")
+
+// Slugify converts a name into a lowercase URL slug, e.g. "Müller & Söhne GmbH" becomes "mueller-and-soehne-gmbh"
+func Slugify(name string) string {
+	name = slugReplacer.Replace(strings.ToLower(name))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimRight(b.String(), "-")
+}
@@ -0,0 +1,425 @@
+package vat
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownPrefix is returned for VAT IDs not starting with the prefix of a member state
+	ErrUnknownPrefix = errors.New("VAT ID doesn't start with the prefix of an EU member state")
+	// ErrInvalidFormat is returned for VAT IDs not formatted like the member state's
+	ErrInvalidFormat = errors.New("VAT ID doesn't match the member state's format")
+	// ErrInvalidChecksum is returned for VAT IDs whose check digits don't match
+	ErrInvalidChecksum = errors.New("VAT ID check digits don't match")
+)
+
+// ID is a VAT identification number of an EU member state
+type ID struct {
+	Prefix string // member state prefix, EL for Greece and XI for Northern Ireland
+	Number string // national number without separators
+}
+
+// String returns the VAT ID without separators
+func (id ID) String() string {
+	return id.Prefix + id.Number
+}
+
+// Country returns the ISO 3166 code of the country that issued the VAT ID
+func (id ID) Country() string {
+	if id.Prefix == "EL" {
+		return "GR"
+	}
+	return id.Prefix
+}
+
+// idFormat describes the national numbers of a member state
+type idFormat struct {
+	pattern *regexp.Regexp
+	check   func(number string) bool
+}
+
+// idFormats holds the number formats and check digit algorithms per prefix
+var idFormats = map[string]idFormat{
+	"AT": {regexp.MustCompile(`^U\d{8}$`), checkAT},
+	"BE": {regexp.MustCompile(`^[01]\d{9}$`), checkBE},
+	"BG": {regexp.MustCompile(`^\d{9,10}$`), checkBG},
+	"CY": {regexp.MustCompile(`^\d{8}[A-Z]$`), checkCY},
+	"CZ": {regexp.MustCompile(`^\d{8,10}$`), checkCZ},
+	"DE": {regexp.MustCompile(`^[1-9]\d{8}$`), checkMod11_10},
+	"DK": {regexp.MustCompile(`^[1-9]\d{7}$`), checkDK},
+	"EE": {regexp.MustCompile(`^10\d{7}$`), checkEE},
+	"EL": {regexp.MustCompile(`^\d{9}$`), checkEL},
+	"ES": {regexp.MustCompile(`^[0-9A-Z]\d{7}[0-9A-Z]$`), checkES},
+	"FI": {regexp.MustCompile(`^\d{8}$`), checkFI},
+	"FR": {regexp.MustCompile(`^[0-9A-HJ-NP-Z]{2}\d{9}$`), checkFR},
+	"HR": {regexp.MustCompile(`^\d{11}$`), checkMod11_10},
+	"HU": {regexp.MustCompile(`^\d{8}$`), checkHU},
+	"IE": {regexp.MustCompile(`^(\d{7}[A-W][A-IW]?|\d[A-Z+*]\d{5}[A-W])$`), checkIE},
+	"IT": {regexp.MustCompile(`^\d{11}$`), checkIT},
+	"LT": {regexp.MustCompile(`^(\d{9}|\d{12})$`), checkLT},
+	"LU": {regexp.MustCompile(`^\d{8}$`), checkLU},
+	"LV": {regexp.MustCompile(`^\d{11}$`), checkLV},
+	"MT": {regexp.MustCompile(`^[1-9]\d{7}$`), checkMT},
+	"NL": {regexp.MustCompile(`^\d{9}B\d{2}$`), checkNL},
+	"PL": {regexp.MustCompile(`^\d{10}$`), checkPL},
+	"PT": {regexp.MustCompile(`^[1-9]\d{8}$`), checkPT},
+	"RO": {regexp.MustCompile(`^[1-9]\d{1,9}$`), checkRO},
+	"SE": {regexp.MustCompile(`^\d{10}01$`), checkSE},
+	"SI": {regexp.MustCompile(`^[1-9]\d{7}$`), checkSI},
+	"SK": {regexp.MustCompile(`^[1-9]\d[2-47-9]\d{7}$`), checkSK},
+	"XI": {regexp.MustCompile(`^(\d{9}|\d{12}|GD[0-4]\d{2}|HA[5-9]\d{2})$`), checkXI},
+}
+
+// ParseID validates the format and check digits of a VAT ID offline. Spaces,
+// dots and dashes are ignored, and GR is accepted for the Greek prefix EL.
+// It doesn't tell whether the VAT ID has been issued; that takes a VIES query.
+func ParseID(s string) (ID, error) {
+	s = strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "", ",", "", "/", "").Replace(s))
+	if len(s) < 3 {
+		return ID{}, ErrInvalidFormat
+	}
+
+	id := ID{Prefix: s[:2], Number: s[2:]}
+	if id.Prefix == "GR" {
+		id.Prefix = "EL"
+	}
+	format, ok := idFormats[id.Prefix]
+	if !ok {
+		return ID{}, ErrUnknownPrefix
+	}
+
+	// Older numbers of these states are one digit shorter
+	if (id.Prefix == "BE" && len(id.Number) == 9) || (id.Prefix == "EL" && len(id.Number) == 8) {
+		id.Number = "0" + id.Number
+	}
+
+	if !format.pattern.MatchString(id.Number) {
+		return ID{}, ErrInvalidFormat
+	}
+	if !format.check(id.Number) {
+		return ID{}, ErrInvalidChecksum
+	}
+	return id, nil
+}
+
+// digits converts a string of decimal digits to their values
+func digits(s string) []int {
+	values := make([]int, len(s))
+	for i, c := range s {
+		values[i] = int(c - '0')
+	}
+	return values
+}
+
+// weightedSum multiplies digits with their weights and adds the products
+func weightedSum(s string, weights ...int) int {
+	sum := 0
+	for i, d := range digits(s[:len(weights)]) {
+		sum += d * weights[i]
+	}
+	return sum
+}
+
+// mod returns a modulo b for positive b, also for negative a
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
+
+// luhn checks the Luhn check digit at the end of s
+func luhn(s string) bool {
+	sum := 0
+	for i, d := range digits(s) {
+		if (len(s)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// checkMod11_10 checks an ISO 7064 Mod 11,10 check digit (DE, HR)
+func checkMod11_10(number string) bool {
+	check := 5
+	for _, d := range digits(number) {
+		if check == 0 {
+			check = 10
+		}
+		check = ((check*2)%11 + d) % 10
+	}
+	return check == 1
+}
+
+// mod97 returns a decimal string modulo 97
+func mod97(s string) int {
+	remainder := 0
+	for _, d := range digits(s) {
+		remainder = (remainder*10 + d) % 97
+	}
+	return remainder
+}
+
+func checkAT(number string) bool {
+	sum := 0
+	for i, d := range digits(number[1:8]) {
+		if i%2 == 1 {
+			d = d*2/10 + d*2%10
+		}
+		sum += d
+	}
+	return (96-sum)%10 == int(number[8]-'0')
+}
+
+func checkBE(number string) bool {
+	check, _ := strconv.Atoi(number[8:])
+	return 97-mod97(number[:8]) == check
+}
+
+func checkBG(number string) bool {
+	last := int(number[len(number)-1] - '0')
+	if len(number) == 9 {
+		check := weightedSum(number, 1, 2, 3, 4, 5, 6, 7, 8) % 11
+		if check == 10 {
+			check = weightedSum(number, 3, 4, 5, 6, 7, 8, 9, 10) % 11 % 10
+		}
+		return check == last
+	}
+
+	// Natural persons, foreigners and others each have their own check digit
+	if weightedSum(number, 2, 4, 8, 5, 10, 9, 7, 3, 6)%11%10 == last {
+		return true
+	}
+	if weightedSum(number, 21, 19, 17, 13, 11, 9, 7, 3, 1)%10 == last {
+		return true
+	}
+	check := 11 - weightedSum(number, 4, 3, 2, 7, 6, 5, 4, 3, 2)%11
+	return check != 10 && check%11 == last
+}
+
+func checkCY(number string) bool {
+	translation := []int{1, 0, 5, 7, 9, 13, 15, 17, 19, 21}
+	sum := 0
+	for i, d := range digits(number[:8]) {
+		if i%2 == 0 {
+			d = translation[d]
+		}
+		sum += d
+	}
+	return number[0:2] != "12" && number[8] == byte('A'+sum%26)
+}
+
+func checkCZ(number string) bool {
+	switch {
+	case len(number) == 8:
+		// Legal entities
+		if number[0] == '9' {
+			return false
+		}
+		check := (11 - weightedSum(number, 8, 7, 6, 5, 4, 3, 2)%11) % 11
+		if check == 0 {
+			check = 1
+		}
+		return check%10 == int(number[7]-'0')
+	case len(number) == 9 && number[0] == '6':
+		// Individuals without a birth number
+		check := weightedSum(number[1:], 8, 7, 6, 5, 4, 3, 2) % 11
+		return (8-mod(10-check, 11))%10 == int(number[8]-'0')
+	case len(number) == 9:
+		// Birth numbers issued before 1954 have no check digit
+		return true
+	default:
+		// Birth numbers are divisible by 11, or end in 0 if the rest leaves 10
+		value, _ := strconv.ParseInt(number, 10, 64)
+		prefix, _ := strconv.ParseInt(number[:9], 10, 64)
+		return value%11 == 0 || (prefix%11 == 10 && number[9] == '0')
+	}
+}
+
+func checkDK(number string) bool {
+	return weightedSum(number, 2, 7, 6, 5, 4, 3, 2, 1)%11 == 0
+}
+
+func checkEE(number string) bool {
+	return weightedSum(number, 3, 7, 1, 3, 7, 1, 3, 7, 1)%10 == 0
+}
+
+func checkEL(number string) bool {
+	check := 0
+	for _, d := range digits(number[:8]) {
+		check = check*2 + d
+	}
+	return check*2%11%10 == int(number[8]-'0')
+}
+
+func checkES(number string) bool {
+	const dniLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+	dni := func(digits string, letter byte) bool {
+		value, err := strconv.Atoi(digits)
+		return err == nil && dniLetters[value%23] == letter
+	}
+
+	switch first := number[0]; {
+	case first >= '0' && first <= '9':
+		// Spanish nationals
+		return dni(number[:8], number[8])
+	case strings.IndexByte("XYZ", first) >= 0:
+		// Foreigners
+		return dni(string('0'+first-'X')+number[1:8], number[8])
+	case strings.IndexByte("KLM", first) >= 0:
+		// Nationals without a DNI
+		return dni(number[1:8], number[8])
+	case strings.IndexByte("ABCDEFGHJNPQRSUVW", first) >= 0:
+		// Legal entities
+		sum := 0
+		for i, d := range digits(number[1:8]) {
+			if i%2 == 0 {
+				d = d*2/10 + d*2%10
+			}
+			sum += d
+		}
+		check := (10 - sum%10) % 10
+		return number[8] == byte('0'+check) || number[8] == "JABCDEFGHI"[check]
+	default:
+		return false
+	}
+}
+
+func checkFI(number string) bool {
+	return weightedSum(number, 7, 9, 10, 5, 8, 4, 2, 1)%11 == 0
+}
+
+func checkFR(number string) bool {
+	if !luhn(number[2:]) && number[2:] != "356000000" {
+		return false
+	}
+	key, err := strconv.Atoi(number[:2])
+	if err != nil {
+		// Keys with letters are issued to new companies and can't be verified
+		return true
+	}
+	return key == mod97(number[2:]+"12")
+}
+
+func checkHU(number string) bool {
+	return weightedSum(number, 9, 7, 3, 1, 9, 7, 3, 1)%10 == 0
+}
+
+func checkIE(number string) bool {
+	const alphabet = "WABCDEFGHIJKLMNOPQRSTUV"
+	if number[1] < '0' || number[1] > '9' {
+		// Old style numbers move the second character to the end
+		number = "0" + number[2:7] + number[:1] + number[7:]
+	}
+	sum := weightedSum(number, 8, 7, 6, 5, 4, 3, 2)
+	if len(number) == 9 {
+		sum += 9 * strings.IndexByte(alphabet, number[8])
+	}
+	return number[7] == alphabet[sum%23]
+}
+
+func checkIT(number string) bool {
+	office, _ := strconv.Atoi(number[7:10])
+	if number[:7] == "0000000" || !(office >= 1 && office <= 100 || office == 120 || office == 121 || office == 888 || office == 999) {
+		return false
+	}
+	return luhn(number)
+}
+
+func checkLT(number string) bool {
+	// The digit before the check digit marks VAT payers
+	if number[len(number)-2] != '1' {
+		return false
+	}
+	body := number[:len(number)-1]
+	sum := 0
+	for i, d := range digits(body) {
+		sum += (1 + i%9) * d
+	}
+	check := sum % 11
+	if check == 10 {
+		sum = 0
+		for i, d := range digits(body) {
+			sum += (1 + (i+2)%9) * d
+		}
+		check = sum % 11
+	}
+	return check%10 == int(number[len(number)-1]-'0')
+}
+
+func checkLU(number string) bool {
+	value, _ := strconv.Atoi(number[:6])
+	check, _ := strconv.Atoi(number[6:])
+	return value%89 == check
+}
+
+func checkLV(number string) bool {
+	if number[0] > '3' {
+		// Legal entities
+		return weightedSum(number, 9, 1, 4, 8, 3, 10, 2, 5, 7, 6, 1)%11 == 3
+	}
+	// Natural persons are numbered by birth date (DDMMYY)
+	day, _ := strconv.Atoi(number[:2])
+	month, _ := strconv.Atoi(number[2:4])
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+func checkMT(number string) bool {
+	return weightedSum(number, 3, 4, 6, 7, 8, 9, 10, 1)%37 == 0
+}
+
+func checkNL(number string) bool {
+	// Legal entities use the Dutch citizen service number check
+	if mod(weightedSum(number, 9, 8, 7, 6, 5, 4, 3, 2)-int(number[8]-'0'), 11) == 0 {
+		return true
+	}
+	// Sole proprietors since 2020 use ISO 7064 Mod 97,10 over the whole ID,
+	// with N=23, L=21 and B=11
+	return mod97("2321"+number[:9]+"11"+number[10:]) == 1
+}
+
+func checkPL(number string) bool {
+	return weightedSum(number, 6, 5, 7, 2, 3, 4, 5, 6, 7)%11 == int(number[9]-'0')
+}
+
+func checkPT(number string) bool {
+	check := (11 - weightedSum(number, 9, 8, 7, 6, 5, 4, 3, 2)%11) % 11 % 10
+	return check == int(number[8]-'0')
+}
+
+func checkRO(number string) bool {
+	padded := strings.Repeat("0", 10-len(number)) + number
+	check := weightedSum(padded, 7, 5, 3, 2, 1, 7, 5, 3, 2) * 10 % 11 % 10
+	return check == int(padded[9]-'0')
+}
+
+func checkSE(number string) bool {
+	return luhn(number[:10])
+}
+
+func checkSI(number string) bool {
+	check := 11 - weightedSum(number, 8, 7, 6, 5, 4, 3, 2)%11
+	if check == 10 {
+		check = 0
+	}
+	return check == int(number[7]-'0')
+}
+
+func checkSK(number string) bool {
+	value, _ := strconv.ParseInt(number, 10, 64)
+	return value%11 == 0
+}
+
+func checkXI(number string) bool {
+	if number[0] == 'G' || number[0] == 'H' {
+		// Government departments and health authorities
+		return true
+	}
+	// Numbers issued since 2010 add 55 before the modulo
+	sum := weightedSum(number, 8, 7, 6, 5, 4, 3, 2, 10, 1) % 97
+	return sum == 0 || sum == 42
+}
@@ -0,0 +1,143 @@
+// Package vat decides the VAT charged on invoices for digital services sold
+// from and within the EU, and validates VAT IDs of EU member states offline.
+package vat
+
+import (
+	"strings"
+)
+
+// Invoice notes explaining why no VAT is charged
+const (
+	NoteReverseCharge = "Reverse charge: VAT to be accounted for by the recipient (Article 196 of Directive 2006/112/EC)"
+	NoteOutsideEU     = "Not subject to VAT: place of supply outside the EU"
+)
+
+// DefaultRates holds the standard VAT rates of the EU member states in percent
+// by ISO 3166 country code
+var DefaultRates = map[string]float64{
+	"AT": 20,
+	"BE": 21,
+	"BG": 20,
+	"CY": 19,
+	"CZ": 21,
+	"DE": 19,
+	"DK": 25,
+	"EE": 24,
+	"ES": 21,
+	"FI": 25.5,
+	"FR": 20,
+	"GR": 24,
+	"HR": 25,
+	"HU": 27,
+	"IE": 23,
+	"IT": 22,
+	"LT": 21,
+	"LU": 17,
+	"LV": 21,
+	"MT": 18,
+	"NL": 21,
+	"PL": 23,
+	"PT": 23,
+	"RO": 21,
+	"SE": 25,
+	"SI": 22,
+	"SK": 23,
+}
+
+// IsMemberState reports whether a country is an EU member state
+func IsMemberState(country string) bool {
+	_, ok := DefaultRates[NormalizeCountry(country)]
+	return ok
+}
+
+// NormalizeCountry upper-cases an ISO 3166 country code and maps the Greek VAT
+// prefix EL to GR
+func NormalizeCountry(country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "EL" {
+		return "GR"
+	}
+	return country
+}
+
+// Buyer is the customer an invoice is issued to
+type Buyer struct {
+	Country  string // ISO 3166 code; empty is treated as the seller's country
+	VATID    string
+	Business bool // businesses without a valid VAT ID are charged like consumers
+}
+
+// Decision is the VAT to charge on an invoice
+type Decision struct {
+	Country       string  // country whose VAT is charged, empty if none is
+	Rate          float64 // percent
+	ReverseCharge bool    // the buyer accounts for the VAT
+	VATID         string  // the buyer's validated VAT ID, empty if it has none
+	Note          string  // wording required on the invoice when no VAT is charged
+}
+
+// Engine decides the VAT of invoices from the seller's and buyer's country.
+// Domestic buyers pay the seller's rate. Businesses in other member states
+// with a valid VAT ID are invoiced without VAT under the reverse charge.
+// Consumers in other member states pay their own country's rate with OSS, or
+// the seller's rate below the OSS threshold. Buyers outside the EU aren't
+// charged VAT.
+type Engine struct {
+	Country  string             // seller's country; empty charges everyone FlatRate
+	Rates    map[string]float64 // rates overriding DefaultRates
+	OSS      bool               // charge consumers in other member states their country's rate
+	FlatRate float64            // rate charged when the seller's country isn't set
+}
+
+// Rate returns the standard VAT rate of a country in percent
+func (e *Engine) Rate(country string) (float64, bool) {
+	country = NormalizeCountry(country)
+	if rate, ok := e.Rates[country]; ok {
+		return rate, true
+	}
+	rate, ok := DefaultRates[country]
+	return rate, ok
+}
+
+// Decide returns the VAT to charge the buyer
+func (e *Engine) Decide(buyer Buyer) Decision {
+	seller := NormalizeCountry(e.Country)
+	if seller == "" {
+		return Decision{Rate: e.FlatRate}
+	}
+
+	country := NormalizeCountry(buyer.Country)
+	if len(country) != 2 {
+		country = seller
+	}
+
+	var vatID string
+	if buyer.VATID != "" {
+		if id, err := ParseID(buyer.VATID); err == nil && id.Country() == country {
+			vatID = id.String()
+		}
+	}
+
+	switch {
+	case country == seller:
+		return e.charge(seller, vatID)
+	case !IsMemberState(country):
+		return Decision{VATID: vatID, Note: NoteOutsideEU}
+	case buyer.Business && vatID != "":
+		return Decision{ReverseCharge: true, VATID: vatID, Note: NoteReverseCharge}
+	case e.OSS:
+		return e.charge(country, vatID)
+	default:
+		return e.charge(seller, vatID)
+	}
+}
+
+// charge returns the decision to charge the standard rate of a country,
+// falling back to FlatRate for countries without a rate
+func (e *Engine) charge(country, vatID string) Decision {
+	rate, ok := e.Rate(country)
+	if !ok {
+		rate = e.FlatRate
+	}
+	return Decision{Country: country, Rate: rate, VATID: vatID}
+}
@@ -0,0 +1,110 @@
+package vat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseID tests format and check digit validation of every member state
+func TestParseID(t *testing.T) {
+	valid := map[string]string{
+		"ATU13585627":       "ATU13585627",
+		"BE403019261":       "BE0403019261",
+		"BG 175 074 752":    "BG175074752",
+		"BG 0542011038":     "BG0542011038",
+		"CY-10259033P":      "CY10259033P",
+		"CZ 25123891":       "CZ25123891",
+		"de 136.695.976":    "DE136695976",
+		"DK 13585628":       "DK13585628",
+		"EE 100 931 558":    "EE100931558",
+		"EL 094259216":      "EL094259216",
+		"GR 94259216":       "EL094259216",
+		"ES A13 585 625":    "ESA13585625",
+		"ES X-2482300W":     "ESX2482300W",
+		"FI 20774740":       "FI20774740",
+		"FR 40 303 265 045": "FR40303265045",
+		"HR 33392005961":    "HR33392005961",
+		"HU-12892312":       "HU12892312",
+		"IE 6433435F":       "IE6433435F",
+		"IE 6433435OA":      "IE6433435OA",
+		"IE 8Z49289F":       "IE8Z49289F",
+		"IT 00743110157":    "IT00743110157",
+		"LT 119511515":      "LT119511515",
+		"LU 150 274 42":     "LU15027442",
+		"LV 4000 3521 600":  "LV40003521600",
+		"MT 1167-9112":      "MT11679112",
+		"NL 004495445B01":   "NL004495445B01",
+		"NL002455799B11":    "NL002455799B11",
+		"PL 8567346215":     "PL8567346215",
+		"PT 501 964 843":    "PT501964843",
+		"RO 185 472 90":     "RO18547290",
+		"SE 123456789701":   "SE123456789701",
+		"SI 5022 3054":      "SI50223054",
+		"SK 202 274 96 19":  "SK2022749619",
+		"XI 980 7806 84":    "XI980780684",
+	}
+	for input, expected := range valid {
+		id, err := ParseID(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, id.String(), input)
+		}
+	}
+
+	invalid := map[string]error{
+		"":                  ErrInvalidFormat,
+		"US123456789":       ErrUnknownPrefix,
+		"DE12345678":        ErrInvalidFormat,
+		"DE136695977":       ErrInvalidChecksum,
+		"ATU13585628":       ErrInvalidChecksum,
+		"FR 41 303 265 045": ErrInvalidChecksum,
+		"IT 00743110158":    ErrInvalidChecksum,
+		"NL 004495446B01":   ErrInvalidChecksum,
+		"PL 8567346216":     ErrInvalidChecksum,
+		"ES A13 585 626":    ErrInvalidChecksum,
+	}
+	for input, expected := range invalid {
+		_, err := ParseID(input)
+		assert.ErrorIs(t, err, expected, input)
+	}
+
+	id, _ := ParseID("GR 094259216")
+	assert.Equal(t, "GR", id.Country())
+}
+
+// TestDecide tests the VAT decision for domestic, EU and foreign buyers
+func TestDecide(t *testing.T) {
+	engine := Engine{Country: "DE", OSS: true, Rates: map[string]float64{"AT": 10}}
+
+	tests := []struct {
+		name     string
+		buyer    Buyer
+		expected Decision
+	}{
+		{"domestic consumer", Buyer{Country: "de"}, Decision{Country: "DE", Rate: 19}},
+		{"domestic business", Buyer{Country: "DE", VATID: "DE136695976", Business: true},
+			Decision{Country: "DE", Rate: 19, VATID: "DE136695976"}},
+		{"unknown country", Buyer{}, Decision{Country: "DE", Rate: 19}},
+		{"EU business", Buyer{Country: "FR", VATID: "FR40303265045", Business: true},
+			Decision{ReverseCharge: true, VATID: "FR40303265045", Note: NoteReverseCharge}},
+		{"Greek business", Buyer{Country: "GR", VATID: "EL094259216", Business: true},
+			Decision{ReverseCharge: true, VATID: "EL094259216", Note: NoteReverseCharge}},
+		{"EU business with invalid VAT ID", Buyer{Country: "FR", VATID: "FR41303265045", Business: true},
+			Decision{Country: "FR", Rate: 20}},
+		{"EU business with VAT ID of another state", Buyer{Country: "FR", VATID: "NL004495445B01", Business: true},
+			Decision{Country: "FR", Rate: 20}},
+		{"EU consumer", Buyer{Country: "FI"}, Decision{Country: "FI", Rate: 25.5}},
+		{"overridden rate", Buyer{Country: "AT"}, Decision{Country: "AT", Rate: 10}},
+		{"foreign business", Buyer{Country: "US", Business: true}, Decision{Note: NoteOutsideEU}},
+		{"foreign consumer", Buyer{Country: "CH"}, Decision{Note: NoteOutsideEU}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, engine.Decide(tt.buyer), tt.name)
+	}
+
+	engine.OSS = false
+	assert.Equal(t, Decision{Country: "DE", Rate: 19}, engine.Decide(Buyer{Country: "FI"}), "below the OSS threshold")
+
+	flat := Engine{FlatRate: 7}
+	assert.Equal(t, Decision{Rate: 7}, flat.Decide(Buyer{Country: "FR", VATID: "FR40303265045", Business: true}))
+}
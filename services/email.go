@@ -3,6 +3,7 @@ package services
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"log"
@@ -35,6 +36,7 @@ const (
 	TemplateInvoice       EmailTemplate = "invoice"
 	TemplateAppointment   EmailTemplate = "appointment"
 	TemplateNotification  EmailTemplate = "notification"
+	TemplateInvitation    EmailTemplate = "invitation"
 )
 
 // EmailData contains data to be passed to email templates
@@ -44,11 +46,20 @@ type EmailData struct {
 	Subject         string
 	VerificationURL string
 	ResetURL        string
+	InvitationURL   string
 	AppName         string
 	SupportEmail    string
 	CompanyName     string
 	CompanyAddress  string
 	CustomData      map[string]interface{}
+	Attachments     []Attachment
+}
+
+// Attachment represents a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 // EmailService handles email operations
@@ -86,6 +97,7 @@ func (e *EmailService) loadTemplates() {
 		TemplateInvoice:       "invoice.html",
 		TemplateAppointment:   "appointment.html",
 		TemplateNotification:  "notification.html",
+		TemplateInvitation:    "invitation.html",
 	}
 
 	for templateType, filename := range templates {
@@ -103,12 +115,17 @@ func (e *EmailService) loadTemplates() {
 
 // SendEmail sends an email using the configured provider
 func (e *EmailService) SendEmail(to, subject, htmlBody, textBody string) error {
+	return e.SendEmailWithAttachments(to, subject, htmlBody, textBody, nil)
+}
+
+// SendEmailWithAttachments sends an email with files attached using the configured provider
+func (e *EmailService) SendEmailWithAttachments(to, subject, htmlBody, textBody string, attachments []Attachment) error {
 	var result error
 	switch e.provider {
 	case ProviderSMTP:
-		result = e.sendSMTP(to, subject, htmlBody, textBody)
+		result = e.sendSMTP(to, subject, htmlBody, textBody, attachments)
 	case ProviderMock:
-		result = e.sendMock(to, subject, htmlBody, textBody)
+		result = e.sendMock(to, subject, htmlBody, textBody, attachments)
 	default:
 		result = fmt.Errorf("unsupported email provider: %s", e.provider)
 	}
@@ -139,7 +156,7 @@ func (e *EmailService) SendTemplateEmail(to string, template EmailTemplate, data
 		}
 
 		textBody := e.htmlToText(htmlBuffer.String())
-		return e.SendEmail(to, data.Subject, htmlBuffer.String(), textBody)
+		return e.SendEmailWithAttachments(to, data.Subject, htmlBuffer.String(), textBody, data.Attachments)
 	}
 
 	// Fall back to default template
@@ -147,7 +164,7 @@ func (e *EmailService) SendTemplateEmail(to string, template EmailTemplate, data
 }
 
 // sendSMTP sends email via SMTP
-func (e *EmailService) sendSMTP(to, subject, htmlBody, textBody string) error {
+func (e *EmailService) sendSMTP(to, subject, htmlBody, textBody string, attachments []Attachment) error {
 	smtpHost := utils.GetEnv("SMTP_HOST", "")
 	smtpPort := utils.GetEnv("SMTP_PORT", "587")
 	smtpUser := utils.GetEnv("SMTP_USER", "")
@@ -160,7 +177,7 @@ func (e *EmailService) sendSMTP(to, subject, htmlBody, textBody string) error {
 
 	auth := smtp.PlainAuth("", smtpUser, smtpPassword, smtpHost)
 
-	message := e.composeMessage(to, subject, htmlBody, textBody)
+	message := e.composeMessage(to, subject, htmlBody, textBody, attachments)
 
 	addr := fmt.Sprintf("%s:%s", smtpHost, smtpPort)
 
@@ -227,7 +244,7 @@ func (e *EmailService) sendSMTPTLS(addr string, auth smtp.Auth, from string, to
 }
 
 // sendMock logs email content instead of sending (for development)
-func (e *EmailService) sendMock(to, subject, htmlBody, textBody string) error {
+func (e *EmailService) sendMock(to, subject, htmlBody, textBody string, attachments []Attachment) error {
 	from := utils.GetEnv("SMTP_FROM", "Unburdy <no-reply@unburdy.de>")
 
 	emailType := e.detectEmailType(subject, htmlBody)
@@ -250,6 +267,9 @@ func (e *EmailService) sendMock(to, subject, htmlBody, textBody string) error {
 	fmt.Println("----------------------------------------")
 	fmt.Println(textBody)
 	fmt.Println("----------------------------------------")
+	for _, attachment := range attachments {
+		fmt.Printf("📎 ATTACHMENT: %s (%s, %d bytes)\n", attachment.Filename, attachment.ContentType, len(attachment.Data))
+	}
 	fmt.Println("✅ Email processing completed successfully (Mock Mode)")
 	fmt.Println("================================================================================")
 
@@ -261,7 +281,9 @@ func (e *EmailService) detectEmailType(subject, htmlBody string) string {
 	subjectLower := strings.ToLower(subject)
 	bodyLower := strings.ToLower(htmlBody)
 
-	if strings.Contains(subjectLower, "verification") || strings.Contains(bodyLower, "verify") {
+	if strings.Contains(subjectLower, "invited") || strings.Contains(bodyLower, "accept invitation") {
+		return "✉️ INVITATION"
+	} else if strings.Contains(subjectLower, "verification") || strings.Contains(bodyLower, "verify") {
 		return "📧 EMAIL VERIFICATION"
 	} else if strings.Contains(subjectLower, "reset") || strings.Contains(bodyLower, "reset") {
 		return "🔑 PASSWORD RESET"
@@ -297,6 +319,12 @@ func (e *EmailService) displayEmailTypeInfo(emailType, htmlBody string) {
 			fmt.Printf("🔗 RESET LINK: %s\n", link)
 		}
 		fmt.Println("💡 INFO: Password reset request")
+	case strings.Contains(emailType, "INVITATION"):
+		link := e.extractLink(htmlBody, []string{"accept-invitation", "invitation"})
+		if link != "" {
+			fmt.Printf("🔗 INVITATION LINK: %s\n", link)
+		}
+		fmt.Println("💡 INFO: Invitation to join a tenant")
 	case strings.Contains(emailType, "CONTACT"):
 		fmt.Println("💡 INFO: Contact form submission received and forwarded")
 	case strings.Contains(emailType, "INVOICE"):
@@ -318,8 +346,9 @@ func (e *EmailService) extractLink(htmlBody string, keywords []string) string {
 	return ""
 }
 
-// composeMessage creates the full email message with headers
-func (e *EmailService) composeMessage(to, subject, htmlBody, textBody string) string {
+// composeMessage creates the full email message with headers. Attachments
+// wrap the text and HTML parts in a multipart/mixed message.
+func (e *EmailService) composeMessage(to, subject, htmlBody, textBody string, attachments []Attachment) string {
 	from := utils.GetEnv("SMTP_FROM", "no-reply@unburdy.de")
 
 	var buf bytes.Buffer
@@ -329,6 +358,11 @@ func (e *EmailService) composeMessage(to, subject, htmlBody, textBody string) st
 	buf.WriteString(fmt.Sprintf("To: %s\r\n", to))
 	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
 	buf.WriteString("MIME-Version: 1.0\r\n")
+	if len(attachments) > 0 {
+		buf.WriteString("Content-Type: multipart/mixed; boundary=\"mixed123\"\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString("--mixed123\r\n")
+	}
 	buf.WriteString("Content-Type: multipart/alternative; boundary=\"boundary123\"\r\n")
 	buf.WriteString("\r\n")
 
@@ -351,6 +385,25 @@ func (e *EmailService) composeMessage(to, subject, htmlBody, textBody string) st
 	// End boundary
 	buf.WriteString("--boundary123--\r\n")
 
+	if len(attachments) > 0 {
+		for _, attachment := range attachments {
+			buf.WriteString("\r\n--mixed123\r\n")
+			buf.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", attachment.ContentType, attachment.Filename))
+			buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+			buf.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", attachment.Filename))
+			buf.WriteString("\r\n")
+
+			// Base64 lines must not exceed 76 characters
+			encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+			for len(encoded) > 76 {
+				buf.WriteString(encoded[:76] + "\r\n")
+				encoded = encoded[76:]
+			}
+			buf.WriteString(encoded + "\r\n")
+		}
+		buf.WriteString("--mixed123--\r\n")
+	}
+
 	return buf.String()
 }
 
@@ -365,12 +418,16 @@ func (e *EmailService) sendDefaultTemplate(to string, template EmailTemplate, da
 		htmlBody = e.getDefaultPasswordResetTemplate(data)
 	case TemplateWelcome:
 		htmlBody = e.getDefaultWelcomeTemplate(data)
+	case TemplateInvitation:
+		htmlBody = e.getDefaultInvitationTemplate(data)
+	case TemplateInvoice:
+		htmlBody = e.getDefaultInvoiceTemplate(data)
 	default:
 		return fmt.Errorf("unsupported template: %s", template)
 	}
 
 	textBody = e.htmlToText(htmlBody)
-	return e.SendEmail(to, data.Subject, htmlBody, textBody)
+	return e.SendEmailWithAttachments(to, data.Subject, htmlBody, textBody, data.Attachments)
 }
 
 // htmlToText converts HTML to plain text (simplified)
@@ -421,6 +478,37 @@ func (e *EmailService) SendWelcomeEmail(to, recipientName string) error {
 	return e.SendTemplateEmail(to, TemplateWelcome, data)
 }
 
+// SendInvitationEmail sends an invitation to join a tenant
+func (e *EmailService) SendInvitationEmail(to, inviterName, tenantName, invitationURL string) error {
+	data := EmailData{
+		RecipientName: to,
+		SenderName:    inviterName,
+		Subject:       "You have been invited to join " + tenantName,
+		InvitationURL: invitationURL,
+		CustomData:    map[string]interface{}{"TenantName": tenantName},
+	}
+	return e.SendTemplateEmail(to, TemplateInvitation, data)
+}
+
+// SendInvoiceEmail sends an invoice with its PDF attached
+func (e *EmailService) SendInvoiceEmail(to, recipientName, invoiceNumber, amount, dueDate string, pdf []byte) error {
+	data := EmailData{
+		RecipientName: recipientName,
+		Subject:       "Invoice " + invoiceNumber,
+		CustomData: map[string]interface{}{
+			"InvoiceNumber": invoiceNumber,
+			"Amount":        amount,
+			"DueDate":       dueDate,
+		},
+		Attachments: []Attachment{{
+			Filename:    invoiceNumber + ".pdf",
+			ContentType: "application/pdf",
+			Data:        pdf,
+		}},
+	}
+	return e.SendTemplateEmail(to, TemplateInvoice, data)
+}
+
 // SendNotificationEmail sends a notification email
 func (e *EmailService) SendNotificationEmail(to, recipientName, subject, message string, customData map[string]interface{}) error {
 	data := EmailData{
@@ -645,3 +733,78 @@ func (e *EmailService) getDefaultWelcomeTemplate(data EmailData) string {
 </body>
 </html>`, data.AppName, data.RecipientName, data.AppName, data.SupportEmail, data.CompanyName)
 }
+
+// getDefaultInvitationTemplate returns a default invitation email template
+func (e *EmailService) getDefaultInvitationTemplate(data EmailData) string {
+	tenantName, _ := data.CustomData["TenantName"].(string)
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Invitation</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #007bff; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .button { background: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; display: inline-block; }
+        .footer { padding: 20px; text-align: center; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Join %s on %s</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>%s has invited you to join %s. Click the button below to set up your account:</p>
+            <p><a href="%s" class="button">Accept Invitation</a></p>
+            <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
+            <p><a href="%s">%s</a></p>
+        </div>
+        <div class="footer">
+            <p>This email was sent by %s. If you didn't expect this invitation, you can safely ignore this email.</p>
+        </div>
+    </div>
+</body>
+</html>`, tenantName, data.AppName, data.SenderName, tenantName, data.InvitationURL, data.InvitationURL, data.InvitationURL, data.CompanyName)
+}
+
+// getDefaultInvoiceTemplate returns a default invoice email template
+func (e *EmailService) getDefaultInvoiceTemplate(data EmailData) string {
+	invoiceNumber, _ := data.CustomData["InvoiceNumber"].(string)
+	amount, _ := data.CustomData["Amount"].(string)
+	dueDate, _ := data.CustomData["DueDate"].(string)
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Invoice</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background: #007bff; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .footer { padding: 20px; text-align: center; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Invoice %s</h1>
+        </div>
+        <div class="content">
+            <p>Hello %s,</p>
+            <p>Please find attached invoice %s over %s, due on %s.</p>
+            <p>If you have any questions about this invoice, contact us at %s.</p>
+        </div>
+        <div class="footer">
+            <p>This email was sent by %s.</p>
+        </div>
+    </div>
+</body>
+</html>`, invoiceNumber, data.RecipientName, invoiceNumber, amount, dueDate, data.SupportEmail, data.CompanyName)
+}